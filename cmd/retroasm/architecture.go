@@ -25,6 +25,10 @@ const (
 	cpuChip8 = string(arch.CHIP8)
 	cpuZ80   = string(arch.Z80)
 
+	cpu65C02  = string(arch.M65C02)
+	cpu65SC02 = "65sc02"
+	cpuW65C02 = "w65c02"
+
 	systemChip8      = string(arch.CHIP8System)
 	systemGameBoy    = string(arch.GameBoy)
 	systemGeneric    = string(arch.Generic)
@@ -36,14 +40,26 @@ var supportedSystemsByCPU = map[string]set.Set[string]{
 	cpu6502:  set.NewFromSlice([]string{systemNES, systemGeneric}),
 	cpuChip8: set.NewFromSlice([]string{systemChip8}),
 	cpuZ80:   set.NewFromSlice([]string{systemGeneric, systemGameBoy, systemZXSpectrum}),
+
+	cpu65C02:  set.NewFromSlice([]string{systemGeneric}),
+	cpu65SC02: set.NewFromSlice([]string{systemGeneric}),
+	cpuW65C02: set.NewFromSlice([]string{systemGeneric}),
 }
 
 var defaultSystemByCPU = map[string]string{
 	cpu6502:  systemNES,
 	cpuChip8: systemChip8,
 	cpuZ80:   systemGeneric,
+
+	cpu65C02:  systemGeneric,
+	cpu65SC02: systemGeneric,
+	cpuW65C02: systemGeneric,
 }
 
+// cpu6502Variants contains the 6502 CPU variants that are not known as architecture
+// by retrogolib.
+var cpu6502Variants = set.NewFromSlice([]string{cpu65SC02, cpuW65C02})
+
 var defaultCPUBySystem = map[string]string{
 	systemChip8:      cpuChip8,
 	systemGameBoy:    cpuZ80,
@@ -113,7 +129,7 @@ func applyDerivedArchitectureDefaults(options *optionFlags) error {
 func validateArchitectureCompatibility(options *optionFlags) error {
 	compatibleSystems, ok := supportedSystemsByCPU[options.cpu]
 	if !ok {
-		return fmt.Errorf("%w: %s (supported: %s, %s, %s)", ErrUnsupportedCPU, options.cpu, cpu6502, cpuChip8, cpuZ80)
+		return unsupportedCPUError(options.cpu)
 	}
 	if !compatibleSystems.Contains(options.system) {
 		return fmt.Errorf("%w: cpu '%s' is not compatible with system '%s'", ErrIncompatibleArch, options.cpu, options.system)
@@ -146,21 +162,26 @@ func validateSystem(options *optionFlags) error {
 }
 
 func validateCPU(options *optionFlags) error {
-	if options.cpu == "" {
+	if options.cpu == "" || cpu6502Variants.Contains(options.cpu) {
 		return nil
 	}
 
 	cpu, ok := arch.FromString(options.cpu)
 	if !ok {
-		return fmt.Errorf("%w: %s (supported: %s, %s, %s)", ErrUnsupportedCPU, options.cpu, cpu6502, cpuChip8, cpuZ80)
+		return unsupportedCPUError(options.cpu)
 	}
 	options.cpu = string(cpu)
 	if _, supported := supportedSystemsByCPU[options.cpu]; !supported {
-		return fmt.Errorf("%w: %s (supported: %s, %s, %s)", ErrUnsupportedCPU, cpu, cpu6502, cpuChip8, cpuZ80)
+		return unsupportedCPUError(options.cpu)
 	}
 	return nil
 }
 
+func unsupportedCPUError(cpu string) error {
+	return fmt.Errorf("%w: %s (supported: %s, %s, %s, %s, %s, %s)", ErrUnsupportedCPU, cpu,
+		cpu6502, cpu65SC02, cpu65C02, cpuW65C02, cpuChip8, cpuZ80)
+}
+
 func registerArchitectureForCPU(asm retroasm.Assembler, cpuName string) error {
 	switch cpuName {
 	case cpu6502:
@@ -170,6 +191,16 @@ func registerArchitectureForCPU(asm retroasm.Assembler, cpuName string) error {
 			return fmt.Errorf("registering architecture '%s': %w", cpu6502, err)
 		}
 		return nil
+	case cpu65SC02, cpu65C02, cpuW65C02:
+		cfg, err := m6502.NewCPU(cpuName)
+		if err != nil {
+			return fmt.Errorf("creating architecture '%s': %w", cpuName, err)
+		}
+		adapter := retroasm.NewArchitectureAdapter(cpuName, cfg, cfg)
+		if err := asm.RegisterArchitecture(cpuName, adapter); err != nil {
+			return fmt.Errorf("registering architecture '%s': %w", cpuName, err)
+		}
+		return nil
 	default:
 		return fmt.Errorf("%w: %s", ErrUnsupportedCPU, cpuName)
 	}
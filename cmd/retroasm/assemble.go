@@ -3,13 +3,16 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/retroenv/retroasm/pkg/retroasm"
 	"github.com/retroenv/retrogolib/app"
 )
 
-// assembleFile processes the input assembly file and generates output.
+// assembleFile processes the input assembly files and generates output.
 func assembleFile(options *optionFlags, args []string) error {
 	asm := retroasm.New()
 
@@ -23,20 +26,153 @@ func assembleFile(options *optionFlags, args []string) error {
 	}
 
 	input := &retroasm.TextInput{
-		Source:     bytes.NewReader(inputData),
-		SourceName: args[0],
-		ConfigFile: options.config,
+		Source:      bytes.NewReader(inputData),
+		SourceName:  args[0],
+		OutputName:  options.output,
+		Format:      options.format,
+		ConfigFile:  options.config,
+		Object:      options.object,
+		AutoImport:  options.autoImport,
+		Symbols:     options.defines,
+		IncludeDirs: options.includeDirs,
+
+		LongBranches: options.longBranches,
+	}
+	// all files are assembled into one output, each file has its own file scope
+	for _, name := range args[1:] {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("opening input file '%s': %w", name, err)
+		}
+		input.Files = append(input.Files, retroasm.SourceFile{Name: name, Source: bytes.NewReader(data)})
+	}
+	if options.listing != "" {
+		input.Listing = &retroasm.ListingOptions{
+			Cycles: options.listingCycles,
+		}
+	}
+
+	if options.debugInfo != "" {
+		input.DebugInfo = &retroasm.DebugInfoOptions{
+			OutputName: options.output,
+		}
 	}
 
 	ctx := app.Context()
 	output, err := asm.AssembleText(ctx, input)
 	if err != nil {
-		return fmt.Errorf("assembling input file '%s': %w", args[0], err)
+		if output != nil {
+			printDiagnostics(os.Stderr, output.Diagnostics)
+		}
+		return fmt.Errorf("assembling input files: %w", err)
+	}
+	if !options.quiet {
+		printDiagnostics(os.Stderr, output.Diagnostics)
 	}
 
 	if err = os.WriteFile(options.output, output.Binary, 0o644); err != nil {
 		return fmt.Errorf("writing output file '%s': %w", options.output, err)
 	}
+	for _, file := range output.Files {
+		if err = os.WriteFile(file.Name, file.Data, 0o644); err != nil {
+			return fmt.Errorf("writing output file '%s': %w", file.Name, err)
+		}
+	}
+
+	if options.listing != "" {
+		if err = os.WriteFile(options.listing, []byte(output.Listing), 0o644); err != nil {
+			return fmt.Errorf("writing listing file '%s': %w", options.listing, err)
+		}
+	}
+
+	if options.debugInfo != "" {
+		if err = os.WriteFile(options.debugInfo, []byte(output.DebugInfo), 0o644); err != nil {
+			return fmt.Errorf("writing debug info file '%s': %w", options.debugInfo, err)
+		}
+	}
+
+	if options.dependencies != "" {
+		rule := dependencyRule(options.output, output.Dependencies)
+		if err = os.WriteFile(options.dependencies, []byte(rule), 0o644); err != nil {
+			return fmt.Errorf("writing dependency file '%s': %w", options.dependencies, err)
+		}
+	}
+
+	return writeSymbolFiles(options, output)
+}
+
+// dependencyRule returns a Makefile rule that makes the output depend on all files
+// that were read while assembling.
+func dependencyRule(output string, dependencies []string) string {
+	var rule strings.Builder
+	rule.WriteString(escapeMakefileName(output) + ":")
+	for _, name := range dependencies {
+		rule.WriteString(" \\\n " + escapeMakefileName(name))
+	}
+	rule.WriteString("\n")
+	return rule.String()
+}
+
+// dependencyFileName returns the name of the dependency file of the output, which
+// replaces the extension of the output file by .d like the -MD option of C compilers.
+func dependencyFileName(output string) string {
+	return strings.TrimSuffix(output, filepath.Ext(output)) + ".d"
+}
+
+// escapeMakefileName escapes the characters of a file name that have a special
+// meaning in Makefile rules.
+func escapeMakefileName(name string) string {
+	replacer := strings.NewReplacer(" ", "\\ ", "#", "\\#", "$", "$$")
+	return replacer.Replace(name)
+}
+
+// writeSymbolFiles writes the debugger symbol files of all requested formats next to the output file.
+func writeSymbolFiles(options *optionFlags, output *retroasm.AssemblyOutput) error {
+	for _, format := range options.symFormats {
+		files, err := retroasm.ExportSymbols(output, format, options.output)
+		if err != nil {
+			return fmt.Errorf("exporting %s symbols: %w", format, err)
+		}
 
+		for _, file := range files {
+			if err := os.WriteFile(file.Name, file.Data, 0o644); err != nil {
+				return fmt.Errorf("writing symbol file '%s': %w", file.Name, err)
+			}
+		}
+	}
 	return nil
 }
+
+// printDiagnostics prints the diagnostics in the common file:line:column compiler format.
+func printDiagnostics(w io.Writer, diagnostics []retroasm.Diagnostic) {
+	for _, diag := range diagnostics {
+		location := diag.Location.Filename
+		if diag.Location.Line > 0 {
+			location = fmt.Sprintf("%s:%d:%d", location, diag.Location.Line, diag.Location.Column)
+		}
+		_, _ = fmt.Fprintf(w, "%s: %s: %s [%s]\n", location, diagnosticLevelName(diag.Level), diag.Message, diag.Code)
+		for _, exp := range diag.Expansions {
+			site := fmt.Sprintf("%s:%d:%d", exp.Location.Filename, exp.Location.Line, exp.Location.Column)
+			if exp.Macro != "" {
+				_, _ = fmt.Fprintf(w, "    in macro '%s' used at %s\n", exp.Macro, site)
+			} else {
+				_, _ = fmt.Fprintf(w, "    included from %s\n", site)
+			}
+		}
+		for _, hint := range diag.Hints {
+			_, _ = fmt.Fprintf(w, "    hint: %s\n", hint)
+		}
+	}
+}
+
+// diagnosticLevelName returns the printed name of a diagnostic level.
+func diagnosticLevelName(level retroasm.DiagnosticLevel) string {
+	switch level {
+	case retroasm.DiagnosticWarning:
+		return "warning"
+	case retroasm.DiagnosticInfo:
+		return "info"
+	default:
+		return "error"
+	}
+}
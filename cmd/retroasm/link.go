@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/retroenv/retroasm/pkg/retroasm"
+	"github.com/retroenv/retrogolib/app"
+	"github.com/retroenv/retrogolib/log"
+)
+
+// linkCommand is the command line argument that selects linking object files.
+const linkCommand = "link"
+
+// linkMain links the object files that are passed as arguments of the link command.
+func linkMain() {
+	options, args := readLinkArguments()
+	printBanner(options)
+
+	options.logger.Info("Linking files...", log.Strings("input", args))
+
+	if err := linkFiles(options, args); err != nil {
+		options.logger.Error("Linking failed", log.Err(err))
+		os.Exit(1)
+	}
+
+	options.logger.Info("Linking finished successfully", log.String("output", options.output))
+}
+
+// readLinkArguments parses the command-line arguments of the link command.
+func readLinkArguments() (*optionFlags, []string) {
+	flags := flag.NewFlagSet(os.Args[0]+" "+linkCommand, flag.ExitOnError)
+	options := &optionFlags{}
+
+	flags.BoolVar(&options.debug, "debug", false, "enable debug logging")
+	flags.StringVar(&options.config, "c", "", "linker config file that places the segments")
+	flags.StringVar(&options.output, "o", "", "name of the output file")
+	symFormats := flags.String("sym-format", "", "comma separated debugger symbol file formats to write (mesen, fceux, sym)")
+	flags.StringVar(&options.cpu, "cpu", "", "target CPU architecture (6502, 65sc02, 65c02, w65c02, chip8, z80)")
+	flags.StringVar(&options.system, "system", "", "target system (nes, chip8, generic, gameboy, zx-spectrum)")
+	flags.BoolVar(&options.quiet, "q", false, "perform operations quietly")
+
+	err := flags.Parse(os.Args[2:])
+	args := flags.Args()
+
+	logger := createLogger(options)
+	options.logger = logger
+
+	if err != nil || len(args) == 0 || options.output == "" {
+		showUsageAndExit(options, flags)
+	}
+
+	if err := validateSymbolFormats(options, *symFormats); err != nil {
+		logger.Error("Invalid symbol file format", log.Err(err))
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := validateAndProcessArchitecture(options); err != nil {
+		logger.Error("Invalid architecture configuration", log.Err(err))
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	return options, args
+}
+
+// linkFiles links the object files and writes the output files.
+func linkFiles(options *optionFlags, args []string) error {
+	asm := retroasm.New()
+
+	if err := registerArchitectureForCPU(asm, options.cpu); err != nil {
+		return err
+	}
+
+	input := &retroasm.LinkInput{
+		OutputName: options.output,
+		ConfigFile: options.config,
+	}
+	for _, name := range args {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("opening object file '%s': %w", name, err)
+		}
+		input.Objects = append(input.Objects, retroasm.ObjectFile{Name: name, Data: data})
+	}
+
+	ctx := app.Context()
+	output, err := asm.Link(ctx, input)
+	if err != nil {
+		if output != nil {
+			printDiagnostics(os.Stderr, output.Diagnostics)
+		}
+		return fmt.Errorf("linking object files: %w", err)
+	}
+
+	if err = os.WriteFile(options.output, output.Binary, 0o644); err != nil {
+		return fmt.Errorf("writing output file '%s': %w", options.output, err)
+	}
+	for _, file := range output.Files {
+		if err = os.WriteFile(file.Name, file.Data, 0o644); err != nil {
+			return fmt.Errorf("writing output file '%s': %w", file.Name, err)
+		}
+	}
+
+	return writeSymbolFiles(options, output)
+}
@@ -2,10 +2,19 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
+	"github.com/retroenv/retroasm/pkg/assembler/config"
+	"github.com/retroenv/retroasm/pkg/expression"
+	"github.com/retroenv/retroasm/pkg/lexer"
+	"github.com/retroenv/retroasm/pkg/lexer/token"
+	"github.com/retroenv/retroasm/pkg/retroasm"
+	"github.com/retroenv/retroasm/pkg/scope"
 	"github.com/retroenv/retrogolib/buildinfo"
 	"github.com/retroenv/retrogolib/log"
 )
@@ -17,22 +26,54 @@ var (
 	date    = ""
 )
 
+// ErrInvalidDefine is returned for a symbol definition that can not be parsed.
+var ErrInvalidDefine = errors.New("invalid symbol definition")
+
 // optionFlags holds command-line options and runtime configuration.
 type optionFlags struct {
-	logger *log.Logger
-	config string
-	output string
-	cpu    string
-	system string
-	debug  bool
-	quiet  bool
+	logger        *log.Logger
+	config        string
+	output        string
+	listing       string
+	debugInfo     string
+	dependencies  string
+	dependencyMD  bool // write the dependency file next to the output file
+	symFormats    []string
+	includeDirs   []string
+	defines       map[string]uint64 // symbols that are defined before the source is parsed
+	format        string
+	cpu           string
+	system        string
+	debug         bool
+	quiet         bool
+	listingCycles bool
+	object        bool
+	autoImport    bool
+	longBranches  bool
+}
+
+// stringListFlag is a command-line flag that collects the values of all its occurrences.
+type stringListFlag []string
+
+func (f *stringListFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringListFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == linkCommand {
+		linkMain()
+		return
+	}
+
 	options, args := readArguments()
 	printBanner(options)
 
-	logFields := buildLogFields(args[0], options)
+	logFields := buildLogFields(strings.Join(args, ", "), options)
 	options.logger.Info("Assembling file...", logFields...)
 
 	if err := assembleFile(options, args); err != nil {
@@ -46,6 +87,9 @@ func main() {
 // buildLogFields creates log fields for assembly operation.
 func buildLogFields(input string, options *optionFlags) []log.Field {
 	fields := []log.Field{log.String("input", input)}
+	if options.format != "" {
+		fields = append(fields, log.String("format", options.format))
+	}
 	if options.cpu != "" {
 		fields = append(fields, log.String("cpu", options.cpu))
 	}
@@ -75,7 +119,20 @@ func readArguments() (*optionFlags, []string) {
 	flags.BoolVar(&options.debug, "debug", false, "enable debug logging")
 	flags.StringVar(&options.config, "c", "", "assembler config file")
 	flags.StringVar(&options.output, "o", "", "name of the output file")
-	flags.StringVar(&options.cpu, "cpu", "", "target CPU architecture (6502, chip8, z80)")
+	flags.StringVar(&options.listing, "l", "", "name of the listing file to write")
+	var defines stringListFlag
+	flags.Var(&defines, "D", "define a symbol as NAME or NAME=expression, can be repeated")
+	flags.Var((*stringListFlag)(&options.includeDirs), "I", "directory to search included files in, can be repeated")
+	flags.StringVar(&options.dependencies, "M", "", "name of the Makefile dependency file to write")
+	flags.BoolVar(&options.dependencyMD, "MD", false, "write a Makefile dependency file named after the output file with a .d extension")
+	flags.StringVar(&options.debugInfo, "dbgfile", "", "name of the ca65 debug info file to write")
+	flags.BoolVar(&options.listingCycles, "listing-cycles", false, "show instruction cycle counts in the listing")
+	flags.BoolVar(&options.longBranches, "long-branch", false, "replace out of range branches by an inverted branch over a jmp")
+	flags.BoolVar(&options.object, "obj", false, "write a relocatable object file for the link command instead of a binary")
+	flags.BoolVar(&options.autoImport, "auto-import", false, "import undefined symbols in object mode instead of requiring .import")
+	symFormats := flags.String("sym-format", "", "comma separated debugger symbol file formats to write (mesen, fceux, sym)")
+	flags.StringVar(&options.format, "format", "", "source format (asm6, ca65, nesasm, x816), detected from the source if empty")
+	flags.StringVar(&options.cpu, "cpu", "", "target CPU architecture (6502, 65sc02, 65c02, w65c02, chip8, z80)")
 	flags.StringVar(&options.system, "system", "", "target system (nes, chip8, generic, gameboy, zx-spectrum)")
 	flags.BoolVar(&options.quiet, "q", false, "perform operations quietly")
 
@@ -88,6 +145,27 @@ func readArguments() (*optionFlags, []string) {
 	if err != nil || len(args) == 0 || options.output == "" {
 		showUsageAndExit(options, flags)
 	}
+	if options.dependencyMD && options.dependencies == "" {
+		options.dependencies = dependencyFileName(options.output)
+	}
+
+	if err := validateFormat(options); err != nil {
+		logger.Error("Invalid source format", log.Err(err))
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := validateSymbolFormats(options, *symFormats); err != nil {
+		logger.Error("Invalid symbol file format", log.Err(err))
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := parseDefines(options, defines); err != nil {
+		logger.Error("Invalid symbol definition", log.Err(err))
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 
 	if err := validateAndProcessArchitecture(options); err != nil {
 		logger.Error("Invalid architecture configuration", log.Err(err))
@@ -98,10 +176,111 @@ func readArguments() (*optionFlags, []string) {
 	return options, args
 }
 
+// validateFormat validates the source format flag, an empty format is detected
+// from the source while assembling.
+func validateFormat(options *optionFlags) error {
+	options.format = strings.ToLower(strings.TrimSpace(options.format))
+	if options.format == "" {
+		return nil
+	}
+
+	if _, err := config.ParseCompatibilityMode(options.format); err != nil {
+		return fmt.Errorf("validating format: %w", err)
+	}
+	return nil
+}
+
+// validateSymbolFormats validates the comma separated list of debugger symbol file formats.
+func validateSymbolFormats(options *optionFlags, formats string) error {
+	for format := range strings.SplitSeq(formats, ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "" {
+			continue
+		}
+		if !slices.Contains(retroasm.SymbolFormats, format) {
+			return fmt.Errorf("%w '%s'", retroasm.ErrUnsupportedSymbolFormat, format)
+		}
+		options.symFormats = append(options.symFormats, format)
+	}
+	return nil
+}
+
+// parseDefines parses the symbol definitions of the form NAME or NAME=expression. A
+// symbol without expression is set to 1, expressions can reference symbols that are
+// defined before.
+func parseDefines(options *optionFlags, defines []string) error {
+	if len(defines) == 0 {
+		return nil
+	}
+
+	sc := scope.New(nil)
+	options.defines = make(map[string]uint64, len(defines))
+
+	for _, define := range defines {
+		name, exp, hasExpression := strings.Cut(define, "=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return fmt.Errorf("%w '%s': missing symbol name", ErrInvalidDefine, define)
+		}
+
+		value := uint64(1)
+		if hasExpression {
+			var err error
+			value, err = evaluateDefine(sc, exp)
+			if err != nil {
+				return fmt.Errorf("%w '%s': %w", ErrInvalidDefine, define, err)
+			}
+		}
+
+		sym, err := scope.NewSymbol(sc, name, scope.EquType)
+		if err != nil {
+			return fmt.Errorf("%w '%s': %w", ErrInvalidDefine, define, err)
+		}
+		symbolValue := expression.New()
+		symbolValue.SetEvaluateOnce(true)
+		symbolValue.SetValue(int64(value))
+		sym.SetExpression(symbolValue)
+
+		options.defines[name] = value
+	}
+	return nil
+}
+
+// evaluateDefine evaluates the expression of a symbol definition to a number.
+func evaluateDefine(sc *scope.Scope, exp string) (uint64, error) {
+	lex := lexer.New(lexer.Config{}, strings.NewReader(exp))
+
+	var tokens []token.Token
+	for {
+		tok, err := lex.NextToken()
+		if err != nil {
+			return 0, fmt.Errorf("reading expression: %w", err)
+		}
+		if tok.Type.IsTerminator() {
+			break
+		}
+		tokens = append(tokens, tok)
+	}
+	if len(tokens) == 0 {
+		return 0, errors.New("missing expression")
+	}
+
+	value, err := expression.New(tokens...).Evaluate(sc, 1)
+	if err != nil {
+		return 0, fmt.Errorf("evaluating expression: %w", err)
+	}
+	i, ok := value.(int64)
+	if !ok || i < 0 {
+		return 0, errors.New("expression does not result in a non-negative number")
+	}
+	return uint64(i), nil
+}
+
 // showUsageAndExit displays usage information and exits.
 func showUsageAndExit(options *optionFlags, flags *flag.FlagSet) {
 	printBanner(options)
-	fmt.Printf("usage: retroasm [options] <file to assemble>\n\n")
+	fmt.Printf("usage: retroasm [options] <files to assemble>\n")
+	fmt.Printf("       retroasm link [options] <object files>\n\n")
 	flags.PrintDefaults()
 	fmt.Println()
 	os.Exit(1)
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/retroenv/retroasm/pkg/arch/m6502"
+	"github.com/retroenv/retroasm/pkg/assembler/config"
 	"github.com/retroenv/retroasm/pkg/retroasm"
 	"github.com/retroenv/retrogolib/arch"
 	"github.com/retroenv/retrogolib/assert"
@@ -40,6 +42,12 @@ func TestBuildLogFields(t *testing.T) {
 			options:  &optionFlags{system: "nes"},
 			expected: 2,
 		},
+		{
+			name:     "input with format",
+			input:    "test.asm",
+			options:  &optionFlags{format: "ca65"},
+			expected: 2,
+		},
 		{
 			name:     "input with cpu and system",
 			input:    "test.asm",
@@ -97,6 +105,134 @@ func TestCreateLogger(t *testing.T) {
 	}
 }
 
+func TestValidateFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     *optionFlags
+		expectedErr error
+		expected    string
+	}{
+		{
+			name:    "empty format",
+			options: &optionFlags{},
+		},
+		{
+			name:     "valid nesasm format",
+			options:  &optionFlags{format: " NESASM "},
+			expected: "nesasm",
+		},
+		{
+			name:     "valid x816 format",
+			options:  &optionFlags{format: "x816"},
+			expected: "x816",
+		},
+		{
+			name:        "invalid format",
+			options:     &optionFlags{format: "tasm"},
+			expectedErr: config.ErrInvalidCompatibilityMode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateFormat(tt.options)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, tt.options.format)
+		})
+	}
+}
+
+func TestValidateSymbolFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		formats     string
+		expectedErr error
+		expected    []string
+	}{
+		{
+			name: "no formats",
+		},
+		{
+			name:     "multiple formats",
+			formats:  "Mesen, fceux,sym",
+			expected: []string{"mesen", "fceux", "sym"},
+		},
+		{
+			name:        "invalid format",
+			formats:     "mesen,nl",
+			expectedErr: retroasm.ErrUnsupportedSymbolFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := &optionFlags{}
+			err := validateSymbolFormats(options, tt.formats)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, options.symFormats)
+		})
+	}
+}
+
+func TestParseDefines(t *testing.T) {
+	tests := []struct {
+		name        string
+		defines     []string
+		expectedErr error
+		expected    map[string]uint64
+	}{
+		{
+			name: "no defines",
+		},
+		{
+			name:     "symbols with and without expression",
+			defines:  []string{"PAL", "REGION=$10", "DEBUG=0", "SIZE=REGION*2+1"},
+			expected: map[string]uint64{"PAL": 1, "REGION": 0x10, "DEBUG": 0, "SIZE": 0x21},
+		},
+		{
+			name:        "missing name",
+			defines:     []string{"=1"},
+			expectedErr: ErrInvalidDefine,
+		},
+		{
+			name:        "missing expression",
+			defines:     []string{"PAL="},
+			expectedErr: ErrInvalidDefine,
+		},
+		{
+			name:        "unknown symbol",
+			defines:     []string{"SIZE=REGION+1"},
+			expectedErr: ErrInvalidDefine,
+		},
+		{
+			name:        "duplicate symbol",
+			defines:     []string{"PAL", "PAL=2"},
+			expectedErr: ErrInvalidDefine,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := &optionFlags{}
+			err := parseDefines(options, tt.defines)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, options.defines)
+		})
+	}
+}
+
 func TestValidateSystem(t *testing.T) {
 	logger := log.NewTestLogger(t)
 
@@ -172,6 +308,16 @@ func TestValidateCPU(t *testing.T) {
 			options:     &optionFlags{cpu: "6502"},
 			expectedErr: nil,
 		},
+		{
+			name:        "valid 65c02 cpu",
+			options:     &optionFlags{cpu: "65C02"},
+			expectedErr: nil,
+		},
+		{
+			name:        "valid w65c02 cpu",
+			options:     &optionFlags{cpu: "w65c02"},
+			expectedErr: nil,
+		},
 		{
 			name:        "unsupported cpu",
 			options:     &optionFlags{cpu: "x86"},
@@ -229,6 +375,17 @@ func TestValidateAndProcessArchitecture(t *testing.T) {
 			expectedErr: nil,
 			expectCPU:   "6502",
 		},
+		{
+			name:        "65c02 cpu defaults to generic system",
+			options:     &optionFlags{cpu: "65c02", logger: logger},
+			expectedErr: nil,
+			expectCPU:   "65c02",
+		},
+		{
+			name:        "incompatible nes and 65c02",
+			options:     &optionFlags{system: "nes", cpu: "65c02", logger: logger},
+			expectedErr: ErrIncompatibleArch,
+		},
 		{
 			name:        "incompatible nes and z80",
 			options:     &optionFlags{system: "nes", cpu: "z80", logger: logger},
@@ -283,6 +440,37 @@ func TestAssembleWithConfigFile(t *testing.T) {
 	}
 }
 
+func TestPrintDiagnostics(t *testing.T) {
+	diagnostics := []retroasm.Diagnostic{
+		{
+			Message:  "symbol not found in scope: 'missing'",
+			Location: retroasm.SourceLocation{Filename: "macros.asm", Line: 3, Column: 5},
+			Expansions: []retroasm.Expansion{
+				{Macro: "store", Location: retroasm.SourceLocation{Filename: "test.asm", Line: 8, Column: 1}},
+				{Location: retroasm.SourceLocation{Filename: "main.asm", Line: 2, Column: 1}},
+			},
+			Code:  "undefined-symbol",
+			Hints: []string{"check the spelling of the symbol name"},
+		},
+		{
+			Level:    retroasm.DiagnosticWarning,
+			Message:  "no position",
+			Location: retroasm.SourceLocation{Filename: "test.asm"},
+			Code:     "error",
+		},
+	}
+
+	var buf bytes.Buffer
+	printDiagnostics(&buf, diagnostics)
+
+	expected := "macros.asm:3:5: error: symbol not found in scope: 'missing' [undefined-symbol]\n" +
+		"    in macro 'store' used at test.asm:8:1\n" +
+		"    included from main.asm:2:1\n" +
+		"    hint: check the spelling of the symbol name\n" +
+		"test.asm: warning: no position [error]\n"
+	assert.Equal(t, expected, buf.String())
+}
+
 func createTestConfigFile(t *testing.T) string {
 	t.Helper()
 	configContent := `MEMORY { CODE: start = $8000, size = $8000, fill = yes; }
@@ -311,3 +499,13 @@ func runAssembleWithConfig(ctx context.Context, configPath string) error {
 	}
 	return nil
 }
+
+func TestDependencyRule(t *testing.T) {
+	rule := dependencyRule("game.nes", []string{"main.asm", "my defs.asm", "memory.cfg"})
+	assert.Equal(t, "game.nes: \\\n main.asm \\\n my\\ defs.asm \\\n memory.cfg\n", rule)
+}
+
+func TestDependencyFileName(t *testing.T) {
+	assert.Equal(t, "build/game.d", dependencyFileName("build/game.nes"))
+	assert.Equal(t, "game.d", dependencyFileName("game"))
+}
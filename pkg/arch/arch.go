@@ -2,10 +2,16 @@
 package arch
 
 import (
+	"errors"
+
 	"github.com/retroenv/retroasm/pkg/lexer/token"
 	"github.com/retroenv/retroasm/pkg/parser/ast"
 )
 
+// ErrBranchOutOfRange is returned when the target of a relative branch is too far away
+// to be encoded in the instruction.
+var ErrBranchOutOfRange = errors.New("relative branch out of range")
+
 // Architecture contains architecture specific information.
 type Architecture[T any] interface {
 	// AddressWidth returns the address width of the architecture in bits.
@@ -21,6 +27,45 @@ type Architecture[T any] interface {
 	ParseIdentifier(p Parser, ins T) (ast.Node, error)
 }
 
+// CycleCounter is an optional interface of architectures that can report the
+// number of cycles that an instruction takes to execute.
+type CycleCounter interface {
+	// InstructionCycles returns the cycle count of the instruction and whether an
+	// additional cycle is needed when a page boundary is crossed.
+	InstructionCycles(ins Instruction) (int, bool)
+}
+
+// OperandLocator is an optional interface of architectures that support relocatable
+// object files. It describes where the argument value is encoded in the instruction opcodes.
+type OperandLocator interface {
+	// InstructionOperand returns the offset and size in bytes of the encoded argument value
+	// in the instruction opcodes and whether the value is encoded relative to the address
+	// after the instruction. The offset and size are only known once the opcodes are
+	// generated, the relative flag is known once the addressing mode is assigned.
+	InstructionOperand(ins Instruction) (offset, size int, relative bool)
+}
+
+// BranchExpander is an optional interface of architectures that can replace a relative
+// branch whose target is out of range by a sequence of instructions that reaches it.
+type BranchExpander interface {
+	// ExpandedBranchSize returns the size in bytes of the expanded form of the instruction
+	// and whether the instruction is a branch that can be expanded.
+	ExpandedBranchSize(ins Instruction) (int, bool)
+	// GenerateExpandedBranchOpcode generates the opcodes of the expanded form of the branch.
+	GenerateExpandedBranchOpcode(assigner AddressAssigner, ins Instruction) error
+}
+
+// CPUSelector is an optional interface of architectures that support CPU variants with
+// different instruction sets. The parser uses it instead of Instruction once a CPU
+// variant or undocumented instructions are selected.
+type CPUSelector[T any] interface {
+	// SupportsCPU returns whether the CPU variant with the given lowercase name can be selected.
+	SupportsCPU(name string) bool
+	// CPUInstruction returns the instruction with the given name if it is accepted by the
+	// selected CPU.
+	CPUInstruction(cpu ast.CPU, name string) (T, bool)
+}
+
 // Parser processes an input stream and parses its token to produce an abstract syntax tree (AST) as output.
 type Parser interface {
 	// AddressWidth returns the address width of the architecture in bits.
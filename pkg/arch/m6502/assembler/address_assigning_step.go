@@ -17,17 +17,9 @@ func AssignInstructionAddress(assigner arch.AddressAssigner, ins arch.Instructio
 	pc := assigner.ProgramCounter()
 	ins.SetAddress(pc)
 
-	var insDetails *m6502.Instruction
-	if id := m6502.OpcodeID(ins.OpcodeID()); id != m6502.InvalidOpcodeID {
-		insDetails = m6502.InstructionsByID[id]
-	}
-	if insDetails == nil {
-		name := strings.ToLower(ins.Name())
-		var ok bool
-		insDetails, ok = m6502.Instructions[name]
-		if !ok {
-			return 0, fmt.Errorf("unsupported instruction '%s'", name)
-		}
+	insDetails, err := instructionDetails(ins)
+	if err != nil {
+		return 0, err
 	}
 
 	addressing := m6502.AddressingMode(ins.Addressing())
@@ -48,6 +40,22 @@ func AssignInstructionAddress(assigner arch.AddressAssigner, ins arch.Instructio
 	return programCounter, nil
 }
 
+// instructionDetails returns the details of the instruction. The name is looked up first,
+// as the undocumented instructions alr, anc, arr and axs have no entry for their opcode ID
+// and lax supports the immediate addressing of lxa.
+func instructionDetails(ins arch.Instruction) (*m6502.Instruction, error) {
+	name := strings.ToLower(ins.Name())
+	if details, ok := parser.Instructions[name]; ok {
+		return details, nil
+	}
+	if id := m6502.OpcodeID(ins.OpcodeID()); id != m6502.InvalidOpcodeID {
+		if details := m6502.InstructionsByID[id]; details != nil {
+			return details, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported instruction '%s'", name)
+}
+
 // disambiguousAddressing maps ambiguous addressing modes to their absolute and
 // zero page variants. The assembler resolves these during address assignment
 // based on whether the argument value fits in a byte.
@@ -64,6 +72,8 @@ func resolveAddressingMode(assigner arch.AddressAssigner, ins arch.Instruction,
 	}
 
 	value, err := assigner.ArgumentValue(ins.Argument())
+	// a forward reference uses absolute addressing until a later address assigning
+	// pass knows its value
 	if errors.Is(err, scope.ErrForwardReference) {
 		ins.SetAddressing(int(modes[0]))
 		return nil
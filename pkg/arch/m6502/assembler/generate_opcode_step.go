@@ -13,12 +13,9 @@ import (
 // GenerateInstructionOpcode generates the instruction opcode based on the instruction base opcode,
 // its addressing mode and parameters.
 func GenerateInstructionOpcode(assigner arch.AddressAssigner, ins arch.Instruction) error {
-	var instructionInfo *m6502.Instruction
-	if id := m6502.OpcodeID(ins.OpcodeID()); id != m6502.InvalidOpcodeID {
-		instructionInfo = m6502.InstructionsByID[id]
-	}
-	if instructionInfo == nil {
-		instructionInfo = m6502.Instructions[strings.ToLower(ins.Name())]
+	instructionInfo, err := instructionDetails(ins)
+	if err != nil {
+		return err
 	}
 	addressing := m6502.AddressingMode(ins.Addressing())
 	addressingInfo := instructionInfo.Addressing[addressing]
@@ -30,14 +27,14 @@ func GenerateInstructionOpcode(assigner arch.AddressAssigner, ins arch.Instructi
 
 	case m6502.ImmediateAddressing,
 		m6502.ZeroPageAddressing, m6502.ZeroPageXAddressing, m6502.ZeroPageYAddressing,
-		m6502.IndirectXAddressing, m6502.IndirectYAddressing:
+		m6502.IndirectXAddressing, m6502.IndirectYAddressing, m6502.ZeroPageIndirectAddressing:
 
 		if err := generateByteAddressingOpcode(assigner, ins); err != nil {
 			return fmt.Errorf("generating opcode: %w", err)
 		}
 
 	case m6502.AbsoluteAddressing, m6502.AbsoluteXAddressing, m6502.AbsoluteYAddressing,
-		m6502.IndirectAddressing:
+		m6502.IndirectAddressing, m6502.AbsoluteXIndirectAddressing:
 
 		if err := generateWordAddressingOpcode(assigner, ins); err != nil {
 			return fmt.Errorf("generating opcode: %w", err)
@@ -48,6 +45,11 @@ func GenerateInstructionOpcode(assigner arch.AddressAssigner, ins arch.Instructi
 			return fmt.Errorf("generating opcode: %w", err)
 		}
 
+	case m6502.ZeroPageRelativeAddressing:
+		if err := generateZeroPageRelativeAddressingOpcode(assigner, ins); err != nil {
+			return fmt.Errorf("generating opcode: %w", err)
+		}
+
 	default:
 		return fmt.Errorf("unsupported instruction addressing %d", addressing)
 	}
@@ -133,12 +135,42 @@ func generateRelativeAddressingOpcode(assigner arch.AddressAssigner, ins arch.In
 	if err != nil {
 		return fmt.Errorf("getting instruction argument: %w", err)
 	}
+	return appendRelativeOffset(assigner, ins, value)
+}
+
+// generateZeroPageRelativeAddressingOpcode generates the opcodes of the bbr and bbs
+// instructions, which encode a zero page address followed by a relative branch offset.
+func generateZeroPageRelativeAddressingOpcode(assigner arch.AddressAssigner, ins arch.Instruction) error {
+	arguments, ok := ins.Argument().([]any)
+	if !ok || len(arguments) != 2 {
+		return fmt.Errorf("unexpected argument type %T for zero page relative addressing", ins.Argument())
+	}
+
+	address, err := assigner.ArgumentValue(arguments[0])
+	if err != nil {
+		return fmt.Errorf("getting zero page address: %w", err)
+	}
+	if address > math.MaxUint8 {
+		return fmt.Errorf("zero page address %d exceeds byte", address)
+	}
+	ins.SetOpcodes(append(ins.Opcodes(), byte(address)))
+
+	target, err := assigner.ArgumentValue(arguments[1])
+	if err != nil {
+		return fmt.Errorf("getting branch target: %w", err)
+	}
+	return appendRelativeOffset(assigner, ins, target)
+}
 
+// appendRelativeOffset appends the offset of the branch target relative to the address
+// after the instruction to the opcodes.
+func appendRelativeOffset(assigner arch.AddressAssigner, ins arch.Instruction, value uint64) error {
 	insAddr := ins.Address() + uint64(ins.Size())
 	b, err := assigner.RelativeOffset(value, insAddr)
 	if err != nil {
 		diff := int64(value) - int64(insAddr)
-		return fmt.Errorf("branch target 0x%X too far from instruction at 0x%X (offset %d, limit -128..127)", value, ins.Address(), diff)
+		return fmt.Errorf("%w: branch target 0x%X too far from instruction at 0x%X (offset %d, limit -128..127)",
+			arch.ErrBranchOutOfRange, value, ins.Address(), diff)
 	}
 
 	opcodes := append(ins.Opcodes(), b)
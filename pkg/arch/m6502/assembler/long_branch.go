@@ -0,0 +1,88 @@
+package assembler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/retroenv/retroasm/pkg/arch"
+	"github.com/retroenv/retrogolib/arch/cpu/m6502"
+)
+
+const (
+	// LongBranchSize is the size of an expanded conditional branch, an inverted branch over a jmp.
+	LongBranchSize = 5
+	// LongJumpSize is the size of an expanded unconditional branch, a jmp to the target.
+	LongJumpSize = 3
+)
+
+// invertedBranches maps the conditional branches to the branch with the inverted condition.
+var invertedBranches = map[string]string{
+	m6502.BccName: m6502.BcsName,
+	m6502.BcsName: m6502.BccName,
+	m6502.BeqName: m6502.BneName,
+	m6502.BneName: m6502.BeqName,
+	m6502.BmiName: m6502.BplName,
+	m6502.BplName: m6502.BmiName,
+	m6502.BvcName: m6502.BvsName,
+	m6502.BvsName: m6502.BvcName,
+}
+
+// ExpandedBranchSize returns the size in bytes of the expanded form of a branch and
+// whether the instruction is a branch that can be expanded.
+func ExpandedBranchSize(ins arch.Instruction) (int, bool) {
+	if m6502.AddressingMode(ins.Addressing()) != m6502.RelativeAddressing {
+		return 0, false
+	}
+	name := branchName(ins)
+	if name == m6502.BraName {
+		return LongJumpSize, true
+	}
+	if _, ok := invertedBranches[name]; !ok {
+		return 0, false
+	}
+	return LongBranchSize, true
+}
+
+// GenerateExpandedBranchOpcode generates the opcodes of a branch whose target is out of
+// range. The branch with the inverted condition skips a jmp to the target, an
+// unconditional branch is replaced by the jmp.
+func GenerateExpandedBranchOpcode(assigner arch.AddressAssigner, ins arch.Instruction) error {
+	name := branchName(ins)
+	inverted, ok := invertedBranches[name]
+	if !ok && name != m6502.BraName {
+		return fmt.Errorf("instruction '%s' is not a branch", ins.Name())
+	}
+
+	value, err := assigner.ArgumentValue(ins.Argument())
+	if err != nil {
+		return fmt.Errorf("getting instruction argument: %w", err)
+	}
+	if value > math.MaxUint16 {
+		return fmt.Errorf("value %d exceeds word", value)
+	}
+
+	jmp := m6502.Instructions[m6502.JmpName].Addressing[m6502.AbsoluteAddressing].Opcode
+	if name == m6502.BraName {
+		ins.SetOpcodes(binary.LittleEndian.AppendUint16([]byte{jmp}, uint16(value)))
+		ins.SetSize(LongJumpSize)
+		return nil
+	}
+
+	branch := m6502.Instructions[inverted].Addressing[m6502.RelativeAddressing].Opcode
+	opcodes := []byte{branch, LongBranchSize - 2, jmp}
+	ins.SetOpcodes(binary.LittleEndian.AppendUint16(opcodes, uint16(value)))
+	ins.SetSize(LongBranchSize)
+	return nil
+}
+
+// branchName returns the lowercase name of the instruction.
+func branchName(ins arch.Instruction) string {
+	if id := m6502.OpcodeID(ins.OpcodeID()); id != m6502.InvalidOpcodeID {
+		if details := m6502.InstructionsByID[id]; details != nil {
+			return details.Name
+		}
+	}
+	return strings.ToLower(ins.Name())
+}
@@ -2,6 +2,10 @@
 package m6502
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/retroenv/retroasm/pkg/arch"
 	"github.com/retroenv/retroasm/pkg/arch/m6502/assembler"
 	"github.com/retroenv/retroasm/pkg/arch/m6502/parser"
@@ -10,6 +14,8 @@ import (
 	"github.com/retroenv/retrogolib/arch/cpu/m6502"
 )
 
+var errUnsupportedCPU = errors.New("unsupported CPU")
+
 // New returns a new 6502 architecture configuration.
 func New() *config.Config[*m6502.Instruction] {
 	p := &arch6502[*m6502.Instruction]{}
@@ -19,16 +25,45 @@ func New() *config.Config[*m6502.Instruction] {
 	return cfg
 }
 
+// NewCPU returns a new 6502 architecture configuration that uses the CPU variant with
+// the given name, like 65c02, until the source selects a different one.
+func NewCPU(name string) (*config.Config[*m6502.Instruction], error) {
+	name = strings.ToLower(name)
+	if !parser.SupportsCPU(name) {
+		return nil, fmt.Errorf("%w '%s'", errUnsupportedCPU, name)
+	}
+
+	p := &arch6502[*m6502.Instruction]{
+		cpu: name,
+	}
+	cfg := &config.Config[*m6502.Instruction]{
+		Arch: p,
+	}
+	return cfg, nil
+}
+
 type arch6502[T any] struct {
+	cpu string // default CPU variant, empty for the NMOS 6502
 }
 
 func (ar *arch6502[T]) AddressWidth() int {
 	return 16
 }
 
+// Instruction returns the documented instruction of the default CPU variant with the given name.
 func (ar *arch6502[T]) Instruction(name string) (*m6502.Instruction, bool) {
-	ins, ok := m6502.Instructions[name]
-	return ins, ok
+	return ar.CPUInstruction(ast.CPU{}, name)
+}
+
+func (ar *arch6502[T]) SupportsCPU(name string) bool {
+	return parser.SupportsCPU(name)
+}
+
+func (ar *arch6502[T]) CPUInstruction(cpu ast.CPU, name string) (*m6502.Instruction, bool) {
+	if cpu.Name == "" {
+		cpu.Name = ar.cpu
+	}
+	return parser.CPUInstruction(cpu, name)
 }
 
 func (ar *arch6502[T]) ParseIdentifier(p arch.Parser, ins *m6502.Instruction) (ast.Node, error) {
@@ -42,3 +77,50 @@ func (ar *arch6502[T]) AssignInstructionAddress(assigner arch.AddressAssigner, i
 func (ar *arch6502[T]) GenerateInstructionOpcode(assigner arch.AddressAssigner, ins arch.Instruction) error {
 	return assembler.GenerateInstructionOpcode(assigner, ins) //nolint:wrapcheck // thin delegation to sub-package
 }
+
+func (ar *arch6502[T]) ExpandedBranchSize(ins arch.Instruction) (int, bool) {
+	return assembler.ExpandedBranchSize(ins)
+}
+
+func (ar *arch6502[T]) GenerateExpandedBranchOpcode(assigner arch.AddressAssigner, ins arch.Instruction) error {
+	return assembler.GenerateExpandedBranchOpcode(assigner, ins) //nolint:wrapcheck // thin delegation to sub-package
+}
+
+func (ar *arch6502[T]) InstructionCycles(ins arch.Instruction) (int, bool) {
+	opcodes := ins.Opcodes()
+	if len(opcodes) == 0 {
+		return 0, false
+	}
+	name := strings.ToLower(ins.Name())
+	if details, ok := parser.Instructions[name]; ok {
+		name = details.Name // resolve asm6f names like ahx
+	}
+
+	// the 65C02 reuses opcodes of undocumented NMOS instructions for its new instructions
+	for _, table := range []*[256]m6502.Opcode{&m6502.Opcodes, &m6502.Opcodes65C02} {
+		opcode := table[opcodes[0]]
+		if opcode.Instruction != nil && opcode.Instruction.Name == name {
+			return int(opcode.Timing), opcode.PageCrossCycle
+		}
+	}
+	return 0, false
+}
+
+func (ar *arch6502[T]) InstructionOperand(ins arch.Instruction) (int, int, bool) {
+	addressing := m6502.AddressingMode(ins.Addressing())
+	if addressing == m6502.ZeroPageRelativeAddressing {
+		return 0, 0, false // the zero page address and the branch offset are separate operands
+	}
+	relative := addressing == m6502.RelativeAddressing
+	if relative && ins.Size() == assembler.LongBranchSize {
+		return 3, 2, false // the jmp of an expanded branch encodes the absolute target
+	}
+	if relative && ins.Size() == assembler.LongJumpSize {
+		return 1, 2, false // an expanded unconditional branch is a jmp to the absolute target
+	}
+	size := len(ins.Opcodes()) - 1
+	if size <= 0 {
+		return 0, 0, relative
+	}
+	return 1, size, relative
+}
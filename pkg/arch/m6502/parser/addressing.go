@@ -77,6 +77,9 @@ func extendedAddressingParam(ins *instruction, indirectAccess bool) ([]m6502.Add
 
 	switch ins.arg2.Value {
 	case "x", "X":
+		if indirectAccess && ins.instruction.HasAddressing(m6502.AbsoluteXIndirectAddressing) {
+			return []m6502.AddressingMode{m6502.AbsoluteXIndirectAddressing}, nil
+		}
 		if indirectAccess {
 			return []m6502.AddressingMode{m6502.IndirectXAddressing}, nil
 		}
@@ -0,0 +1,219 @@
+package parser
+
+import (
+	"maps"
+
+	"github.com/retroenv/retroasm/pkg/parser/ast"
+	"github.com/retroenv/retrogolib/arch/cpu/m6502"
+	"github.com/retroenv/retrogolib/set"
+)
+
+// CPU variant names that can be selected by the .setcpu directive.
+const (
+	cpu6502   = "6502"
+	cpu6502X  = "6502x"  // NMOS 6502 including all undocumented instructions, like ca65
+	cpu65SC02 = "65sc02" // 65C02 without the Rockwell bit instructions
+	cpu65C02  = "65c02"  // Rockwell 65C02, like ca65
+	cpuW65C02 = "w65c02" // WDC 65C02, adds wai and stp to the Rockwell 65C02
+)
+
+// supportedCPUs contains the names of all CPU variants that can be selected.
+var supportedCPUs = set.NewFromSlice([]string{cpu6502, cpu6502X, cpu65SC02, cpu65C02, cpuW65C02})
+
+// Levels of undocumented instructions, each level includes the instructions of the lower levels.
+const (
+	documented = iota
+	undocumentedStable
+	undocumentedUnstable
+	undocumentedHighlyUnstable
+)
+
+// cmosInstructions contains the instructions of the instruction table of retrogolib that
+// were added by the 65C02 and are not supported by the NMOS 6502.
+var cmosInstructions = set.NewFromSlice([]string{
+	m6502.BraName, m6502.PhxName, m6502.PhyName, m6502.PlxName,
+	m6502.PlyName, m6502.StzName, m6502.TrbName, m6502.TsbName,
+})
+
+// documentedInstructions contains the documented instructions of the NMOS 6502.
+var documentedInstructions = nmosDocumentedInstructions()
+
+// undocumentedInstructions contains the undocumented instructions of the NMOS 6502 for
+// every level. The levels follow asm6f, which only accepts the unstable instructions after
+// .unstable and the highly unstable instructions after .hunstable.
+var undocumentedInstructions = [...]map[string]*m6502.Instruction{
+	undocumentedStable: {
+		m6502.AlrName: m6502.AlrInst,
+		m6502.AncName: m6502.AncInst,
+		m6502.ArrName: m6502.ArrInst,
+		m6502.AxsName: m6502.AxsInst,
+		m6502.DcpName: m6502.DcpInst,
+		m6502.IscName: m6502.IscInst,
+		m6502.LaxName: m6502.LaxInst,
+		m6502.RlaName: m6502.RlaInst,
+		m6502.RraName: m6502.RraInst,
+		m6502.SaxName: m6502.SaxInst,
+		m6502.SloName: m6502.SloInst,
+		m6502.SreName: m6502.SreInst,
+	},
+	undocumentedUnstable: {
+		"ahx":         m6502.ShaInst, // asm6f name
+		m6502.LasName: m6502.LasInst,
+		m6502.ShaName: m6502.ShaInst,
+		m6502.ShxName: m6502.ShxInst,
+		m6502.ShyName: m6502.ShyInst,
+		m6502.TasName: m6502.TasInst,
+	},
+	undocumentedHighlyUnstable: {
+		m6502.AneName: m6502.AneInst,
+		m6502.LaxName: laxImmediateInst,
+		m6502.LxaName: m6502.LxaInst,
+		"xaa":         m6502.AneInst, // asm6f name
+	},
+}
+
+// laxImmediateInst is lax including the immediate addressing of lxa, which asm6f accepts
+// as highly unstable instruction.
+var laxImmediateInst = &m6502.Instruction{
+	Name:       m6502.LaxName,
+	Unofficial: true,
+	Addressing: combinedAddressing(m6502.LaxInst, m6502.LxaInst),
+	ParamFunc:  m6502.LaxInst.ParamFunc,
+}
+
+// cmosReplacedInstructions contains the instructions of the 65C02 that replace the NMOS
+// instructions, as they support additional addressing modes.
+var cmosReplacedInstructions = []*m6502.Instruction{
+	m6502.Adc65C02Inst, m6502.And65C02Inst, m6502.Bit65C02Inst, m6502.Cmp65C02Inst,
+	m6502.Dec65C02Inst, m6502.Eor65C02Inst, m6502.Inc65C02Inst, m6502.Jmp65C02Inst,
+	m6502.Lda65C02Inst, m6502.Ora65C02Inst, m6502.Sbc65C02Inst, m6502.Sta65C02Inst,
+}
+
+// rockwellInstructions contains the bit manipulation and bit branch instructions that
+// Rockwell added to the 65C02.
+var rockwellInstructions = []*m6502.Instruction{
+	m6502.Bbr0, m6502.Bbr1, m6502.Bbr2, m6502.Bbr3, m6502.Bbr4, m6502.Bbr5, m6502.Bbr6, m6502.Bbr7,
+	m6502.Bbs0, m6502.Bbs1, m6502.Bbs2, m6502.Bbs3, m6502.Bbs4, m6502.Bbs5, m6502.Bbs6, m6502.Bbs7,
+	m6502.Rmb0, m6502.Rmb1, m6502.Rmb2, m6502.Rmb3, m6502.Rmb4, m6502.Rmb5, m6502.Rmb6, m6502.Rmb7,
+	m6502.Smb0, m6502.Smb1, m6502.Smb2, m6502.Smb3, m6502.Smb4, m6502.Smb5, m6502.Smb6, m6502.Smb7,
+}
+
+// waiInst is the wait for interrupt instruction of the WDC 65C02.
+var waiInst = &m6502.Instruction{
+	Name: "wai",
+	Addressing: map[m6502.AddressingMode]m6502.OpcodeInfo{
+		m6502.ImpliedAddressing: {Opcode: 0xcb, Size: 1},
+	},
+}
+
+// stpInst is the stop the processor instruction of the WDC 65C02.
+var stpInst = &m6502.Instruction{
+	Name: "stp",
+	Addressing: map[m6502.AddressingMode]m6502.OpcodeInfo{
+		m6502.ImpliedAddressing: {Opcode: 0xdb, Size: 1},
+	},
+}
+
+// cmosVariantInstructions contains the instructions of the 65C02 CPU variants. The
+// undocumented NMOS instructions are not supported by these variants.
+var cmosVariantInstructions = map[string]map[string]*m6502.Instruction{
+	cpu65SC02: cmosVariant(documentedInstructions, cmosReplacedInstructions, cmosNewInstructions()),
+	cpu65C02:  cmosVariant(documentedInstructions, cmosReplacedInstructions, cmosNewInstructions(), rockwellInstructions),
+	cpuW65C02: cmosVariant(documentedInstructions, cmosReplacedInstructions, cmosNewInstructions(), rockwellInstructions,
+		[]*m6502.Instruction{waiInst, stpInst}),
+}
+
+// Instructions contains the instructions of all supported CPU variants. The assembler
+// uses it to look up the details of parsed instructions.
+var Instructions = allInstructions()
+
+// SupportsCPU returns whether the CPU variant with the given lowercase name can be selected.
+func SupportsCPU(name string) bool {
+	return supportedCPUs.Contains(name)
+}
+
+// CPUInstruction returns the instruction with the given name if it is accepted by the
+// selected CPU. Without a selected CPU, the instructions that the 65C02 added are accepted
+// as well, like in previous versions; selecting the 6502 CPU rejects them.
+func CPUInstruction(cpu ast.CPU, name string) (*m6502.Instruction, bool) {
+	if instructions, ok := cmosVariantInstructions[cpu.Name]; ok {
+		ins, ok := instructions[name]
+		return ins, ok
+	}
+
+	for level := undocumentedLevel(cpu); level > documented; level-- {
+		if ins, ok := undocumentedInstructions[level][name]; ok {
+			return ins, true
+		}
+	}
+	if ins, ok := documentedInstructions[name]; ok {
+		return ins, true
+	}
+	if cpu.Name == "" && cmosInstructions.Contains(name) {
+		return m6502.Instructions[name], true
+	}
+	return nil, false
+}
+
+// undocumentedLevel returns the highest level of undocumented instructions that the CPU
+// accepts.
+func undocumentedLevel(cpu ast.CPU) int {
+	switch {
+	case cpu.Name == cpu6502X || cpu.HighlyUnstable:
+		return undocumentedHighlyUnstable
+	case cpu.Unstable:
+		return undocumentedUnstable
+	case cpu.Undocumented:
+		return undocumentedStable
+	default:
+		return documented
+	}
+}
+
+func nmosDocumentedInstructions() map[string]*m6502.Instruction {
+	instructions := make(map[string]*m6502.Instruction, len(m6502.Instructions))
+	for name, ins := range m6502.Instructions {
+		if !ins.Unofficial && !cmosInstructions.Contains(name) {
+			instructions[name] = ins
+		}
+	}
+	return instructions
+}
+
+func allInstructions() map[string]*m6502.Instruction {
+	instructions := maps.Clone(m6502.Instructions)
+	for _, level := range undocumentedInstructions[undocumentedStable:] {
+		maps.Copy(instructions, level)
+	}
+	// the 65C02 instructions are supersets of the NMOS instructions that they replace
+	maps.Copy(instructions, cmosVariantInstructions[cpuW65C02])
+	return instructions
+}
+
+// cmosNewInstructions returns the instructions that the 65C02 added to the NMOS 6502.
+func cmosNewInstructions() []*m6502.Instruction {
+	instructions := make([]*m6502.Instruction, 0, len(cmosInstructions))
+	for name := range cmosInstructions {
+		instructions = append(instructions, m6502.Instructions[name])
+	}
+	return instructions
+}
+
+// cmosVariant returns the instructions of a 65C02 variant, based on the documented NMOS
+// instructions and extended by the given instruction lists.
+func cmosVariant(base map[string]*m6502.Instruction, extensions ...[]*m6502.Instruction) map[string]*m6502.Instruction {
+	instructions := maps.Clone(base)
+	for _, extension := range extensions {
+		for _, ins := range extension {
+			instructions[ins.Name] = ins
+		}
+	}
+	return instructions
+}
+
+// combinedAddressing returns the addressing modes of both instructions.
+func combinedAddressing(ins, additional *m6502.Instruction) map[m6502.AddressingMode]m6502.OpcodeInfo {
+	addressing := maps.Clone(ins.Addressing)
+	maps.Copy(addressing, additional.Addressing)
+	return addressing
+}
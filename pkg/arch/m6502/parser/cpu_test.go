@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/retroenv/retroasm/pkg/parser/ast"
+	"github.com/retroenv/retrogolib/arch/cpu/m6502"
+	"github.com/retroenv/retrogolib/assert"
+)
+
+func TestCPUInstruction(t *testing.T) {
+	stable := ast.CPU{Undocumented: true}
+	unstable := ast.CPU{Undocumented: true, Unstable: true}
+	highlyUnstable := ast.CPU{HighlyUnstable: true}
+
+	tests := []struct {
+		name        string
+		cpu         ast.CPU
+		instruction string
+		found       bool
+		wantName    string
+		immediate   bool // whether the instruction supports immediate addressing
+	}{
+		{name: "documented", cpu: ast.CPU{}, instruction: "lda", found: true, wantName: "lda", immediate: true},
+		{name: "undocumented not selected", cpu: ast.CPU{}, instruction: "lax"},
+		{name: "65c02 without cpu", cpu: ast.CPU{}, instruction: "stz", found: true, wantName: "stz"},
+		{name: "65c02 on 6502", cpu: ast.CPU{Name: cpu6502}, instruction: "stz"},
+		{name: "rockwell without cpu", cpu: ast.CPU{}, instruction: "rmb0"},
+		{name: "stable", cpu: stable, instruction: "alr", found: true, wantName: "alr", immediate: true},
+		{name: "stable lax", cpu: stable, instruction: "lax", found: true, wantName: "lax"},
+		{name: "unstable not selected", cpu: stable, instruction: "shx"},
+		{name: "unstable asm6f name", cpu: unstable, instruction: "ahx", found: true, wantName: m6502.ShaName},
+		{name: "highly unstable not selected", cpu: unstable, instruction: "xaa"},
+		{name: "highly unstable lax", cpu: highlyUnstable, instruction: "lax", found: true, wantName: "lax", immediate: true},
+		{name: "6502x", cpu: ast.CPU{Name: cpu6502X}, instruction: "lxa", found: true, wantName: "lxa", immediate: true},
+		{name: "65sc02", cpu: ast.CPU{Name: cpu65SC02}, instruction: "stz", found: true, wantName: "stz"},
+		{name: "65sc02 bit", cpu: ast.CPU{Name: cpu65SC02}, instruction: "bit", found: true, wantName: "bit", immediate: true},
+		{name: "65sc02 rockwell", cpu: ast.CPU{Name: cpu65SC02}, instruction: "rmb0"},
+		{name: "65c02 rockwell", cpu: ast.CPU{Name: cpu65C02}, instruction: "bbs7", found: true, wantName: "bbs7"},
+		{name: "65c02 wdc", cpu: ast.CPU{Name: cpu65C02}, instruction: "wai"},
+		{name: "65c02 undocumented", cpu: ast.CPU{Name: cpu65C02, HighlyUnstable: true}, instruction: "lax"},
+		{name: "w65c02", cpu: ast.CPU{Name: cpuW65C02}, instruction: "stp", found: true, wantName: "stp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ins, ok := CPUInstruction(tt.cpu, tt.instruction)
+			assert.Equal(t, tt.found, ok)
+			if !tt.found {
+				return
+			}
+			assert.Equal(t, tt.wantName, ins.Name)
+			assert.Equal(t, tt.immediate, ins.HasAddressing(m6502.ImmediateAddressing))
+		})
+	}
+}
+
+func TestSupportsCPU(t *testing.T) {
+	assert.True(t, SupportsCPU("6502"))
+	assert.True(t, SupportsCPU("6502x"))
+	assert.True(t, SupportsCPU("65c02"))
+	assert.True(t, SupportsCPU("w65c02"))
+	assert.False(t, SupportsCPU("z80"))
+}
@@ -59,6 +59,10 @@ func parseInstruction(parser arch.Parser, instructionDetails *m6502.Instruction)
 	ins.arg1 = resolveArg1Token(parser)
 	ins.modifiers = directives.ParseModifier(parser)
 
+	if instructionDetails.HasAddressing(m6502.ZeroPageRelativeAddressing) {
+		return parseInstructionZeroPageRelative(parser, ins)
+	}
+
 	next1 := parser.NextToken(1)
 	if next1.Type == token.Comma {
 		parser.AdvanceReadPosition(2)
@@ -136,8 +140,13 @@ func parseInstructionParentheses(parser arch.Parser, ins *instruction) (ast.Node
 }
 
 func parseInstructionIndirect(ins *instruction) (ast.Node, error) {
-	if !ins.instruction.HasAddressing(m6502.IndirectAddressing) {
-		return nil, errors.New("invalid indirect addressing mode usage")
+	// jmp uses an absolute address, the 65C02 supports a zero page address for the other instructions
+	addressing := m6502.IndirectAddressing
+	if !ins.instruction.HasAddressing(addressing) {
+		addressing = m6502.ZeroPageIndirectAddressing
+		if !ins.instruction.HasAddressing(addressing) {
+			return nil, errors.New("invalid indirect addressing mode usage")
+		}
 	}
 
 	// Parentheses select indirect addressing regardless of the operand's value;
@@ -156,7 +165,7 @@ func parseInstructionIndirect(ins *instruction) (ast.Node, error) {
 		return nil, fmt.Errorf("invalid indirect argument type %s", ins.arg1.Type)
 	}
 
-	return newInstruction(ins.instruction, int(m6502.IndirectAddressing), argument, ins.modifiers), nil
+	return newInstruction(ins.instruction, int(addressing), argument, ins.modifiers), nil
 }
 
 func parseInstructionSingleIdentifier(parser arch.Parser, ins *instruction) (ast.Node, error) {
@@ -259,6 +268,44 @@ func parseBranchingInstruction(parser arch.Parser, ins *instruction) (ast.Node,
 	return newInstruction(ins.instruction, int(addressing), l, nil), nil
 }
 
+// parseInstructionZeroPageRelative parses the zero page address and branch target of the
+// bbr and bbs instructions of the Rockwell 65C02: BBR0 zp, label.
+func parseInstructionZeroPageRelative(parser arch.Parser, ins *instruction) (ast.Node, error) {
+	if parser.NextToken(1).Type != token.Comma {
+		return nil, errors.New("missing branch target")
+	}
+
+	address, err := operandNode(ins.arg1)
+	if err != nil {
+		return nil, fmt.Errorf("parsing zero page address: %w", err)
+	}
+
+	parser.AdvanceReadPosition(2)
+	target, err := operandNode(resolveArg1Token(parser))
+	if err != nil {
+		return nil, fmt.Errorf("parsing branch target: %w", err)
+	}
+
+	argument := ast.NewInstructionArguments(address, target)
+	return newInstruction(ins.instruction, int(m6502.ZeroPageRelativeAddressing), argument, ins.modifiers), nil
+}
+
+// operandNode returns the node of a number or label operand.
+func operandNode(tok token.Token) (ast.Node, error) {
+	switch tok.Type {
+	case token.Identifier:
+		return ast.NewLabel(tok.Value), nil
+	case token.Number:
+		value, err := number.Parse(tok.Value)
+		if err != nil {
+			return nil, fmt.Errorf("parsing number '%s': %w", tok.Value, err)
+		}
+		return ast.NewNumber(value), nil
+	default:
+		return nil, fmt.Errorf("unsupported argument type %s", tok.Type)
+	}
+}
+
 func parseInstructionSecondIdentifier(ins *instruction, indirectAccess bool) (ast.Node, error) {
 	addressings, err := extendedAddressingParam(ins, indirectAccess)
 	if err != nil {
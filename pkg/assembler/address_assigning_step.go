@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -13,14 +14,25 @@ import (
 	"github.com/retroenv/retroasm/pkg/scope"
 )
 
+// maxAddressPasses is the maximum number of address assigning passes. Forward references
+// use absolute addressing in the first pass, every further pass resolves them with the
+// addresses of the previous pass until no address changes.
+const maxAddressPasses = 16
+
+var errAddressesNotConverging = errors.New("addresses do not converge")
+
 type addressAssign[T any] struct {
 	arch arch.Architecture[T]
 
 	currentScope   *scope.Scope // current scope, can be a function scope with file scope as parent
 	programCounter uint64
+	offsetCounter  uint64 // NESASM .rs variable counter, set by .rsset
+	bank           bankSelection
 
 	enumActive               bool
 	enumBackupProgramCounter uint64
+
+	addresses []uint64 // program counter after every node, compared between the passes
 }
 
 // ArgumentValue returns the value of an instruction argument, either a number or a symbol value.
@@ -41,6 +53,10 @@ func (aa *addressAssign[T]) ArgumentValue(argument any) (uint64, error) {
 		}
 
 		value, err := sym.Value(aa.currentScope)
+		if errors.Is(err, scope.ErrForwardReference) && sym.ZeroPage() {
+			// declared zero page symbols use zero page addressing before their address is known
+			return 0, nil
+		}
 		if err != nil {
 			return 0, fmt.Errorf("getting symbol '%s' value: %w", name, err)
 		}
@@ -81,7 +97,7 @@ func (aa *addressAssign[T]) RelativeOffset(destination, addressAfterInstruction
 
 	switch {
 	case diff < -128 || diff > 127:
-		return 0, fmt.Errorf("relative distance %d exceeds limit", diff)
+		return 0, fmt.Errorf("%w: relative distance %d exceeds limit", arch.ErrBranchOutOfRange, diff)
 
 	case diff >= 0:
 		return byte(diff), nil
@@ -137,56 +153,185 @@ func (aa *addressAssign[T]) addressWidth() int {
 	return aa.arch.AddressWidth()
 }
 
-// assignAddressesStep assigns an address for every node in each scope.
+// assignAddressesStep assigns an address for every node in each scope. The segments are
+// placed one after another in their memory areas in the order of the configuration.
+// The addresses are assigned in multiple passes to shrink forward references to zero
+// page addresses, until the addresses of a pass match the previous pass.
 func assignAddressesStep[T any](_ context.Context, asm *Assembler[T]) error {
-	var err error
+	var previous []uint64
+
+	for range maxAddressPasses {
+		addresses, err := assignAddressesPass(asm)
+		if err != nil {
+			return err
+		}
+		if slices.Equal(addresses, previous) {
+			return nil
+		}
+		previous = addresses
+	}
+
+	return fmt.Errorf("%w after %d passes", errAddressesNotConverging, maxAddressPasses)
+}
+
+// assignAddressesPass assigns an address for every node and returns the program counter
+// after every node.
+func assignAddressesPass[T any](asm *Assembler[T]) ([]uint64, error) {
+	var errs []error
 	aa := addressAssign[T]{
 		arch:         asm.cfg.Arch,
 		currentScope: asm.fileScope,
 	}
+	layout := newMemoryLayout(asm.cfg.Memories)
 
-	for _, seg := range asm.segmentsOrder {
-		aa.programCounter = seg.config.Start
+	for _, segCfg := range asm.cfg.SegmentsOrdered {
+		seg, ok := asm.segments[segCfg.SegmentName]
+		if !ok {
+			if asm.object != nil {
+				continue // an object file only contains the fragments of the used segments
+			}
+			if !segCfg.Optional && !ca65DefaultSegments.Contains(segCfg.SegmentName) {
+				errs = append(errs, fmt.Errorf("%w: '%s'", errSegmentMissing, segCfg.SegmentName))
+			}
+			if !segCfg.Define {
+				continue
+			}
+			seg = &segment{config: segCfg} // place unused segments for their define symbols
+		}
 
-		for _, node := range seg.nodes {
-			switch n := node.(type) {
-			case ast.Base:
-				aa.programCounter, err = assignBaseAddress(n)
+		segErrs, err := assignSegmentAddresses(asm, &aa, layout, seg)
+		errs = append(errs, segErrs...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("placing segment '%s': %w", segCfg.SegmentName, err))
+		}
+	}
 
-			case ast.Configuration:
+	return aa.addresses, errors.Join(errs...)
+}
 
-			case ast.Enum:
-				aa.programCounter, err = assignEnumAddress(&aa, n)
+// assignSegmentAddresses places the segment in its memory area and assigns an address for
+// every node of the segment. It returns the errors of the nodes and an error of the placement.
+func assignSegmentAddresses[T any](asm *Assembler[T], aa *addressAssign[T], layout *memoryLayout,
+	seg *segment) ([]error, error) {
 
-			case ast.EnumEnd:
-				aa.programCounter, err = assignEnumEndAddress(&aa)
+	var errs []error
 
-			case *data:
-				aa.programCounter, err = assignDataAddress(aa, n)
+	load, run, err := layout.segmentStart(seg.config)
+	if err != nil {
+		return nil, err
+	}
+	seg.start = load
+	seg.runStart = run
+	aa.programCounter = run
+	aa.bank = newBankSelection(asm.cfg.Memories, seg.config)
+	end := run
+
+	for _, node := range seg.nodes {
+		supported, err := assignNodeAddress(asm, aa, seg, node)
+		if !supported {
+			return errs, fmt.Errorf("unsupported node type %T", node)
+		}
+		if err != nil {
+			errs = append(errs, nodeError(node, err))
+		}
+		if !aa.enumActive && !aa.bank.active {
+			end = max(end, aa.programCounter)
+		}
+		aa.addresses = append(aa.addresses, aa.programCounter)
+	}
 
-			case *instruction:
-				aa.programCounter, err = aa.arch.AssignInstructionAddress(&aa, n)
+	// the size of banked code depends on the used banks instead of the CPU addresses
+	seg.size = max(end-run, aa.bank.size)
+	layout.finishSegment(seg.config, load, run, seg.size)
+	if seg.config.Define && asm.object == nil {
+		if err := setSegmentDefineSymbols(asm.fileScope, seg.config, load, run, seg.size); err != nil {
+			return errs, err
+		}
+	}
+	return errs, nil
+}
 
-			case scopeChange:
-				aa.currentScope = n.scope
+// assignNodeAddress assigns the address of a node of the segment and advances the
+// program counter. It returns false for an unsupported node type.
+func assignNodeAddress[T any](asm *Assembler[T], aa *addressAssign[T], seg *segment, node ast.Node) (bool, error) {
+	var err error
 
-			case *symbol:
-				err = assignSymbolAddress(aa, n)
+	switch n := node.(type) {
+	case ast.Bank:
+		err = assignBank(asm, aa, n)
 
-			case *variable:
-				aa.programCounter = assignVariableAddress(aa, n)
+	case ast.Base:
+		aa.programCounter, err = assignBaseAddress(n)
+		aa.bank.setProgramCounter(aa.programCounter)
 
-			default:
-				return fmt.Errorf("unsupported node type %T", n)
-			}
+	case ast.Configuration:
+		asm.inesHeader.setConfiguration(n)
 
-			if err != nil {
-				return err
-			}
-		}
+	case ast.Enum:
+		aa.programCounter, err = assignEnumAddress(aa, n)
+
+	case ast.OffsetCounter:
+		aa.offsetCounter = n.Number
+
+	case ast.EnumEnd:
+		aa.programCounter, err = assignEnumEndAddress(aa)
+
+	case *data:
+		aa.programCounter, err = assignDataAddress(*aa, n)
+		err = errors.Join(err, aa.bank.place(aa.programCounter))
+
+	case *instruction:
+		// ambiguous addressing modes are resolved again with the addresses of the last pass
+		n.addressing = n.sourceAddressing
+		aa.programCounter, err = assignInstructionAddress(asm, aa, n)
+		err = errors.Join(err, aa.bank.place(aa.programCounter))
+
+	case scopeChange:
+		aa.currentScope = n.scope
+
+	case *symbol:
+		err = assignSymbolAddress(*aa, seg, n)
+
+	case *variable:
+		aa.programCounter = assignVariableAddress(aa, seg, n)
+
+	default:
+		return false, nil
 	}
 
-	return nil
+	return true, err
+}
+
+// assignInstructionAddress assigns the address of an instruction and returns the program
+// counter after it. If long branches are enabled, a branch whose target is out of range
+// is expanded and keeps its expanded size in all following passes.
+func assignInstructionAddress[T any](asm *Assembler[T], aa *addressAssign[T], ins *instruction) (uint64, error) {
+	programCounter, err := aa.arch.AssignInstructionAddress(aa, ins)
+	if err != nil || !asm.longBranches {
+		return programCounter, err //nolint:wrapcheck // architecture errors are wrapped by the node error
+	}
+
+	expander, ok := aa.arch.(arch.BranchExpander)
+	if !ok {
+		return programCounter, nil
+	}
+	size, ok := expander.ExpandedBranchSize(ins)
+	if !ok {
+		return programCounter, nil
+	}
+
+	if !ins.longBranch {
+		// the target of a forward reference is checked with the addresses of the next pass
+		target, err := aa.ArgumentValue(ins.argument)
+		if err != nil {
+			return programCounter, nil //nolint:nilerr // the opcode generation reports invalid arguments
+		}
+		if _, err := aa.RelativeOffset(target, programCounter); err == nil {
+			return programCounter, nil
+		}
+		ins.longBranch = true
+	}
+	return ins.address + uint64(size), nil
 }
 
 // parseReferenceOffset splits a reference name into a base symbol name and
@@ -246,9 +391,24 @@ func assignDataAddress[T any](aa addressAssign[T], d *data) (uint64, error) {
 	return aa.programCounter, nil
 }
 
-func assignVariableAddress[T any](aa addressAssign[T], v *variable) uint64 {
-	v.address = aa.programCounter
-	aa.programCounter += uint64(v.v.Size)
+// assignVariableAddress assigns the address of a variable. NESASM .rs variables
+// are placed at the offset counter and do not reserve space in the segment.
+func assignVariableAddress[T any](aa *addressAssign[T], seg *segment, v *variable) uint64 {
+	if v.v.UseOffsetCounter {
+		v.address = aa.offsetCounter
+		aa.offsetCounter += uint64(v.v.Size)
+	} else {
+		v.address = aa.programCounter
+		aa.programCounter += uint64(v.v.Size)
+	}
+
+	if v.symbol != nil {
+		v.symbol.SetAddress(v.address)
+		v.symbol.SetSegment(seg.config.SegmentName)
+		if !v.v.UseOffsetCounter {
+			setSymbolPlacement(aa, seg, v.symbol, v.address)
+		}
+	}
 	return aa.programCounter
 }
 
@@ -260,8 +420,12 @@ func assignBaseAddress(b ast.Base) (uint64, error) {
 	return uint64(i), nil
 }
 
-func assignSymbolAddress[T any](aa addressAssign[T], sym *symbol) error {
+func assignSymbolAddress[T any](aa addressAssign[T], seg *segment, sym *symbol) error {
 	sym.SetAddress(aa.programCounter)
+	if typ := sym.Type(); typ == scope.LabelType || typ == scope.FunctionType {
+		sym.SetSegment(seg.config.SegmentName)
+		setSymbolPlacement(&aa, seg, sym.Symbol, aa.programCounter)
+	}
 	exp := sym.Expression()
 	if exp != nil && exp.IsEvaluatedAtAddressAssign() {
 		_, err := exp.EvaluateAtProgramCounter(aa.currentScope, aa.arch.AddressWidth(), aa.programCounter)
@@ -272,6 +436,25 @@ func assignSymbolAddress[T any](aa addressAssign[T], sym *symbol) error {
 	return nil
 }
 
+// setSymbolPlacement sets the bank and the load address of a symbol that is placed at
+// the given address of the segment. Symbols of an enum are not placed in the segment.
+func setSymbolPlacement[T any](aa *addressAssign[T], seg *segment, sym *scope.Symbol, address uint64) {
+	if bank, ok := aa.bank.symbolBank(); ok {
+		sym.SetBank(bank)
+	}
+	if !aa.enumActive {
+		sym.SetLoadAddress(aa.bank.loadAddress(seg, address))
+	}
+}
+
+// assignBank selects the bank that the following nodes of the segment are placed in.
+func assignBank[T any](asm *Assembler[T], aa *addressAssign[T], bank ast.Bank) error {
+	if asm.object != nil {
+		return errObjectBank
+	}
+	return aa.bank.selectBank(bank, aa.programCounter)
+}
+
 func assignEnumAddress[T any](aa *addressAssign[T], e ast.Enum) (uint64, error) {
 	if aa.enumActive {
 		return 0, errors.New("invalid enum inside enum context")
@@ -3,6 +3,7 @@ package assembler
 import (
 	"testing"
 
+	"github.com/retroenv/retroasm/pkg/assembler/config"
 	"github.com/retroenv/retroasm/pkg/lexer/token"
 	"github.com/retroenv/retroasm/pkg/parser/ast"
 	"github.com/retroenv/retroasm/pkg/scope"
@@ -40,11 +41,35 @@ func TestAssignVariableAddress(t *testing.T) {
 		v: ast.NewVariable("test", 4),
 	}
 
-	result := assignVariableAddress(aa, v)
+	result := assignVariableAddress(&aa, &segment{config: &config.Segment{SegmentName: "BSS"}}, v)
 	assert.Equal(t, uint64(0x204), result)
 	assert.Equal(t, uint64(0x200), v.address)
 }
 
+func TestAssignVariableAddressOffsetCounter(t *testing.T) {
+	aa := addressAssign[any]{
+		programCounter: 0x8000,
+		offsetCounter:  0x10,
+	}
+	astVar := ast.NewVariable("counter", 2)
+	astVar.UseOffsetCounter = true
+	sym, err := scope.NewSymbol(scope.New(nil), "counter", scope.VariableType)
+	assert.NoError(t, err)
+	v := &variable{
+		v:      astVar,
+		symbol: sym,
+	}
+
+	result := assignVariableAddress(&aa, &segment{config: &config.Segment{SegmentName: "CODE"}}, v)
+	assert.Equal(t, uint64(0x8000), result)
+	assert.Equal(t, uint64(0x12), aa.offsetCounter)
+
+	value, err := sym.Value(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0x10), value)
+	assert.Equal(t, "CODE", sym.Segment())
+}
+
 func TestAddressAssign_ArgumentValueExpression(t *testing.T) {
 	aa := addressAssign[any]{
 		currentScope:   scope.New(nil),
@@ -71,3 +96,72 @@ func TestAddressAssign_ArgumentValueExpression(t *testing.T) {
 		assert.Equal(t, uint64(0x201), value)
 	})
 }
+
+func TestAssignAddressesStepForwardReferences(t *testing.T) {
+	testConfig := `
+MEMORY {
+    ZP:  start = $0000, size = $100;
+    PRG: start = $8000, size = $100;
+}
+SEGMENTS {
+    CODE:     load = PRG, type = ro;
+    ZEROPAGE: load = ZP, type = zp;
+}
+`
+
+	tests := []struct {
+		name     string
+		code     string
+		expected []byte
+	}{
+		{
+			name: "zero page label of a later segment",
+			code: `.segment "CODE"
+lda ptr
+sta ptr,x
+.segment "ZEROPAGE"
+.res 2
+ptr: .res 1
+`,
+			expected: []byte{0xa5, 0x02, 0x95, 0x02},
+		},
+		{
+			name: "absolute label",
+			code: `.segment "CODE"
+lda data
+data: .byte 1
+`,
+			expected: []byte{0xad, 0x03, 0x80, 0x01},
+		},
+		{
+			name: "alias of a later label",
+			code: `.segment "CODE"
+value = data - $8000
+lda value
+data: .byte 1
+`,
+			expected: []byte{0xa5, 0x02, 0x01},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := runAsm6Test(t, testConfig, tt.code)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, output)
+		})
+	}
+}
+
+func TestAssignAddressesStepNotConverging(t *testing.T) {
+	// the zero page form of the instruction moves the label so that the value
+	// needs absolute addressing and the other way around
+	code := `.segment "HEADER"
+value = $102 - target
+lda value
+target:
+`
+
+	_, err := runAsm6Test(t, unitTestConfig, code)
+	assert.ErrorIs(t, err, errAddressesNotConverging)
+}
@@ -17,11 +17,14 @@
 package assembler
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 
 	"github.com/retroenv/retroasm/pkg/assembler/config"
 	"github.com/retroenv/retroasm/pkg/parser"
@@ -30,7 +33,10 @@ import (
 	"github.com/retroenv/retrogolib/set"
 )
 
-var errNoCurrentSegment = errors.New("no current segment found")
+var (
+	errNoCurrentSegment    = errors.New("no current segment found")
+	errObjectMultipleFiles = errors.New("object mode supports only a single source file")
+)
 
 // Assembler is the assembler implementation for retro computer systems.
 // It processes assembly language and converts it into machine code through
@@ -40,14 +46,59 @@ type Assembler[T any] struct {
 	writer io.Writer
 
 	// a function that reads in a file, for testing includes, defaults to os.ReadFile
-	fileReader func(name string) ([]byte, error)
+	fileReader  func(name string) ([]byte, error)
+	includeDirs []string // directories that included files are searched in
+
+	defines map[string]uint64 // symbols that are defined in the file scope before parsing
+
+	longBranches bool         // expand branches whose target is out of range
+	notes        []Diagnostic // diagnostics of a successful assembly, like expanded branches
 
-	fileScope *scope.Scope // scope for current to be parsed file
+	sourceName  string          // file name of the processed source, set in all source positions
+	sourceNames []string        // file names of all processed sources, in the order of processing
+	sources     *sourceRecorder // read source files and expansions, used for the listing
+
+	dependencies *dependencyRecorder // names of all read files
+
+	// scope for current to be parsed file, the global scope that is the parent of all
+	// file scopes if multiple files are processed
+	fileScope *scope.Scope
+	// set if every file is parsed in its own scope that is a child scope of fileScope
+	childFileScopes bool
 
 	segments      map[string]*segment // maps segment name to segment
 	segmentsOrder []*segment          // sorted list of all parsed segments
 
-	macros map[string]macro
+	macros       map[macroKey]macro
+	declarations []visibilityDeclaration // symbols of .export, .import and .global directives
+
+	inesHeader inesHeader // iNES header configured by NESASM directives
+
+	outputName  string                // name of the main output file, replaces %O in memory file names
+	outputFiles []OutputFile          // additional output files of memories with a different file name
+	memoryFiles map[string]memoryFile // maps memory name to its output file, set for written memories
+
+	object     *objectState // set when a relocatable object file is written instead of a binary
+	autoImport bool         // import all undefined symbols in object mode
+}
+
+// SourceFile is a source file of a program that is assembled from multiple files.
+type SourceFile struct {
+	Name   string
+	Reader io.Reader
+}
+
+// OutputFile is an additional output file that contains the memory areas whose file
+// attribute names a different file than the main output.
+type OutputFile struct {
+	Name string
+	Data []byte
+}
+
+// memoryFile describes the location of a memory area in an output file.
+type memoryFile struct {
+	name   string // name of the additional output file, empty for the main output
+	offset uint64 // offset of the memory start in the file
 }
 
 // New returns a new assembler.
@@ -58,27 +109,143 @@ func New[T any](cfg *config.Config[T], writer io.Writer) *Assembler[T] {
 
 		fileReader: os.ReadFile,
 
-		fileScope: scope.New(nil),
+		sources:      newSourceRecorder(),
+		dependencies: newDependencyRecorder(),
+		fileScope:    scope.New(nil),
 
-		macros: map[string]macro{},
+		macros: map[macroKey]macro{},
 	}
 }
 
+// SetSourceName sets the file name of the source that Process reads. The name is
+// part of the source positions of nodes, symbols and errors.
+func (asm *Assembler[T]) SetSourceName(name string) {
+	asm.sourceName = name
+}
+
+// SetDefines sets symbols that are defined as constants in the file scope before the
+// source is parsed, for example to select conditionally assembled code by .ifdef.
+func (asm *Assembler[T]) SetDefines(defines map[string]uint64) {
+	asm.defines = defines
+}
+
+// SetLongBranches sets whether branches whose target is out of range are replaced by an
+// inverted branch over a jump, or by a jump for unconditional branches. The .feature
+// longbranch directive enables it from the source.
+func (asm *Assembler[T]) SetLongBranches(enabled bool) {
+	asm.longBranches = enabled
+}
+
+// Notes returns the diagnostics that did not fail the assembly, like the branches that
+// were expanded because their target is out of range. Call this after Process.
+func (asm *Assembler[T]) Notes() []Diagnostic {
+	return asm.notes
+}
+
+// SetIncludeDirs sets the directories that included files are searched in if they are
+// not found relative to the including file.
+func (asm *Assembler[T]) SetIncludeDirs(dirs []string) {
+	asm.includeDirs = dirs
+}
+
+// SetFileSystem sets the file system that included files are read from instead of the
+// disk. File names are used as slash separated paths of the file system.
+func (asm *Assembler[T]) SetFileSystem(fsys fs.FS) {
+	asm.fileReader = func(name string) ([]byte, error) {
+		return fs.ReadFile(fsys, filepath.ToSlash(name)) //nolint:wrapcheck // wrapped by the include parsing
+	}
+}
+
+// SetOutputName sets the name of the main output file. It replaces the %O reference in
+// the file attributes of memory areas, memory areas with a different file name are
+// returned by OutputFiles.
+func (asm *Assembler[T]) SetOutputName(name string) {
+	asm.outputName = name
+}
+
+// OutputFiles returns the additional output files of memory areas whose file attribute
+// names a different file than the main output. Call this after Process.
+func (asm *Assembler[T]) OutputFiles() []OutputFile {
+	return asm.outputFiles
+}
+
 // Process processes assembly source code from a reader and assembles it into the output writer.
 // This is the primary text-based API for CLI usage. For library integration with
 // pre-parsed AST nodes, use ProcessAST instead.
 func (asm *Assembler[T]) Process(ctx context.Context, inputReader io.Reader) error {
+	nodes, err := asm.parseSource(ctx, asm.sourceName, inputReader)
+	if err != nil {
+		return err
+	}
+
+	return asm.ProcessAST(ctx, nodes)
+}
+
+// ProcessFiles assembles multiple source files into one program and writes it to the
+// output writer. Every file is parsed with its own file scope and all files share the
+// segments of the configuration. Symbols that a file exports by visibility directives
+// are visible to all other files.
+func (asm *Assembler[T]) ProcessFiles(ctx context.Context, files []SourceFile) error {
+	if asm.object != nil {
+		// object files of multiple source files are combined by the linker instead
+		if len(files) != 1 {
+			return errObjectMultipleFiles
+		}
+		asm.sourceName = files[0].Name
+		return asm.Process(ctx, files[0].Reader)
+	}
+	if len(files) > 0 {
+		asm.sourceName = files[0].Name
+	}
+
+	p, err := asm.newParseAST()
+	if err != nil {
+		return fmt.Errorf("parsing AST nodes: %w", err)
+	}
+	asm.childFileScopes = true
+
+	var errs []error
+	for _, file := range files {
+		nodes, err := asm.parseSource(ctx, file.Name, file.Reader)
+		if err != nil {
+			return fmt.Errorf("file '%s': %w", file.Name, err)
+		}
+
+		p.enterFile(scope.New(asm.fileScope))
+		if err := asm.parseNodes(ctx, p, nodes); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	p.enterFile(asm.fileScope)
+
+	if err := errors.Join(append(errs, asm.finishParsing(p))...); err != nil {
+		return fmt.Errorf("parsing AST nodes: %w", err)
+	}
+
+	return asm.runSteps(ctx)
+}
+
+// parseSource reads the source and converts it to AST nodes.
+func (asm *Assembler[T]) parseSource(ctx context.Context, name string, reader io.Reader) ([]ast.Node, error) {
+	source, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("reading source: %w", err)
+	}
+	asm.sources.addSource(name, source)
+	asm.sourceNames = append(asm.sourceNames, name)
+	asm.dependencies.add(name)
+
 	// Parse AST nodes first
-	pars := parser.New[T](asm.cfg.Arch, inputReader, asm.cfg.CompatibilityMode)
+	pars := parser.New[T](asm.cfg.Arch, bytes.NewReader(source), asm.cfg.CompatibilityMode)
+	pars.SetSource(name, nil)
 	if err := pars.Read(ctx); err != nil {
-		return fmt.Errorf("parsing lexer tokens: %w", err)
+		return nil, fmt.Errorf("parsing lexer tokens: %w", err)
 	}
 	nodes, err := pars.TokensToAstNodes()
 	if err != nil {
-		return fmt.Errorf("converting tokens to ast nodes: %w", err)
+		return nil, fmt.Errorf("converting tokens to ast nodes: %w", err)
 	}
-
-	return asm.ProcessAST(ctx, nodes)
+	return nodes, nil
 }
 
 // ProcessAST processes pre-parsed AST nodes and assembles them into the output writer.
@@ -91,6 +258,11 @@ func (asm *Assembler[T]) ProcessAST(ctx context.Context, nodes []ast.Node) error
 	}
 
 	// Then run the remaining assembly steps
+	return asm.runSteps(ctx)
+}
+
+// runSteps runs all assembler steps that follow the parsing of the AST nodes.
+func (asm *Assembler[T]) runSteps(ctx context.Context) error {
 	steps := asm.Steps()
 	for i, stp := range steps {
 		// Check for cancellation before each step
@@ -116,13 +288,41 @@ func (asm *Assembler[T]) Symbols() map[string]uint64 {
 
 // parseASTNodes processes the given AST nodes and converts them to internal types.
 func (asm *Assembler[T]) parseASTNodes(ctx context.Context, nodes []ast.Node) error {
+	p, err := asm.newParseAST()
+	if err != nil {
+		return err
+	}
+
+	if err := asm.parseNodes(ctx, p, nodes); err != nil {
+		return errors.Join(err, asm.finishParsing(p))
+	}
+	return asm.finishParsing(p)
+}
+
+// newParseAST returns the state for parsing the AST nodes of the source files.
+func (asm *Assembler[T]) newParseAST() (*parseAST[T], error) {
 	p := &parseAST[T]{
 		cfg:           asm.cfg,
 		fileReader:    asm.fileReader,
+		includeDirs:   asm.includeDirs,
+		sources:       asm.sources,
+		dependencies:  asm.dependencies,
 		includeActive: set.New[string](),
+		fileScope:     asm.fileScope,
 		currentScope:  asm.fileScope,
 		segments:      map[string]*segment{},
+		longBranches:  &asm.longBranches,
+	}
+	if err := addDefineSymbols(asm.fileScope, asm.defines); err != nil {
+		return nil, err
 	}
+	// in object mode the linker defines the segment symbols once all fragments are placed
+	if asm.object == nil {
+		if err := addSegmentDefineSymbols(asm.fileScope, asm.cfg.SegmentsOrdered); err != nil {
+			return nil, err
+		}
+	}
+
 	if len(asm.cfg.SegmentsOrdered) == 1 {
 		segCfg := asm.cfg.SegmentsOrdered[0]
 		seg := &segment{
@@ -132,6 +332,14 @@ func (asm *Assembler[T]) parseASTNodes(ctx context.Context, nodes []ast.Node) er
 		p.segments[seg.config.SegmentName] = seg
 		p.segmentsOrder = append(p.segmentsOrder, seg)
 	}
+	return p, nil
+}
+
+// parseNodes converts the AST nodes of a source file to internal types and adds them
+// to their segments.
+func (asm *Assembler[T]) parseNodes(ctx context.Context, p *parseAST[T], nodes []ast.Node) error {
+	// errors of independent nodes are collected to report all of them at once
+	var errs []error
 
 	for _, node := range nodes {
 		// Check for cancellation in the parsing loop
@@ -145,17 +353,38 @@ func (asm *Assembler[T]) parseASTNodes(ctx context.Context, nodes []ast.Node) er
 
 		case ast.Segment:
 			if err := parseSegment(p, n); err != nil {
-				return fmt.Errorf("parsing segment node: %w", err)
+				errs = append(errs, nodeError(n, fmt.Errorf("parsing segment node: %w", err)))
 			}
 
+		case ast.SymbolVisibility:
+			// visibility directives do not depend on a segment and usually precede the first one
+			parseSymbolVisibility(p, n)
+
+		case ast.CPU:
+			// the CPU is usually selected before the first segment
+			parseCPU(p, n)
+
 		default:
+			// the long branch feature does not depend on a segment
+			if cfg, ok := node.(ast.Configuration); ok && parseLongBranchFeature(p, cfg) {
+				continue
+			}
 			if p.currentSegment == nil {
-				return errNoCurrentSegment
+				// NESASM header directives usually precede the first segment or bank
+				if cfg, ok := node.(ast.Configuration); ok && asm.inesHeader.setConfiguration(cfg) {
+					continue
+				}
+				// banked sources do not select segments, the banks are placed in the code segment
+				_, isBank := node.(ast.Bank)
+				if !isBank || parseSegment(p, ast.NewSegment(codeSegmentName)) != nil {
+					return errors.Join(append(errs, nodeError(node, errNoCurrentSegment))...)
+				}
 			}
 
 			newNodes, err := parseASTNode(ctx, p, node)
 			if err != nil {
-				return err
+				errs = append(errs, nodeError(node, err))
+				continue
 			}
 			for _, newNode := range newNodes {
 				p.currentSegment.addNode(newNode)
@@ -163,8 +392,29 @@ func (asm *Assembler[T]) parseASTNodes(ctx context.Context, nodes []ast.Node) er
 		}
 	}
 
+	return errors.Join(errs...)
+}
+
+// finishParsing adds the nodes and symbols that depend on all parsed files and stores
+// the parsed segments.
+func (asm *Assembler[T]) finishParsing(p *parseAST[T]) error {
+	var errs []error
+
+	if asm.object != nil {
+		// the linker creates the tables of the functions of all object files
+		asm.object.condes = p.condes
+	} else {
+		if err := addCondesTables(p, asm.fileScope); err != nil {
+			errs = append(errs, err)
+		}
+		if err := addConfigSymbols(asm.fileScope, asm.cfg.Symbols); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
 	asm.segments = p.segments
 	asm.segmentsOrder = p.segmentsOrder
+	asm.declarations = p.declarations
 
-	return nil
+	return errors.Join(errs...)
 }
@@ -316,7 +316,7 @@ func TestAssemblerAsm6AlignAlreadyAligned(t *testing.T) {
 	assert.Equal(t, []byte{1, 2, 3, 4, 0xff}, b)
 }
 
-var asm6ForwardRefAbsoluteCode = `
+var asm6ForwardRefZeroPageCode = `
 .segment "HEADER"
 LDA forward,X
 NOP
@@ -324,13 +324,13 @@ forward:
 DB $42
 `
 
-func TestAssemblerAsm6ForwardRefAbsoluteAddressing(t *testing.T) {
-	b, err := runAsm6Test(t, unitTestConfig, asm6ForwardRefAbsoluteCode)
+func TestAssemblerAsm6ForwardRefZeroPageAddressing(t *testing.T) {
+	b, err := runAsm6Test(t, unitTestConfig, asm6ForwardRefZeroPageCode)
 	assert.NoError(t, err)
 
-	// Forward references must reserve absolute-width encoding during address assignment.
+	// Forward references that resolve to a zero page address shrink in a later pass.
 	expected := []byte{
-		0xbd, 0x04, 0x00,
+		0xb5, 0x03,
 		0xea,
 		0x42,
 	}
@@ -509,6 +509,7 @@ MACRO setAXY x,y,z
 ENDM
 
 setAXY $12,$34,$56
+setAXY $78,$9a,$bc
 `
 
 func TestAssemblerAsm6Macro(t *testing.T) {
@@ -518,6 +519,9 @@ func TestAssemblerAsm6Macro(t *testing.T) {
 		0xa9, 0x12, // 2 items
 		0xa2, 0x34, // 2 items
 		0xa0, 0x56, // 2 items
+		0xa9, 0x78, // 2 items
+		0xa2, 0x9a, // 2 items
+		0xa0, 0xbc, // 2 items
 	}
 	assert.Equal(t, expected, b)
 }
@@ -0,0 +1,127 @@
+package assembler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/retroenv/retroasm/pkg/arch/m6502"
+	"github.com/retroenv/retrogolib/assert"
+)
+
+func processFiles(t *testing.T, sources ...string) ([]byte, error) {
+	t.Helper()
+
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(objectTestConfig)))
+
+	files := make([]SourceFile, 0, len(sources))
+	for i, source := range sources {
+		files = append(files, SourceFile{
+			Name:   string(rune('a'+i)) + ".s",
+			Reader: strings.NewReader(source),
+		})
+	}
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	err := asm.ProcessFiles(t.Context(), files)
+	return buf.Bytes(), err
+}
+
+func TestAssemblerProcessFiles(t *testing.T) {
+	main := `.importzp ptr
+.import init
+.export main
+.segment "CODE"
+main: lda ptr
+  jsr init
+loop: jmp loop
+`
+	audio := `.exportzp ptr
+.export init
+.import main
+.segment "ZEROPAGE"
+.res 2
+ptr: .res 1
+.segment "CODE"
+init: jmp main
+loop: jmp loop
+`
+
+	output, err := processFiles(t, main, audio)
+	assert.NoError(t, err)
+	expected := []byte{
+		0xa5, 0x02, // lda ptr
+		0x20, 0x08, 0x80, // jsr init
+		0x4c, 0x05, 0x80, // jmp loop of the first file
+		0x4c, 0x00, 0x80, // jmp main
+		0x4c, 0x0b, 0x80, // jmp loop of the second file
+	}
+	assert.Equal(t, expected, output)
+}
+
+func TestAssemblerProcessFilesLocalExpressions(t *testing.T) {
+	first := `.segment "CODE"
+loop1: nop
+`
+	second := `.segment "CODE"
+loop2: nop
+  .word loop2+1
+`
+
+	output, err := processFiles(t, first, second)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xea, 0xea, 0x02, 0x80}, output)
+}
+
+func TestAssemblerProcessFilesMacros(t *testing.T) {
+	first := `.segment "CODE"
+MACRO store value
+  lda #value
+ENDM
+store 1
+`
+	second := `.segment "CODE"
+MACRO store value
+  ldx #value
+ENDM
+store 2
+`
+
+	output, err := processFiles(t, first, second)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xa9, 0x01, 0xa2, 0x02}, output)
+}
+
+func TestAssemblerProcessFilesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []string
+		err     error
+	}{
+		{
+			name: "duplicate export",
+			sources: []string{
+				".export main\n.segment \"CODE\"\nmain: nop\n",
+				".export main\n.segment \"CODE\"\nmain: nop\n",
+			},
+			err: errDuplicateExport,
+		},
+		{
+			name: "import of unexported symbol",
+			sources: []string{
+				".import helper\n.segment \"CODE\"\njsr helper\n",
+				".segment \"CODE\"\nhelper: rts\n",
+			},
+			err: errUnresolvedImport,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := processFiles(t, tt.sources...)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
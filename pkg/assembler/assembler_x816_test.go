@@ -27,7 +27,7 @@ nop
 	var output bytes.Buffer
 	asm := New(cfg, &output)
 	assert.NoError(t, asm.Process(t.Context(), strings.NewReader(code)))
-	assert.Equal(t, []byte{0xad, 0x02, 0x00, 0xea}, output.Bytes())
+	assert.Equal(t, []byte{0xa5, 0x01, 0xea}, output.Bytes())
 }
 
 func TestAssemblerX816ForwardAddressByteData(t *testing.T) {
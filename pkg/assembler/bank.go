@@ -0,0 +1,99 @@
+package assembler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/retroenv/retroasm/pkg/assembler/config"
+	"github.com/retroenv/retroasm/pkg/parser/ast"
+)
+
+// bankSize is the size of a bank that is selected by the .bank directive. Like in
+// NESASM, every bank is mapped to an 8 KB window of the CPU address space and .org
+// selects the window that the following code runs at.
+const bankSize = 0x2000
+
+// codeSegmentName is the name of the segment that banks are placed in if the source
+// does not select a segment.
+const codeSegmentName = "CODE"
+
+var (
+	errBankOverflow = errors.New("bank overflow")
+	errObjectBank   = errors.New("banks are not supported in object files")
+)
+
+// bankSelection is the bank that the nodes of a segment are placed in. Banks are
+// placed in the output one after another, independent of the CPU address of their code.
+type bankSelection struct {
+	active      bool   // set once a bank was selected by a .bank directive
+	number      uint64 // selected bank
+	windowStart uint64 // CPU address of the bank window that the program counter is in
+	size        uint64 // size of all banks up to the end of the last placed node
+
+	// bank attribute of the memory area that the segment runs in, used for the symbols
+	// of segments that do not select a bank
+	memoryBank    uint64
+	hasMemoryBank bool
+}
+
+// newBankSelection returns the bank selection for the start of the segment.
+func newBankSelection(memories map[string]*config.Memory, segCfg *config.Segment) bankSelection {
+	mem := &segCfg.Memory
+	if runMemory, ok := memories[segCfg.Run]; ok {
+		mem = runMemory
+	}
+	return bankSelection{
+		memoryBank:    mem.Bank,
+		hasMemoryBank: mem.HasBank,
+	}
+}
+
+// selectBank selects the bank of a .bank directive at the current program counter.
+func (b *bankSelection) selectBank(bank ast.Bank, programCounter uint64) error {
+	if bank.Number < 0 {
+		return fmt.Errorf("invalid bank number %d", bank.Number)
+	}
+	b.active = true
+	b.number = uint64(bank.Number)
+	b.setProgramCounter(programCounter)
+	return nil
+}
+
+// setProgramCounter sets the bank window to the window of the program counter that
+// was set by an .org directive.
+func (b *bankSelection) setProgramCounter(programCounter uint64) {
+	b.windowStart = programCounter - programCounter%bankSize
+}
+
+// place records a node of the selected bank that ends at the given CPU address. It
+// returns an error if the node exceeds the end of the bank.
+func (b *bankSelection) place(end uint64) error {
+	if !b.active {
+		return nil
+	}
+	if end > b.windowStart+bankSize {
+		return fmt.Errorf("%w: bank %d ends at $%04X, node ends at $%04X",
+			errBankOverflow, b.number, b.windowStart+bankSize, end)
+	}
+	b.size = max(b.size, b.number*bankSize+end-b.windowStart)
+	return nil
+}
+
+// symbolBank returns the bank of a symbol that is defined at the current program
+// counter and whether the symbol is placed in a bank.
+func (b *bankSelection) symbolBank() (uint64, bool) {
+	if b.active {
+		return b.number, true
+	}
+	return b.memoryBank, b.hasMemoryBank
+}
+
+// loadAddress returns the address in the load memory of the segment for an assigned
+// address of a node. The load address of a node in a bank is its offset in the bank
+// after all previous banks.
+func (b *bankSelection) loadAddress(seg *segment, address uint64) uint64 {
+	if !b.active {
+		return seg.loadAddress(address)
+	}
+	return seg.start + b.number*bankSize + address%bankSize
+}
@@ -0,0 +1,105 @@
+package assembler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/retroenv/retroasm/pkg/arch/m6502"
+	"github.com/retroenv/retroasm/pkg/assembler/config"
+	"github.com/retroenv/retrogolib/assert"
+)
+
+var bankTestConfig = `
+MEMORY {
+    PRG: start = $0000, size = $8000, fill = yes;
+}
+SEGMENTS {
+    CODE: load = PRG, type = ro;
+}
+`
+
+func runBankTest(t *testing.T, testConfig, testCode string, mode config.CompatibilityMode) ([]byte, error) {
+	t.Helper()
+
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(testConfig)))
+	cfg.CompatibilityMode = mode
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	err := asm.Process(t.Context(), strings.NewReader(testCode))
+	return buf.Bytes(), err
+}
+
+func TestAssemblerBanks(t *testing.T) {
+	code := `.bank 0
+.org $C000
+reset:
+  lda #BANK(vectors)
+  lda #BANK(data)
+  jmp reset
+.bank 2
+.org $A000
+data:
+  .byte $aa
+.bank 3
+.org $FFFA
+vectors:
+  .dw reset
+`
+
+	output, err := runBankTest(t, bankTestConfig, code, config.CompatNesasm)
+	assert.NoError(t, err)
+	assert.Len(t, output, 4*bankSize)
+
+	assert.Equal(t, []byte{
+		0xa9, 0x03, // lda #BANK(vectors)
+		0xa9, 0x02, // lda #BANK(data)
+		0x4c, 0x00, 0xc0, // jmp reset
+	}, output[:7])
+	assert.Equal(t, byte(0xaa), output[2*bankSize])
+	assert.Equal(t, []byte{0x00, 0xc0}, output[3*bankSize+0x1ffa:3*bankSize+0x1ffc])
+}
+
+func TestAssemblerBankOverflow(t *testing.T) {
+	code := `.bank 0
+.org $DFFF
+  lda #1
+`
+
+	_, err := runBankTest(t, bankTestConfig, code, config.CompatNesasm)
+	assert.ErrorIs(t, err, errBankOverflow)
+}
+
+func TestAssemblerMemoryBank(t *testing.T) {
+	testConfig := `
+MEMORY {
+    PRG: start = $8000, size = $8000, bank = 5;
+}
+SEGMENTS {
+    CODE: load = PRG, type = ro;
+}
+`
+	code := `.segment "CODE"
+main:
+  lda #^main
+  ldx #.bank(main)
+  .byte ^main
+`
+
+	output, err := runBankTest(t, testConfig, code, config.CompatCa65)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xa9, 0x05, 0xa2, 0x05, 0x05}, output)
+}
+
+func TestAssemblerObjectBank(t *testing.T) {
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(objectTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	asm.SetObjectMode(true)
+	err := asm.Process(t.Context(), strings.NewReader(".segment \"CODE\"\n.bank 1\nnop\n"))
+	assert.ErrorIs(t, err, errObjectBank)
+}
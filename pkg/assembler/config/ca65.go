@@ -10,17 +10,28 @@ import (
 	"github.com/retroenv/retroasm/pkg/lexer"
 	"github.com/retroenv/retroasm/pkg/lexer/token"
 	"github.com/retroenv/retroasm/pkg/number"
+	"github.com/retroenv/retroasm/pkg/parser/ast"
 	"github.com/retroenv/retrogolib/set"
 )
 
-// TODO support FEATURES and SYMBOLS areas
-// https://cc65.github.io/doc/ld65.html#toc5
-
 const (
 	yes = "yes"
 	no  = "no"
+
+	// defaultStartAddress is the start address referenced by %S if the
+	// STARTADDRESS feature is not configured, it matches the ld65 default.
+	defaultStartAddress   = 0x200
+	startAddressReference = "%S"
 )
 
+// ca65Sections contains the areas of all sections of a ca65 config.
+type ca65Sections struct {
+	memory   []*ca65Area
+	segments []*ca65Area
+	symbols  []*ca65Area
+	features []*ca65Area
+}
+
 // ReadCa65Config reads a ca65 configuration.
 func (c *Config[T]) ReadCa65Config(reader io.Reader) error {
 	lexerCfg := lexer.Config{
@@ -28,18 +39,20 @@ func (c *Config[T]) ReadCa65Config(reader io.Reader) error {
 		DecimalPrefix:   0,
 	}
 
-	lex := lexer.New(lexerCfg, reader)
+	lex := &ca65Lexer{
+		lexer: lexer.New(lexerCfg, reader),
+	}
 	return c.readCa65Config(lex)
 }
 
 // readCa65Config reads a ca65 config from the given lexer.
-func (c *Config[T]) readCa65Config(lex *lexer.Lexer) error {
-	var memory, segments []*ca65Area
+func (c *Config[T]) readCa65Config(lex *ca65Lexer) error {
+	var sections ca65Sections
 
 	for eof := false; !eof; {
 		tok, err := lex.NextToken()
 		if err != nil {
-			return fmt.Errorf("reading next token: %w", err)
+			return err
 		}
 
 		switch tok.Type {
@@ -58,16 +71,33 @@ func (c *Config[T]) readCa65Config(lex *lexer.Lexer) error {
 			identifier := strings.ToLower(tok.Value)
 			switch identifier {
 			case "memory":
-				memory, err = readCa65ConfigSection(lex)
+				sections.memory, err = readCa65ConfigSection(lex, true)
 				if err != nil {
 					return fmt.Errorf("reading memory section: %w", err)
 				}
 
 			case "segments":
-				segments, err = readCa65ConfigSection(lex)
+				sections.segments, err = readCa65ConfigSection(lex, true)
 				if err != nil {
 					return fmt.Errorf("reading segments section: %w", err)
 				}
+
+			case "symbols":
+				// the entries of multiple sections are combined
+				symbols, err := readCa65ConfigSection(lex, true)
+				if err != nil {
+					return fmt.Errorf("reading symbols section: %w", err)
+				}
+				sections.symbols = append(sections.symbols, symbols...)
+
+			case "features":
+				// features like CONDES can be specified multiple times, also in multiple sections
+				features, err := readCa65ConfigSection(lex, false)
+				if err != nil {
+					return fmt.Errorf("reading features section: %w", err)
+				}
+				sections.features = append(sections.features, features...)
+
 			default:
 				return fmt.Errorf("unsupported identifier '%s' found at line %d column %d",
 					tok.Value, tok.Position.Line, tok.Position.Column)
@@ -79,32 +109,54 @@ func (c *Config[T]) readCa65Config(lex *lexer.Lexer) error {
 		}
 	}
 
-	if err := c.readFromCa65Areas(memory, segments); err != nil {
+	if err := c.readFromCa65Areas(sections); err != nil {
 		return fmt.Errorf("reading areas: %w", err)
 	}
 	return nil
 }
 
 // readFromCa65Areas reads the configuration from the ca65 config areas.
-func (c *Config[T]) readFromCa65Areas(memories, segments []*ca65Area) error {
+//
+//nolint:cyclop // one processing loop per config section
+func (c *Config[T]) readFromCa65Areas(sections ca65Sections) error {
+	eval, err := newCa65Evaluator(c.Defines, sections.symbols)
+	if err != nil {
+		return fmt.Errorf("processing symbols: %w", err)
+	}
+
+	c.StartAddress = defaultStartAddress
+	c.Condes = nil
+	for _, feature := range sections.features {
+		if err := c.convertCa65Feature(eval, feature); err != nil {
+			return fmt.Errorf("processing feature '%s': %w", feature.name, err)
+		}
+	}
+	if err := eval.addConstant(startAddressReference, c.StartAddress); err != nil {
+		return err
+	}
+
 	memoryNames := map[string]*Memory{}
-	for _, m := range memories {
-		memory, err := convertCa65MemoryArea(m)
+	for _, m := range sections.memory {
+		memory, err := convertCa65MemoryArea(eval, m)
 		if err != nil {
 			return fmt.Errorf("processing memory area '%s': %w", m.name, err)
 		}
 
 		memoryNames[m.name] = memory
 	}
+	c.Memories = memoryNames
 
 	startup := &ca65Area{
-		name: "STARTUP",
+		name:       "STARTUP",
+		attributes: map[string]string{"optional": yes},
 	}
+	segments := sections.segments
 	segments = append(segments, startup)
 
 	c.Segments = map[string]*Segment{}
+	c.SegmentsOrdered = nil
 	for _, seg := range segments {
-		segment, err := convertCa65SegmentArea(seg, memoryNames)
+		segment, err := convertCa65SegmentArea(eval, seg, memoryNames)
 		if err != nil {
 			return fmt.Errorf("processing segment area '%s': %w", seg.name, err)
 		}
@@ -114,18 +166,36 @@ func (c *Config[T]) readFromCa65Areas(memories, segments []*ca65Area) error {
 		c.SegmentsOrdered = append(c.SegmentsOrdered, segment)
 	}
 
+	c.Symbols = nil
+	for _, sym := range sections.symbols {
+		symbol, err := convertCa65SymbolArea(eval, sym)
+		if err != nil {
+			return fmt.Errorf("processing symbol '%s': %w", sym.name, err)
+		}
+		c.Symbols = append(c.Symbols, symbol)
+	}
+
+	for _, condes := range c.Condes {
+		if _, ok := c.Segments[condes.Segment]; !ok {
+			return fmt.Errorf("condes segment '%s' not found", condes.Segment)
+		}
+	}
+
 	return nil
 }
 
 type ca65Area struct {
 	name       string
-	attributes map[string]string
+	attributes map[string]string        // maps attribute name to its value
+	values     map[string][]token.Token // maps attribute name to the tokens of its value
 }
 
 var errNoStartingBlock = errors.New("no { starting block found")
 
-// readCa65ConfigSection reads a config section, this can either be MEMORY or SEGMENTS.
-func readCa65ConfigSection(lex *lexer.Lexer) ([]*ca65Area, error) {
+// readCa65ConfigSection reads a config section, this can be MEMORY, SEGMENTS, SYMBOLS
+// or FEATURES. If unique names are requested, multiple areas with the same name
+// return an error.
+func readCa65ConfigSection(lex *ca65Lexer, uniqueNames bool) ([]*ca65Area, error) {
 	leftBraceFound := false          // flag to detect multiple left braces
 	identifiers := set.New[string]() // area identifiers set to detect duplicates
 	var areas []*ca65Area            // all read areas to return
@@ -134,7 +204,7 @@ func readCa65ConfigSection(lex *lexer.Lexer) ([]*ca65Area, error) {
 	for {
 		tok, err := lex.NextToken()
 		if err != nil {
-			return nil, fmt.Errorf("reading next token: %w", err)
+			return nil, err
 		}
 
 		switch tok.Type {
@@ -170,7 +240,7 @@ func readCa65ConfigSection(lex *lexer.Lexer) ([]*ca65Area, error) {
 			}
 
 			// identifiers are case-sensitive
-			if identifiers.Contains(tok.Value) {
+			if uniqueNames && identifiers.Contains(tok.Value) {
 				return nil, fmt.Errorf("multiple areas named '%s' found", tok.Value)
 			}
 			identifiers.Add(tok.Value)
@@ -178,6 +248,7 @@ func readCa65ConfigSection(lex *lexer.Lexer) ([]*ca65Area, error) {
 			ar = &ca65Area{
 				name:       tok.Value,
 				attributes: map[string]string{},
+				values:     map[string][]token.Token{},
 			}
 			areas = append(areas, ar)
 
@@ -192,74 +263,92 @@ func readCa65ConfigSection(lex *lexer.Lexer) ([]*ca65Area, error) {
 // and:
 // start $0800
 // size = $4000.
-func readCa65ConfigAttribute(lex *lexer.Lexer, ar *ca65Area, attribute string) error {
+func readCa65ConfigAttribute(lex *ca65Lexer, ar *ca65Area, attribute string) error {
 	tok, err := lex.NextToken()
 	if err != nil {
-		return fmt.Errorf("reading next token: %w", err)
+		return err
 	}
 
 	if tok.Type == token.Assign { // assign is optional
 		tok, err = lex.NextToken()
 		if err != nil {
-			return fmt.Errorf("reading next token: %w", err)
+			return err
 		}
 	}
 
-	if tok.Value == "%" { // support %O references
-		tok, err = lex.NextToken()
-		if err != nil {
-			return fmt.Errorf("reading next token: %w", err)
-		}
-		tok.Value = "%" + tok.Value
+	tokens, err := readCa65ConfigValue(lex, tok)
+	if err != nil {
+		return err
 	}
 
-	if tok.Type != token.Identifier && tok.Type != token.Number {
-		return fmt.Errorf("unexpected token type found: '%s'", tok.Type.String())
+	values := make([]string, 0, len(tokens))
+	for _, tok := range tokens {
+		if tok.Type.IsOperator() || tok.Type == token.LeftParentheses || tok.Type == token.RightParentheses {
+			values = append(values, tok.Type.String())
+		} else {
+			values = append(values, tok.Value)
+		}
 	}
 
-	ar.attributes[strings.ToLower(attribute)] = tok.Value
+	key := strings.ToLower(attribute)
+	ar.attributes[key] = strings.Join(values, " ")
+	ar.values[key] = tokens
 	return nil
 }
 
 // convertCa65MemoryArea converts a MEMORY area of the ca65 configuration to a memory type instance.
-func convertCa65MemoryArea(ar *ca65Area) (*Memory, error) {
+func convertCa65MemoryArea(eval *ca65Evaluator, ar *ca65Area) (*Memory, error) {
 	mem := &Memory{
 		Name: ar.name,
+		File: OutputNameReference,
 	}
-	if err := parseCa65MemoryArea(ar, mem, false); err != nil {
+	if err := parseCa65MemoryArea(eval, ar, mem, false); err != nil {
 		return nil, fmt.Errorf("parsing memory area: %w", err)
 	}
+
+	switch mem.Typ {
+	case "", TypeReadOnly, TypeReadWrite:
+	default:
+		return nil, fmt.Errorf("unsupported memory type '%s'", mem.Typ)
+	}
 	return mem, nil
 }
 
 //nolint:cyclop // attribute switch with one case per config key
-func parseCa65MemoryArea(ar *ca65Area, mem *Memory, ignoreUnknownKeys bool) error {
+func parseCa65MemoryArea(eval *ca65Evaluator, ar *ca65Area, mem *Memory, ignoreUnknownKeys bool) error {
 	var err error
 
 	for key, value := range ar.attributes {
 		switch key {
 		case "start":
-			mem.Start, err = number.Parse(value)
+			mem.Start, err = eval.number(ar, key)
 			if err != nil {
-				return fmt.Errorf("parsing number '%s': %w", value, err)
+				return err
 			}
 
 		case "size":
-			mem.Size, err = number.Parse(value)
+			mem.Size, err = eval.number(ar, key)
 			if err != nil {
-				return fmt.Errorf("parsing number '%s': %w", value, err)
+				return err
 			}
 
 		case "file":
 			mem.File = strings.Trim(value, "\"'") // unescape string
 
+		case "bank":
+			mem.Bank, err = eval.number(ar, key)
+			if err != nil {
+				return err
+			}
+			mem.HasBank = true
+
 		case "type":
 			mem.Typ = value
 
 		case "fillval":
-			i, err := number.Parse(value)
+			i, err := eval.number(ar, key)
 			if err != nil {
-				return fmt.Errorf("parsing number '%s': %w", value, err)
+				return err
 			}
 			if i > math.MaxUint8 {
 				return fmt.Errorf("fill value '%s' exceeds byte", value)
@@ -288,7 +377,7 @@ func parseCa65MemoryArea(ar *ca65Area, mem *Memory, ignoreUnknownKeys bool) erro
 // convertCa65SegmentArea converts a SEGMENTS area of the ca65 configuration to a segment type instance.
 //
 //nolint:cyclop,funlen // attribute switch with one case per config key
-func convertCa65SegmentArea(ar *ca65Area, memoryNames map[string]*Memory) (*Segment, error) {
+func convertCa65SegmentArea(eval *ca65Evaluator, ar *ca65Area, memoryNames map[string]*Memory) (*Segment, error) {
 	seg := &Segment{}
 	var err error
 
@@ -305,30 +394,42 @@ func convertCa65SegmentArea(ar *ca65Area, memoryNames map[string]*Memory) (*Segm
 	memoryStart := seg.Memory.Start
 
 	// overload all specified memory related keys
-	if err := parseCa65MemoryArea(ar, &seg.Memory, true); err != nil {
+	if err := parseCa65MemoryArea(eval, ar, &seg.Memory, true); err != nil {
 		return nil, fmt.Errorf("parsing memory area keys: %w", err)
 	}
 
 	seg.SegmentStart = seg.Start
+	_, seg.FixedStart = ar.attributes["start"]
 	// restore memory start in case the memory loader overwrote it
 	if memoryStart != seg.Start {
 		seg.Start = memoryStart
 	}
 
+	switch seg.Typ {
+	case "", TypeReadOnly, TypeReadWrite, TypeBSS, TypeZeroPage:
+	default:
+		return nil, fmt.Errorf("unsupported segment type '%s'", seg.Typ)
+	}
+
 	// parse all segment specific keys
 	for key, value := range ar.attributes {
 		switch key {
 		case "align":
-			seg.Align, err = number.Parse(value)
+			seg.Align, err = eval.number(ar, key)
 			if err != nil {
-				return nil, fmt.Errorf("parsing number '%s': %w", value, err)
+				return nil, err
 			}
 
 		case "offset":
-			seg.Offset, err = number.Parse(value)
+			if seg.FixedStart {
+				return nil, errors.New("start and offset attributes can not be combined")
+			}
+			seg.Offset, err = eval.number(ar, key)
 			if err != nil {
-				return nil, fmt.Errorf("parsing number '%s': %w", value, err)
+				return nil, err
 			}
+			seg.SegmentStart = memoryStart + seg.Offset
+			seg.FixedStart = true
 
 		case "define":
 			switch value {
@@ -349,9 +450,135 @@ func convertCa65SegmentArea(ar *ca65Area, memoryNames map[string]*Memory) (*Segm
 			}
 
 		case "run":
+			if _, ok := memoryNames[value]; !ok {
+				return nil, fmt.Errorf("run memory area '%s' not found", value)
+			}
 			seg.Run = value
 		}
 	}
 
 	return seg, nil
 }
+
+// convertCa65SymbolArea converts a SYMBOLS area of the ca65 configuration to a symbol type instance.
+func convertCa65SymbolArea(eval *ca65Evaluator, ar *ca65Area) (*Symbol, error) {
+	sym := &Symbol{
+		Name: ar.name,
+		Typ:  SymbolExport,
+	}
+	var err error
+	valueSet := false
+
+	for key, value := range ar.attributes {
+		switch key {
+		case "type":
+			switch value {
+			case SymbolExport, SymbolImport, SymbolWeak:
+				sym.Typ = value
+			default:
+				return nil, fmt.Errorf("unsupported symbol type '%s'", value)
+			}
+
+		case "value":
+			sym.Value, err = eval.number(ar, key)
+			if err != nil {
+				return nil, err
+			}
+			valueSet = true
+
+		case "addrsize":
+			// the address size is derived from the value when the symbol is referenced
+
+		default:
+			return nil, fmt.Errorf("unsupported symbol key '%s'", key)
+		}
+	}
+
+	switch {
+	case sym.Typ == SymbolImport && valueSet:
+		return nil, errors.New("imported symbol can not have a value")
+	case sym.Typ != SymbolImport && !valueSet:
+		return nil, errors.New("missing symbol value")
+	}
+	return sym, nil
+}
+
+// convertCa65Feature converts a FEATURES area of the ca65 configuration.
+func (c *Config[T]) convertCa65Feature(eval *ca65Evaluator, ar *ca65Area) error {
+	switch strings.ToLower(ar.name) {
+	case "startaddress":
+		for key := range ar.attributes {
+			if key != "default" {
+				return fmt.Errorf("unsupported start address key '%s'", key)
+			}
+
+			address, err := eval.number(ar, key)
+			if err != nil {
+				return err
+			}
+			c.StartAddress = address
+		}
+		return nil
+
+	case "condes":
+		condes, err := convertCa65CondesFeature(ar)
+		if err != nil {
+			return err
+		}
+		c.Condes = append(c.Condes, condes)
+		return nil
+
+	default:
+		return errors.New("unsupported feature")
+	}
+}
+
+// convertCa65CondesFeature converts a CONDES feature area to a condes type instance.
+func convertCa65CondesFeature(ar *ca65Area) (*Condes, error) {
+	for _, key := range []string{"segment", "type", "label"} {
+		if _, ok := ar.attributes[key]; !ok {
+			return nil, fmt.Errorf("missing condes attribute '%s'", key)
+		}
+	}
+
+	condes := &Condes{}
+	for key, value := range ar.attributes {
+		switch key {
+		case "segment":
+			condes.Segment = value
+
+		case "type":
+			typ, ok := ast.CondesTypes[strings.ToLower(value)]
+			if !ok {
+				i, err := number.Parse(value)
+				if err != nil || i > ast.CondesMaxType {
+					return nil, fmt.Errorf("unsupported condes type '%s'", value)
+				}
+				typ = int(i)
+			}
+			condes.Type = typ
+
+		case "label":
+			condes.Label = value
+
+		case "count":
+			condes.Count = value
+
+		case "order":
+			switch value {
+			case "increasing":
+			case "decreasing":
+				condes.Decreasing = true
+			default:
+				return nil, fmt.Errorf("unsupported order value '%s'", value)
+			}
+
+		case "import":
+			// forcing the import of a symbol is not needed, the program is assembled as a whole
+
+		default:
+			return nil, fmt.Errorf("unsupported condes key '%s'", key)
+		}
+	}
+	return condes, nil
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/retroenv/retroasm/pkg/expression"
+	"github.com/retroenv/retroasm/pkg/lexer"
+	"github.com/retroenv/retroasm/pkg/lexer/token"
+	"github.com/retroenv/retroasm/pkg/scope"
+)
+
+// ca65Lexer wraps the lexer to allow reading a token again, which is needed to detect
+// the end of an attribute value expression.
+type ca65Lexer struct {
+	lexer  *lexer.Lexer
+	unread []token.Token
+}
+
+// NextToken returns the next token.
+func (l *ca65Lexer) NextToken() (token.Token, error) {
+	if n := len(l.unread); n > 0 {
+		tok := l.unread[n-1]
+		l.unread = l.unread[:n-1]
+		return tok, nil
+	}
+	tok, err := l.lexer.NextToken()
+	if err != nil {
+		return token.Token{}, fmt.Errorf("reading next token: %w", err)
+	}
+	return tok, nil
+}
+
+// unreadToken returns the token to the lexer, to be returned by the next call of NextToken.
+func (l *ca65Lexer) unreadToken(tok token.Token) {
+	l.unread = append(l.unread, tok)
+}
+
+// readCa65ConfigValue reads the tokens of an attribute value that starts with the given
+// token. A value is either a single identifier, number or string or an expression like
+// $10000 - __STACKSIZE__. The placeholders %O and %S are returned as identifiers.
+func readCa65ConfigValue(lex *ca65Lexer, tok token.Token) ([]token.Token, error) {
+	var tokens []token.Token
+	expectOperand := true
+
+	for {
+		if expectOperand {
+			switch tok.Type {
+			case token.LeftParentheses:
+
+			case token.Percent: // support %O and %S references
+				next, err := lex.NextToken()
+				if err != nil {
+					return nil, err
+				}
+				if next.Type != token.Identifier {
+					return nil, fmt.Errorf("unexpected token type found after %%: '%s'", next.Type.String())
+				}
+				tok = token.Token{Type: token.Identifier, Value: "%" + next.Value, Position: tok.Position}
+				expectOperand = false
+
+			case token.Identifier, token.Number:
+				expectOperand = false
+
+			default:
+				return nil, fmt.Errorf("unexpected token type found: '%s'", tok.Type.String())
+			}
+		} else {
+			if !tok.Type.IsOperator() && tok.Type != token.RightParentheses {
+				lex.unreadToken(tok)
+				return tokens, nil
+			}
+			expectOperand = tok.Type != token.RightParentheses
+		}
+
+		tokens = append(tokens, tok)
+
+		var err error
+		tok, err = lex.NextToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ca65Evaluator evaluates the numeric attribute values of a ca65 config. The values can be
+// expressions that reference the symbols of the SYMBOLS section, external defines and
+// the %S start address.
+type ca65Evaluator struct {
+	scope *scope.Scope
+}
+
+// newCa65Evaluator returns a new evaluator for the given external defines and areas of
+// the SYMBOLS section. Weak symbols are overridden by external defines.
+func newCa65Evaluator(defines map[string]uint64, symbols []*ca65Area) (*ca65Evaluator, error) {
+	e := &ca65Evaluator{
+		scope: scope.New(nil),
+	}
+
+	for name, value := range defines {
+		if err := e.addConstant(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, ar := range symbols {
+		tokens, ok := ar.values["value"]
+		if !ok {
+			continue // imported symbols do not have a value
+		}
+
+		if _, err := e.scope.GetSymbol(ar.name); err == nil {
+			if ar.attributes["type"] == SymbolWeak {
+				continue
+			}
+			return nil, fmt.Errorf("symbol '%s' is defined by the configuration and externally", ar.name)
+		}
+
+		sym, err := scope.NewSymbol(e.scope, ar.name, scope.EquType)
+		if err != nil {
+			return nil, fmt.Errorf("creating symbol: %w", err)
+		}
+		sym.SetExpression(expression.New(tokens...))
+	}
+
+	return e, nil
+}
+
+// addConstant adds a symbol with a constant value that can be referenced by expressions.
+func (e *ca65Evaluator) addConstant(name string, value uint64) error {
+	sym, err := scope.NewSymbol(e.scope, name, scope.EquType)
+	if err != nil {
+		return fmt.Errorf("creating symbol: %w", err)
+	}
+
+	exp := expression.New()
+	exp.SetEvaluateOnce(true)
+	exp.SetValue(int64(value))
+	sym.SetExpression(exp)
+	return nil
+}
+
+// number evaluates the value of the attribute to a number.
+func (e *ca65Evaluator) number(ar *ca65Area, key string) (uint64, error) {
+	value, err := expression.New(ar.values[key]...).Evaluate(e.scope, 1)
+	if err != nil {
+		return 0, fmt.Errorf("evaluating expression '%s': %w", ar.attributes[key], err)
+	}
+
+	i, ok := value.(int64)
+	if !ok || i < 0 {
+		return 0, fmt.Errorf("expression '%s' does not result in a positive number", ar.attributes[key])
+	}
+	return uint64(i), nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/retroenv/retrogolib/arch/cpu/m6502"
@@ -51,3 +52,207 @@ SEGMENTS { CODE: load = ROM, type = ro, offset = xyz; }
 		assert.Error(t, cfg.ReadCa65Config(bytes.NewReader(input)))
 	})
 }
+
+func TestConfigReadCa65Config_SegmentAttributes(t *testing.T) {
+	input := []byte(`
+MEMORY {
+    ROM: start = $8000, size = $4000, type = ro, bank = 2;
+    RAM: start = $0300, size = $0500, type = rw;
+}
+SEGMENTS {
+    CODE:   load = ROM, type = ro, start = $8100, align = $100;
+    DATA:   load = ROM, run = RAM, type = rw, define = yes, optional = yes;
+    BSS:    load = RAM, type = bss;
+}
+`)
+	var cfg Config[*m6502.Instruction]
+	assert.NoError(t, cfg.ReadCa65Config(bytes.NewReader(input)))
+
+	code := cfg.Segments["CODE"]
+	assert.True(t, code.FixedStart)
+	assert.Equal(t, uint64(0x8100), code.SegmentStart)
+	assert.Equal(t, uint64(0x100), code.Align)
+	assert.True(t, code.HasBank)
+	assert.Equal(t, uint64(2), code.Bank)
+	assert.False(t, cfg.Memories["RAM"].HasBank)
+
+	data := cfg.Segments["DATA"]
+	assert.False(t, data.FixedStart)
+	assert.Equal(t, "RAM", data.Run)
+	assert.True(t, data.Define)
+	assert.True(t, data.Optional)
+
+	assert.True(t, cfg.Segments["BSS"].Uninitialized())
+	assert.False(t, data.Uninitialized())
+	assert.True(t, cfg.Segments["STARTUP"].Optional)
+	assert.Len(t, cfg.Memories, 2)
+}
+
+func TestConfigReadCa65Config_SegmentAttributeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"start and offset", `MEMORY { ROM: start = $8000, size = $4000; }
+SEGMENTS { CODE: load = ROM, start = $8000, offset = $10; }`},
+		{"unknown run memory", `MEMORY { ROM: start = $8000, size = $4000; }
+SEGMENTS { CODE: load = ROM, run = RAM; }`},
+		{"invalid segment type", `MEMORY { ROM: start = $8000, size = $4000; }
+SEGMENTS { CODE: load = ROM, type = code; }`},
+		{"invalid memory type", `MEMORY { ROM: start = $8000, size = $4000, type = bss; }
+SEGMENTS { CODE: load = ROM; }`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config[*m6502.Instruction]
+			assert.Error(t, cfg.ReadCa65Config(strings.NewReader(tt.input)))
+		})
+	}
+}
+
+func TestConfigReadCa65Config_SymbolsAndFeatures(t *testing.T) {
+	input := []byte(`
+FEATURES {
+    STARTADDRESS: default = $0801;
+    CONDES: segment = RODATA, type = constructor, label = __CONSTRUCTOR_TABLE__, count = __CONSTRUCTOR_COUNT__;
+    CONDES: segment = RODATA, type = 1, label = __DESTRUCTOR_TABLE__, order = decreasing;
+}
+SYMBOLS {
+    __STACKSIZE__: type = weak, value = $0200;
+    __LOADADDR__:  value = $0801;
+    main:          type = import;
+}
+MEMORY { MAIN: start = %S, size = $C000, type = rw; }
+SEGMENTS { RODATA: load = MAIN, type = ro; }
+`)
+	var cfg Config[*m6502.Instruction]
+	assert.NoError(t, cfg.ReadCa65Config(bytes.NewReader(input)))
+
+	assert.Equal(t, uint64(0x0801), cfg.StartAddress)
+	assert.Equal(t, uint64(0x0801), cfg.Memories["MAIN"].Start)
+
+	assert.Equal(t, []*Symbol{
+		{Name: "__STACKSIZE__", Typ: SymbolWeak, Value: 0x200},
+		{Name: "__LOADADDR__", Typ: SymbolExport, Value: 0x801},
+		{Name: "main", Typ: SymbolImport},
+	}, cfg.Symbols)
+
+	assert.Equal(t, []*Condes{
+		{Type: 0, Segment: "RODATA", Label: "__CONSTRUCTOR_TABLE__", Count: "__CONSTRUCTOR_COUNT__"},
+		{Type: 1, Segment: "RODATA", Label: "__DESTRUCTOR_TABLE__", Decreasing: true},
+	}, cfg.Condes)
+}
+
+func TestConfigReadCa65Config_MultipleSections(t *testing.T) {
+	input := []byte(`
+FEATURES { STARTADDRESS: default = $0801; }
+FEATURES { CONDES: segment = CODE, type = constructor, label = __CONSTRUCTOR_TABLE__; }
+SYMBOLS { __STACKSIZE__: value = $0200; }
+SYMBOLS { main: type = import; }
+MEMORY { MAIN: start = %S, size = $C000; }
+SEGMENTS { CODE: load = MAIN, type = ro; }
+`)
+	var cfg Config[*m6502.Instruction]
+	assert.NoError(t, cfg.ReadCa65Config(bytes.NewReader(input)))
+
+	assert.Equal(t, uint64(0x0801), cfg.StartAddress)
+	assert.Equal(t, []*Condes{
+		{Type: 0, Segment: "CODE", Label: "__CONSTRUCTOR_TABLE__"},
+	}, cfg.Condes)
+	assert.Equal(t, []*Symbol{
+		{Name: "__STACKSIZE__", Typ: SymbolExport, Value: 0x200},
+		{Name: "main", Typ: SymbolImport},
+	}, cfg.Symbols)
+}
+
+func TestConfigReadCa65Config_SymbolsAndFeaturesErrors(t *testing.T) {
+	memory := "MEMORY { ROM: start = $8000, size = $4000; }\nSEGMENTS { CODE: load = ROM; }\n"
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"unsupported symbol type", "SYMBOLS { foo: type = global, value = 1; }"},
+		{"missing symbol value", "SYMBOLS { foo: type = weak; }"},
+		{"imported symbol with value", "SYMBOLS { foo: type = import, value = 1; }"},
+		{"duplicate symbol", "SYMBOLS { foo: value = 1; foo: value = 2; }"},
+		{"duplicate symbol in sections", "SYMBOLS { foo: value = 1; }\nSYMBOLS { foo: value = 2; }"},
+		{"unsupported feature", "FEATURES { UNKNOWN: default = 1; }"},
+		{"condes missing label", "FEATURES { CONDES: segment = CODE, type = constructor; }"},
+		{"condes unknown segment", "FEATURES { CONDES: segment = DATA, type = constructor, label = table; }"},
+		{"condes invalid type", "FEATURES { CONDES: segment = CODE, type = 7, label = table; }"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config[*m6502.Instruction]
+			assert.Error(t, cfg.ReadCa65Config(strings.NewReader(memory+tt.input)))
+		})
+	}
+}
+
+func TestConfigReadCa65Config_Expressions(t *testing.T) {
+	input := []byte(`
+FEATURES { STARTADDRESS: default = $0801; }
+SYMBOLS {
+    __STACKSIZE__:  type = weak, value = $0800;
+    __STACKSTART__: type = export, value = $10000 - __STACKSIZE__;
+}
+MEMORY {
+    MAIN:  start = %S + 2, size = __STACKSTART__ - (%S + 2), fillval = BANKS * 2;
+    STACK: start = __STACKSTART__, size = __STACKSIZE__;
+}
+SEGMENTS { CODE: load = MAIN, start = %S + $10, align = 4 * 4; }
+`)
+
+	tests := []struct {
+		name      string
+		defines   map[string]uint64
+		stackSize uint64
+	}{
+		{"config symbols", map[string]uint64{"BANKS": 2}, 0x800},
+		{"define overrides weak symbol", map[string]uint64{"BANKS": 2, "__STACKSIZE__": 0x400}, 0x400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config[*m6502.Instruction]{Defines: tt.defines}
+			assert.NoError(t, cfg.ReadCa65Config(bytes.NewReader(input)))
+
+			main := cfg.Memories["MAIN"]
+			assert.Equal(t, uint64(0x0803), main.Start)
+			assert.Equal(t, 0x10000-tt.stackSize-0x0803, main.Size)
+			assert.Equal(t, byte(4), main.FillValue)
+
+			stack := cfg.Memories["STACK"]
+			assert.Equal(t, 0x10000-tt.stackSize, stack.Start)
+			assert.Equal(t, tt.stackSize, stack.Size)
+
+			code := cfg.Segments["CODE"]
+			assert.Equal(t, uint64(0x0811), code.SegmentStart)
+			assert.Equal(t, uint64(0x10), code.Align)
+			assert.Equal(t, 0x10000-tt.stackSize, cfg.Symbols[1].Value)
+		})
+	}
+}
+
+func TestConfigReadCa65Config_ExpressionErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"unknown symbol", `MEMORY { ROM: start = $8000, size = SIZE; }`},
+		{"negative result", `MEMORY { ROM: start = $8000, size = 1 - 2; }`},
+		{"circular symbols", `SYMBOLS { a: value = b; b: value = a + 1; }
+MEMORY { ROM: start = a, size = $100; }`},
+		{"exported symbol defined externally", `SYMBOLS { BANKS: type = export, value = 1; }`},
+		{"missing operand", `MEMORY { ROM: start = $8000 +; }`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config[*m6502.Instruction]{Defines: map[string]uint64{"BANKS": 2}}
+			assert.Error(t, cfg.ReadCa65Config(strings.NewReader(tt.input)))
+		})
+	}
+}
@@ -61,6 +61,12 @@ func (m CompatibilityMode) BankByteOperator() bool {
 	return m == CompatX816 || m == CompatCa65
 }
 
+// BankFunction returns whether this mode supports the BANK() function without dot prefix,
+// that returns the bank of a label like the .bank() function.
+func (m CompatibilityMode) BankFunction() bool {
+	return m == CompatNesasm
+}
+
 // ColonOptionalLabels returns whether this mode treats trailing colons on labels as optional.
 func (m CompatibilityMode) ColonOptionalLabels() bool {
 	return m == CompatX816 || m == CompatAsm6
@@ -81,6 +87,12 @@ func (m CompatibilityMode) NesasmMacroSyntax() bool {
 	return m == CompatNesasm
 }
 
+// UndocumentedInstructions returns whether this mode accepts the stable undocumented
+// instructions of the NMOS 6502 without selecting a CPU, like asm6f.
+func (m CompatibilityMode) UndocumentedInstructions() bool {
+	return m == CompatAsm6
+}
+
 // UnnamedLabels returns whether this mode supports ca65-style unnamed labels (: / :- / :+).
 func (m CompatibilityMode) UnnamedLabels() bool {
 	return m == CompatCa65
@@ -88,6 +88,14 @@ func TestCompatibilityMode_Features(t *testing.T) {
 		assert.False(t, CompatNesasm.LocalLabelScoping())
 	})
 
+	t.Run("undocumented instructions", func(t *testing.T) {
+		assert.False(t, CompatDefault.UndocumentedInstructions())
+		assert.False(t, CompatX816.UndocumentedInstructions())
+		assert.True(t, CompatAsm6.UndocumentedInstructions())
+		assert.False(t, CompatCa65.UndocumentedInstructions())
+		assert.False(t, CompatNesasm.UndocumentedInstructions())
+	})
+
 	t.Run("unnamed labels", func(t *testing.T) {
 		assert.False(t, CompatDefault.UnnamedLabels())
 		assert.False(t, CompatX816.UnnamedLabels())
@@ -121,4 +129,12 @@ func TestCompatibilityMode_NesasmFeatures(t *testing.T) {
 		assert.True(t, CompatCa65.BankByteOperator())
 		assert.False(t, CompatNesasm.BankByteOperator())
 	})
+
+	t.Run("bank function", func(t *testing.T) {
+		assert.False(t, CompatDefault.BankFunction())
+		assert.False(t, CompatX816.BankFunction())
+		assert.False(t, CompatAsm6.BankFunction())
+		assert.False(t, CompatCa65.BankFunction())
+		assert.True(t, CompatNesasm.BankFunction())
+	})
 }
@@ -9,10 +9,31 @@ import (
 type Config[T any] struct {
 	Arch              arch.Architecture[T]
 	CompatibilityMode CompatibilityMode
+	Memories          map[string]*Memory // maps memory area name to its configuration
 	Segments          map[string]*Segment
 	SegmentsOrdered   []*Segment
+
+	Symbols      []*Symbol // symbols of the SYMBOLS section
+	Condes       []*Condes // tables of the CONDES feature
+	StartAddress uint64    // default start address referenced by %S, set by the STARTADDRESS feature
+
+	// Defines contains externally defined symbols like command-line defines, that can be
+	// referenced by expressions in the config. Set it before reading the config.
+	Defines map[string]uint64
 }
 
+// Memory and segment types.
+const (
+	TypeReadOnly  = "ro"
+	TypeReadWrite = "rw"
+	TypeBSS       = "bss" // uninitialized data
+	TypeZeroPage  = "zp"  // uninitialized data in the zero page
+)
+
+// OutputNameReference references the name of the main output file in the file
+// attribute of a memory area.
+const OutputNameReference = "%O"
+
 // Memory contains the basic configuration for a memory segment.
 type Memory struct {
 	Name string
@@ -20,11 +41,14 @@ type Memory struct {
 	Start uint64
 	Size  uint64
 
-	Typ  string // TODO: support
-	File string
+	Typ  string // ro or rw for memory areas, segments can also use bss or zp
+	File string // output file name, defaults to %O for the main output, empty if not written
 
 	Fill      bool
 	FillValue byte
+
+	Bank    uint64 // bank number of the memory area, returned by .bank() for its symbols
+	HasBank bool   // the bank attribute is set
 }
 
 // Segment contains the extended configuration for a memory segment.
@@ -32,12 +56,43 @@ type Segment struct {
 	Memory
 
 	SegmentName  string
-	SegmentStart uint64
+	SegmentStart uint64 // start address of the segment, only used if FixedStart is set
+	FixedStart   bool   // the segment start was set by a start or offset attribute
+
+	Offset uint64 // offset of the segment start inside its memory area
+	Align  uint64 // alignment of the segment start
+	Run    string // name of the memory area that the segment runs in, if it differs from the load memory
 
-	Offset uint64 // TODO: support
-	Align  uint64 // TODO: support
-	Run    string // TODO: support
+	Define   bool // define __NAME_LOAD__, __NAME_RUN__ and __NAME_SIZE__ symbols for the segment
+	Optional bool // the segment does not have to be used by the program
+}
+
+// Uninitialized returns whether the segment contains only reserved space and
+// does not emit any bytes to the output.
+func (s *Segment) Uninitialized() bool {
+	return s.Typ == TypeBSS || s.Typ == TypeZeroPage
+}
+
+// Symbol types of the SYMBOLS section.
+const (
+	SymbolExport = "export" // the configuration defines the symbol
+	SymbolImport = "import" // the program has to define the symbol
+	SymbolWeak   = "weak"   // the configuration defines the symbol unless the program defines it
+)
+
+// Symbol contains the configuration of a symbol of the SYMBOLS section.
+type Symbol struct {
+	Name  string
+	Typ   string // export, import or weak
+	Value uint64 // not set for imported symbols
+}
 
-	Define   bool // TODO: support
-	Optional bool // TODO: support
+// Condes contains the configuration of a table of constructor, destructor or
+// interruptor functions that the CONDES feature creates.
+type Condes struct {
+	Type       int    // table type, see the ast.Condes type constants
+	Segment    string // name of the segment that the table is appended to
+	Label      string // symbol for the start address of the table
+	Count      string // optional symbol for the number of table entries
+	Decreasing bool   // sort the entries by decreasing instead of increasing priority
 }
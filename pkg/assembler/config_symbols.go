@@ -0,0 +1,159 @@
+package assembler
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/retroenv/retroasm/pkg/assembler/config"
+	"github.com/retroenv/retroasm/pkg/expression"
+	"github.com/retroenv/retroasm/pkg/lexer/token"
+	"github.com/retroenv/retroasm/pkg/parser/ast"
+	"github.com/retroenv/retroasm/pkg/scope"
+)
+
+var (
+	errConfigSymbolDefined = errors.New("symbol exported by the configuration is also defined by the program")
+	errConfigSymbolMissing = errors.New("symbol imported by the configuration is not defined by the program")
+)
+
+// condesEntry is a function that was declared for a constructor, destructor or
+// interruptor table.
+type condesEntry struct {
+	name     string
+	typ      int
+	priority int
+	scope    *scope.Scope // scope of the declaration, used to resolve the function name
+	position token.Position
+}
+
+func parseCondes[T any](asm *parseAST[T], condes ast.Condes) {
+	asm.condes = append(asm.condes, condesEntry{
+		name:     condes.Name,
+		typ:      condes.Type,
+		priority: condes.Priority,
+		scope:    asm.currentScope,
+		position: condes.Position(),
+	})
+}
+
+// addConfigSymbols adds the symbols of the SYMBOLS configuration section to the file
+// scope, after all symbols of the program were defined. Weak symbols are only added
+// if the program does not define them, imported symbols have to be defined by the program.
+func addConfigSymbols(sc *scope.Scope, symbols []*config.Symbol) error {
+	var errs []error
+
+	for _, sym := range symbols {
+		_, err := sc.GetSymbol(sym.Name)
+		defined := err == nil
+
+		switch sym.Typ {
+		case config.SymbolImport:
+			if !defined {
+				errs = append(errs, fmt.Errorf("%w: '%s'", errConfigSymbolMissing, sym.Name))
+			}
+
+		case config.SymbolWeak:
+			if defined {
+				continue
+			}
+			if _, err := newConstantSymbol(sc, sym.Name, sym.Value); err != nil {
+				errs = append(errs, err)
+			}
+
+		default:
+			if defined {
+				errs = append(errs, fmt.Errorf("%w: '%s'", errConfigSymbolDefined, sym.Name))
+				continue
+			}
+			if _, err := newConstantSymbol(sc, sym.Name, sym.Value); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// addDefineSymbols adds the externally defined symbols to the file scope, sorted by
+// name to create them in a deterministic order.
+func addDefineSymbols(sc *scope.Scope, defines map[string]uint64) error {
+	names := slices.Sorted(maps.Keys(defines))
+	for _, name := range names {
+		if _, err := newConstantSymbol(sc, name, defines[name]); err != nil {
+			return fmt.Errorf("defining symbol '%s': %w", name, err)
+		}
+	}
+	return nil
+}
+
+// addCondesTables appends the tables of the CONDES feature to their configured segments.
+// Every table contains the addresses of the declared functions of its type, sorted by
+// their priority. The table label is set to the table start and the optional count
+// symbol to the number of table entries.
+func addCondesTables[T any](p *parseAST[T], fileScope *scope.Scope) error {
+	for _, table := range p.cfg.Condes {
+		var entries []condesEntry
+		for _, entry := range p.condes {
+			if entry.typ == table.Type {
+				entries = append(entries, entry)
+			}
+		}
+		slices.SortStableFunc(entries, func(a, b condesEntry) int {
+			if table.Decreasing {
+				return cmp.Compare(b.priority, a.priority)
+			}
+			return cmp.Compare(a.priority, b.priority)
+		})
+
+		if err := parseSegment(p, ast.NewSegment(table.Segment)); err != nil {
+			return fmt.Errorf("parsing condes table segment: %w", err)
+		}
+
+		label, err := scope.NewSymbol(fileScope, table.Label, scope.LabelType)
+		if err != nil {
+			return fmt.Errorf("creating condes table label: %w", err)
+		}
+		p.currentSegment.addNode(&symbol{Symbol: label})
+
+		for _, entry := range entries {
+			dat := &data{
+				position: entry.position,
+				width:    2,
+				size:     expression.New(),
+				values:   []any{reference{name: entry.name, typ: fullAddress}},
+			}
+
+			if entry.scope == fileScope {
+				p.currentSegment.addNode(dat)
+				continue
+			}
+			p.currentSegment.addNode(scopeChange{scope: entry.scope})
+			p.currentSegment.addNode(dat)
+			p.currentSegment.addNode(scopeChange{scope: fileScope})
+		}
+
+		if table.Count != "" {
+			if _, err := newConstantSymbol(fileScope, table.Count, uint64(len(entries))); err != nil {
+				return fmt.Errorf("creating condes table count: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// newConstantSymbol creates a symbol with a constant value in the given scope.
+func newConstantSymbol(sc *scope.Scope, name string, value uint64) (*scope.Symbol, error) {
+	sym, err := scope.NewSymbol(sc, name, scope.EquType)
+	if err != nil {
+		return nil, fmt.Errorf("creating symbol: %w", err)
+	}
+
+	exp := expression.New()
+	exp.SetEvaluateOnce(true)
+	exp.SetValue(int64(value))
+	sym.SetExpression(exp)
+	return sym, nil
+}
@@ -0,0 +1,186 @@
+package assembler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/retroenv/retroasm/pkg/arch/m6502"
+	"github.com/retroenv/retrogolib/assert"
+)
+
+func TestAssemblerConfigSymbols(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		code     string
+		expected []byte
+		symbols  map[string]uint64
+	}{
+		{
+			name: "export and weak symbols",
+			config: `
+MEMORY { PRG: start = $8000, size = $100; }
+SEGMENTS { CODE: load = PRG, type = ro; }
+SYMBOLS {
+    __STACKSIZE__: type = weak, value = $0200;
+    __VERSION__:   type = export, value = 3;
+}`,
+			code:     ".segment \"CODE\"\nlda #>__STACKSIZE__\nldx #__VERSION__\n",
+			expected: []byte{0xa9, 0x02, 0xa2, 0x03},
+			symbols:  map[string]uint64{"__STACKSIZE__": 0x200, "__VERSION__": 3},
+		},
+		{
+			name: "program overrides weak symbol",
+			config: `
+MEMORY { PRG: start = $8000, size = $100; }
+SEGMENTS { CODE: load = PRG, type = ro; }
+SYMBOLS { __STACKSIZE__: type = weak, value = $0200; }`,
+			code:     ".segment \"CODE\"\n__STACKSIZE__ = $0100\nlda #>__STACKSIZE__\n",
+			expected: []byte{0xa9, 0x01},
+		},
+		{
+			name: "imported symbol defined by program",
+			config: `
+MEMORY { PRG: start = $8000, size = $100; }
+SEGMENTS { CODE: load = PRG, type = ro; }
+SYMBOLS { start: type = import; }`,
+			code:     ".segment \"CODE\"\nstart: nop\n",
+			expected: []byte{0xea},
+		},
+		{
+			name: "condes tables",
+			config: `
+MEMORY { PRG: start = $8000, size = $100; }
+SEGMENTS {
+    CODE:   load = PRG, type = ro;
+    RODATA: load = PRG, type = ro;
+}
+FEATURES {
+    CONDES: segment = RODATA, type = constructor,
+            label = __CONSTRUCTOR_TABLE__, count = __CONSTRUCTOR_COUNT__;
+    CONDES: type = destructor, segment = RODATA, order = decreasing,
+            label = __DESTRUCTOR_TABLE__, count = __DESTRUCTOR_COUNT__;
+}`,
+			code: `.segment "CODE"
+.constructor init_b, 10
+.constructor init_a
+.destructor done_a, 2
+.condes done_b, destructor, 20
+init_a: rts
+init_b: rts
+done_a: rts
+done_b: rts
+ldx #__CONSTRUCTOR_COUNT__
+`,
+			expected: []byte{
+				0x60, 0x60, 0x60, 0x60, 0xa2, 0x02,
+				0x00, 0x80, 0x01, 0x80, // constructors sorted by increasing priority
+				0x03, 0x80, 0x02, 0x80, // destructors sorted by decreasing priority
+			},
+			symbols: map[string]uint64{
+				"__CONSTRUCTOR_TABLE__": 0x8006,
+				"__CONSTRUCTOR_COUNT__": 2,
+				"__DESTRUCTOR_TABLE__":  0x800a,
+				"__DESTRUCTOR_COUNT__":  2,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := m6502.New()
+			assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(tt.config)))
+
+			var buf bytes.Buffer
+			asm := New(cfg, &buf)
+			assert.NoError(t, asm.Process(t.Context(), strings.NewReader(tt.code)))
+			assert.Equal(t, tt.expected, buf.Bytes())
+
+			symbols := map[string]uint64{}
+			for _, sym := range asm.DefinedSymbols() {
+				symbols[sym.Name] = sym.Value
+			}
+			for name, value := range tt.symbols {
+				assert.Equal(t, value, symbols[name], name)
+			}
+		})
+	}
+}
+
+func TestAssemblerConfigSymbolErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		symbols  string
+		code     string
+		expected error
+	}{
+		{
+			name:     "exported symbol defined by program",
+			symbols:  "SYMBOLS { value: type = export, value = 1; }",
+			code:     ".segment \"CODE\"\nvalue: nop\n",
+			expected: errConfigSymbolDefined,
+		},
+		{
+			name:     "imported symbol not defined",
+			symbols:  "SYMBOLS { start: type = import; }",
+			code:     ".segment \"CODE\"\nnop\n",
+			expected: errConfigSymbolMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := "MEMORY { PRG: start = $8000, size = $100; }\nSEGMENTS { CODE: load = PRG, type = ro; }\n" + tt.symbols
+			cfg := m6502.New()
+			assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(config)))
+
+			var buf bytes.Buffer
+			asm := New(cfg, &buf)
+			assert.ErrorIs(t, asm.Process(t.Context(), strings.NewReader(tt.code)), tt.expected)
+		})
+	}
+}
+
+func TestAssemblerDefines(t *testing.T) {
+	const code = `.segment "CODE"
+.ifdef PAL
+  lda #PAL
+.else
+  lda #0
+.endif
+.if DEBUG
+  nop
+.endif
+`
+
+	tests := []struct {
+		name     string
+		defines  map[string]uint64
+		expected []byte
+	}{
+		{
+			name:     "no defines",
+			defines:  map[string]uint64{"DEBUG": 0},
+			expected: []byte{0xa9, 0x00},
+		},
+		{
+			name:     "defined symbols",
+			defines:  map[string]uint64{"PAL": 1, "DEBUG": 2},
+			expected: []byte{0xa9, 0x01, 0xea},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := m6502.New()
+			assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(objectTestConfig)))
+
+			var buf bytes.Buffer
+			asm := New(cfg, &buf)
+			asm.SetDefines(tt.defines)
+			assert.NoError(t, asm.Process(t.Context(), strings.NewReader(code)))
+			assert.Equal(t, tt.expected, buf.Bytes())
+		})
+	}
+}
@@ -4,6 +4,7 @@ package assembler
 type conditionalContext struct {
 	processNodes bool
 	hasElse      bool // to detect invalid multiple else usages
+	failed       bool // the condition could not be evaluated, no branch is processed
 
 	parent *conditionalContext
 }
@@ -18,7 +19,7 @@ func processElseCondition[T any](expEval *expressionEvaluation[T]) error {
 	}
 
 	expEval.currentContext.hasElse = true
-	expEval.currentContext.processNodes = !expEval.currentContext.processNodes
+	expEval.currentContext.processNodes = !expEval.currentContext.processNodes && !expEval.currentContext.failed
 	return nil
 }
 
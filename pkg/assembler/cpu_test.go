@@ -0,0 +1,238 @@
+package assembler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/retroenv/retroasm/pkg/arch/m6502"
+	"github.com/retroenv/retroasm/pkg/assembler/config"
+	"github.com/retroenv/retrogolib/assert"
+)
+
+func runCPUTest(t *testing.T, mode config.CompatibilityMode, cpu, code string, files map[string]string) ([]byte, error) {
+	t.Helper()
+
+	cfg := m6502.New()
+	if cpu != "" {
+		var err error
+		cfg, err = m6502.NewCPU(cpu)
+		assert.NoError(t, err)
+	}
+	cfg.CompatibilityMode = mode
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(unitTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	asm.fileReader = func(name string) ([]byte, error) {
+		content, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("file '%s' not found", name)
+		}
+		return []byte(content), nil
+	}
+	err := asm.Process(t.Context(), strings.NewReader(".segment \"HEADER\"\n"+code))
+	return buf.Bytes(), err
+}
+
+func TestAssemblerUndocumentedInstructions(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     config.CompatibilityMode
+		code     string
+		expected []byte
+		wantErr  string
+	}{
+		{
+			name: "asm6f stable instructions",
+			mode: config.CompatAsm6,
+			code: `lax $10
+sax $10,y
+dcp $1234
+isc $10,x
+slo ($10,x)
+rla ($10),y
+sre $10
+rra $10
+anc #$0b
+alr #1
+arr #2
+axs #3
+`,
+			expected: []byte{
+				0xa7, 0x10, 0x97, 0x10, 0xcf, 0x34, 0x12, 0xf7, 0x10, 0x03, 0x10, 0x33, 0x10,
+				0x47, 0x10, 0x67, 0x10, 0x0b, 0x0b, 0x4b, 0x01, 0x6b, 0x02, 0xcb, 0x03,
+			},
+		},
+		{
+			name:    "asm6f unstable instruction without .unstable",
+			mode:    config.CompatAsm6,
+			code:    "shx $1234,y\n",
+			wantErr: "unexpected identifier 'shx'",
+		},
+		{
+			name:     "asm6f unstable instructions",
+			mode:     config.CompatAsm6,
+			code:     ".unstable\nshx $1234,y\nahx ($10),y\nlas $1234,y\n",
+			expected: []byte{0x9e, 0x34, 0x12, 0x93, 0x10, 0xbb, 0x34, 0x12},
+		},
+		{
+			name:    "asm6f highly unstable instruction with .unstable",
+			mode:    config.CompatAsm6,
+			code:    ".unstable\nxaa #1\n",
+			wantErr: "unexpected identifier 'xaa'",
+		},
+		{
+			name:     "asm6f highly unstable instructions",
+			mode:     config.CompatAsm6,
+			code:     ".hunstable\nxaa #1\nlax #2\nlax $10\ntas $1234,y\n",
+			expected: []byte{0x8b, 0x01, 0xab, 0x02, 0xa7, 0x10, 0x9b, 0x34, 0x12},
+		},
+		{
+			name:    "default mode without cpu",
+			mode:    config.CompatDefault,
+			code:    "lax $10\n",
+			wantErr: "unexpected identifier 'lax'",
+		},
+		{
+			name:     "6502x cpu",
+			mode:     config.CompatDefault,
+			code:     ".setcpu \"6502X\"\nlax $10\nshy $1234,x\nane #1\n",
+			expected: []byte{0xa7, 0x10, 0x9c, 0x34, 0x12, 0x8b, 0x01},
+		},
+		{
+			name:    "unsupported cpu",
+			mode:    config.CompatDefault,
+			code:    ".setcpu \"z80\"\n",
+			wantErr: "unsupported CPU 'z80'",
+		},
+		{
+			name:     "included file",
+			mode:     config.CompatDefault,
+			code:     ".setcpu \"6502x\"\n.include \"inc.asm\"\n",
+			expected: []byte{0xa7, 0x10},
+		},
+		{
+			name:     "macro",
+			mode:     config.CompatAsm6,
+			code:     ".unstable\n.macro store\nshx $1234,y\nENDM\n  store\n",
+			expected: []byte{0x9e, 0x34, 0x12},
+		},
+	}
+
+	files := map[string]string{
+		"inc.asm": "lax $10\n",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := runCPUTest(t, tt.mode, "", tt.code, files)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, output[:len(tt.expected)])
+		})
+	}
+}
+
+func TestAssembler65C02Instructions(t *testing.T) {
+	tests := []struct {
+		name     string
+		cpu      string // default CPU variant of the architecture
+		code     string
+		expected []byte
+		wantErr  string
+	}{
+		{
+			name: "65c02 instructions",
+			code: `.pc02
+bra target
+phx
+phy
+plx
+ply
+stz $10
+stz $1234,x
+trb $10
+tsb $1234
+target:
+`,
+			expected: []byte{
+				0x80, 0x0e, 0xda, 0x5a, 0xfa, 0x7a, 0x64, 0x10, 0x9e, 0x34, 0x12,
+				0x14, 0x10, 0x0c, 0x34, 0x12,
+			},
+		},
+		{
+			name:     "65c02 addressing modes",
+			code:     ".setcpu \"65C02\"\nlda ($10)\nsta ($20)\njmp ($1234,x)\njmp ($1234)\nbit #1\ninc a\n",
+			expected: []byte{0xb2, 0x10, 0x92, 0x20, 0x7c, 0x34, 0x12, 0x6c, 0x34, 0x12, 0x89, 0x01, 0x1a},
+		},
+		{
+			name:     "rockwell instructions",
+			code:     ".pc02\nloop:\nrmb0 $10\nsmb7 $10\nbbr0 $10, loop\nbbs7 $10, done\ndone:\n",
+			expected: []byte{0x07, 0x10, 0xf7, 0x10, 0x0f, 0x10, 0xf9, 0xff, 0x10, 0x00},
+		},
+		{
+			name:    "rockwell instructions on 65sc02",
+			code:    ".psc02\nrmb0 $10\n",
+			wantErr: "unexpected identifier 'rmb0'",
+		},
+		{
+			name:     "wdc instructions",
+			code:     ".setcpu \"w65c02\"\nwai\nstp\n",
+			expected: []byte{0xcb, 0xdb},
+		},
+		{
+			name:    "wdc instructions on 65c02",
+			code:    ".pc02\nwai\n",
+			wantErr: "unexpected identifier 'wai'",
+		},
+		{
+			name:     "65c02 instructions without cpu selection",
+			code:     "stz $10\nphx\nloop:\nbra loop\n",
+			expected: []byte{0x64, 0x10, 0xda, 0x80, 0xfe},
+		},
+		{
+			name:    "65c02 instructions on 6502",
+			code:    ".setcpu \"6502\"\nphx\n",
+			wantErr: "unexpected identifier 'phx'",
+		},
+		{
+			name:    "6502 after 65c02",
+			code:    ".pc02\nstz $10\n.p02\nstz $10\n",
+			wantErr: "unexpected identifier 'stz'",
+		},
+		{
+			name:     "default cpu variant",
+			cpu:      "65C02",
+			code:     "stz $10\nloop:\nbbr1 $10, loop\n",
+			expected: []byte{0x64, 0x10, 0x1f, 0x10, 0xfd},
+		},
+		{
+			name:    "6502 after default cpu variant",
+			cpu:     "65c02",
+			code:    ".p02\nstz $10\n",
+			wantErr: "unexpected identifier 'stz'",
+		},
+		{
+			name:    "zero page indirect on 6502",
+			code:    "lda ($10)\n",
+			wantErr: "invalid indirect addressing mode usage",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := runCPUTest(t, config.CompatDefault, tt.cpu, tt.code, nil)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, output[:len(tt.expected)])
+		})
+	}
+}
@@ -2,11 +2,14 @@ package assembler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 )
 
 // updateDataSizesStep updates the size information of data nodes.
 func updateDataSizesStep[T any](_ context.Context, asm *Assembler[T]) error {
+	var errs []error
+
 	for _, seg := range asm.segmentsOrder {
 		for _, node := range seg.nodes {
 			dat, ok := node.(*data)
@@ -15,11 +18,11 @@ func updateDataSizesStep[T any](_ context.Context, asm *Assembler[T]) error {
 			}
 
 			if err := updateDataSize(dat); err != nil {
-				return err
+				errs = append(errs, nodeError(dat, err))
 			}
 		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 func updateDataSize(dat *data) error {
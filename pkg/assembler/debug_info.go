@@ -0,0 +1,418 @@
+package assembler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/retroenv/retroasm/pkg/lexer/token"
+	"github.com/retroenv/retroasm/pkg/scope"
+	"github.com/retroenv/retrogolib/set"
+)
+
+// debugLineTypeMacro is the ld65 line type of source lines that were expanded from a macro.
+const debugLineTypeMacro = 2
+
+// debugSegment is a segment record of a debug info file.
+type debugSegment struct {
+	usage    SegmentUsage
+	typ      string // ro or rw
+	addrSize string
+}
+
+// debugSpan is a span record of a debug info file, a block of bytes of a segment.
+type debugSpan struct {
+	segment int
+	start   uint64 // offset relative to the segment start
+	size    uint64
+}
+
+// debugLineKey identifies a line record of a debug info file.
+type debugLineKey struct {
+	file  int
+	line  int
+	count int // macro expansion depth
+}
+
+// debugLine is a line record of a debug info file.
+type debugLine struct {
+	debugLineKey
+	spans []int
+}
+
+// debugRange is the address range that a scope covers in a segment.
+type debugRange struct {
+	start, end uint64
+}
+
+// debugScope is a scope record of a debug info file.
+type debugScope struct {
+	scope  *scope.Scope
+	parent int // -1 for the file scope
+	symbol int // id of the symbol that defines the scope, -1 if not set
+	size   uint64
+	spans  []int
+	ranges map[int]*debugRange // maps segment id to the covered address range
+}
+
+// debugSymbol is a symbol record of a debug info file.
+type debugSymbol struct {
+	symbol  *scope.Symbol
+	value   uint64
+	scope   int
+	line    int // id of the line of the definition, -1 if unknown
+	segment int // -1 for symbols that are not part of a segment
+}
+
+// debugInfoWriter writes a debug info file in the format of the ld65 linker.
+type debugInfoWriter[T any] struct {
+	asm        *Assembler[T]
+	outputName string
+
+	fileIDs    map[string]int
+	segmentIDs map[string]int
+	segments   []debugSegment
+	spans      []debugSpan
+	lines      []*debugLine
+	lineIDs    map[debugLineKey]int
+	scopes     []*debugScope
+	scopeIDs   map[*scope.Scope]int
+	symbols    []debugSymbol
+}
+
+// WriteDebugInfo writes a debug info file in the format of the ld65 linker that lists the
+// source files, lines, segments, spans, scopes and symbols of the assembled program.
+// Debuggers that read cc65 debug info files use it for source level debugging.
+// The output name is referenced by the segments that are part of the output file.
+// Call this after Process.
+func (asm *Assembler[T]) WriteDebugInfo(writer io.Writer, outputName string) error {
+	dw := &debugInfoWriter[T]{
+		asm:        asm,
+		outputName: outputName,
+		fileIDs:    map[string]int{},
+		segmentIDs: map[string]int{},
+		lineIDs:    map[debugLineKey]int{},
+		scopeIDs:   map[*scope.Scope]int{},
+	}
+
+	for i, name := range asm.sources.files {
+		dw.fileIDs[name] = i
+	}
+	dw.addSegments()
+	dw.addScopes(asm.fileScope, -1)
+	dw.addNodeSpans()
+	dw.addScopeSpans()
+	dw.addSymbols()
+
+	w := bufio.NewWriter(writer)
+	dw.write(w)
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing debug info: %w", err)
+	}
+	return nil
+}
+
+// addSegments adds all used segments.
+func (dw *debugInfoWriter[T]) addSegments() {
+	for i, usage := range dw.asm.SegmentUsage() {
+		seg := dw.asm.segmentsOrder[i]
+		typ := "rw"
+		if strings.EqualFold(seg.config.Typ, "ro") {
+			typ = "ro"
+		}
+		addrSize := "absolute"
+		if strings.EqualFold(seg.config.Typ, "zp") {
+			addrSize = "zeropage"
+		}
+
+		dw.segmentIDs[usage.Name] = i
+		dw.segments = append(dw.segments, debugSegment{
+			usage:    usage,
+			typ:      typ,
+			addrSize: addrSize,
+		})
+	}
+}
+
+// addScopes adds the scope and all its child scopes.
+func (dw *debugInfoWriter[T]) addScopes(sc *scope.Scope, parent int) {
+	id := len(dw.scopes)
+	dw.scopeIDs[sc] = id
+	dw.scopes = append(dw.scopes, &debugScope{
+		scope:  sc,
+		parent: parent,
+		symbol: -1,
+		ranges: map[int]*debugRange{},
+	})
+
+	for _, child := range sc.Children() {
+		dw.addScopes(child, id)
+	}
+}
+
+// addNodeSpans adds a span for every node that emits bytes or reserves space and
+// assigns it to the line of the node and the address ranges of its scopes.
+func (dw *debugInfoWriter[T]) addNodeSpans() {
+	currentScope := dw.asm.fileScope
+
+	for segmentID, seg := range dw.asm.segmentsOrder {
+		segmentStart := dw.segments[segmentID].usage.Start
+
+		for _, node := range seg.nodes {
+			var (
+				pos     token.Position
+				address uint64
+				size    uint64
+			)
+
+			switch n := node.(type) {
+			case scopeChange:
+				currentScope = n.scope
+				continue
+
+			case *data:
+				pos, address = n.position, n.address
+				for _, val := range n.values {
+					if b, ok := val.([]byte); ok {
+						size += uint64(len(b))
+					}
+				}
+
+			case *instruction:
+				pos, address, size = n.position, n.address, uint64(len(n.opcodes))
+
+			case *variable:
+				if n.v.UseOffsetCounter {
+					continue
+				}
+				pos, address, size = n.Position(), n.address, uint64(n.v.Size)
+
+			default:
+				continue
+			}
+
+			if size == 0 {
+				continue
+			}
+
+			spanID := len(dw.spans)
+			dw.spans = append(dw.spans, debugSpan{
+				segment: segmentID,
+				start:   address - segmentStart,
+				size:    size,
+			})
+			if lineID, ok := dw.lineID(pos); ok {
+				line := dw.lines[lineID]
+				line.spans = append(line.spans, spanID)
+			}
+
+			for id := dw.scopeIDs[currentScope]; id >= 0; id = dw.scopes[id].parent {
+				dw.scopes[id].extendRange(segmentID, address, address+size)
+			}
+		}
+	}
+}
+
+// extendRange extends the address range that the scope covers in the segment.
+func (sc *debugScope) extendRange(segmentID int, start, end uint64) {
+	r, ok := sc.ranges[segmentID]
+	if !ok {
+		sc.ranges[segmentID] = &debugRange{start: start, end: end}
+		return
+	}
+	r.start = min(r.start, start)
+	r.end = max(r.end, end)
+}
+
+// addScopeSpans adds a span for every address range that a scope covers.
+func (dw *debugInfoWriter[T]) addScopeSpans() {
+	for _, sc := range dw.scopes {
+		segmentIDs := make([]int, 0, len(sc.ranges))
+		for id := range sc.ranges {
+			segmentIDs = append(segmentIDs, id)
+		}
+		slices.Sort(segmentIDs)
+
+		for _, segmentID := range segmentIDs {
+			r := sc.ranges[segmentID]
+			sc.spans = append(sc.spans, len(dw.spans))
+			sc.size += r.end - r.start
+			dw.spans = append(dw.spans, debugSpan{
+				segment: segmentID,
+				start:   r.start - dw.segments[segmentID].usage.Start,
+				size:    r.end - r.start,
+			})
+		}
+	}
+}
+
+// addSymbols adds all symbols that resolve to a number and links named scopes to
+// the symbols that define them.
+func (dw *debugInfoWriter[T]) addSymbols() {
+	// exported symbols of programs with multiple files are also part of the global scope
+	added := set.New[*scope.Symbol]()
+
+	for scopeID, sc := range dw.scopes {
+		for _, sym := range sc.scope.Symbols() {
+			if added.Contains(sym) {
+				continue
+			}
+			value, ok := symbolNumericValue(sc.scope, sym)
+			if !ok {
+				continue
+			}
+			added.Add(sym)
+
+			ds := debugSymbol{
+				symbol:  sym,
+				value:   value,
+				scope:   scopeID,
+				line:    -1,
+				segment: -1,
+			}
+			if lineID, ok := dw.lineID(sym.Position()); ok {
+				ds.line = lineID
+			}
+			if segmentID, ok := dw.segmentIDs[sym.Segment()]; ok && sym.Segment() != "" {
+				ds.segment = segmentID
+			}
+			dw.symbols = append(dw.symbols, ds)
+		}
+	}
+
+	for _, sc := range dw.scopes {
+		if sc.parent < 0 || sc.scope.Name() == "" {
+			continue
+		}
+		parent := dw.scopes[sc.parent].scope
+		for symbolID, ds := range dw.symbols {
+			if ds.symbol.Name() == sc.scope.Name() && dw.scopes[ds.scope].scope == parent {
+				sc.symbol = symbolID
+				break
+			}
+		}
+	}
+}
+
+// lineID returns the id of the line record for the source position, the record is
+// created if it does not exist yet. Positions of unknown files have no line record.
+func (dw *debugInfoWriter[T]) lineID(pos token.Position) (int, bool) {
+	fileID, ok := dw.fileIDs[pos.File]
+	if !ok || pos.Line == 0 {
+		return 0, false
+	}
+
+	key := debugLineKey{file: fileID, line: pos.Line}
+	for _, exp := range pos.ExpansionStack() {
+		if exp.Kind == token.MacroExpansion {
+			key.count++
+		}
+	}
+
+	if id, ok := dw.lineIDs[key]; ok {
+		return id, true
+	}
+	id := len(dw.lines)
+	dw.lineIDs[key] = id
+	dw.lines = append(dw.lines, &debugLine{debugLineKey: key})
+	return id, true
+}
+
+// write writes all records of the debug info file.
+func (dw *debugInfoWriter[T]) write(w io.Writer) {
+	sources := dw.asm.sources
+
+	_, _ = fmt.Fprintln(w, "version\tmajor=2,minor=0")
+	_, _ = fmt.Fprintf(w, "info\tcsym=0,file=%d,lib=0,line=%d,mod=1,scope=%d,seg=%d,span=%d,sym=%d,type=0\n",
+		len(sources.files), len(dw.lines), len(dw.scopes), len(dw.segments), len(dw.spans), len(dw.symbols))
+
+	for id, name := range sources.files {
+		_, _ = fmt.Fprintf(w, "file\tid=%d,name=\"%s\",size=%d,mtime=0x00000000,mod=0\n", id, name, sources.sizes[name])
+	}
+
+	for id, line := range dw.lines {
+		s := fmt.Sprintf("line\tid=%d,file=%d,line=%d", id, line.file, line.line)
+		if line.count > 0 {
+			s += fmt.Sprintf(",type=%d,count=%d", debugLineTypeMacro, line.count)
+		}
+		_, _ = fmt.Fprintln(w, s+spanList(line.spans))
+	}
+
+	_, _ = fmt.Fprintf(w, "mod\tid=0,name=\"%s\",file=0\n", dw.asm.sourceName)
+
+	for id, seg := range dw.segments {
+		s := fmt.Sprintf("seg\tid=%d,name=\"%s\",start=0x%06X,size=0x%04X,addrsize=%s,type=%s",
+			id, seg.usage.Name, seg.usage.Start, seg.usage.Size, seg.addrSize, seg.typ)
+		outputName := dw.outputName
+		if seg.usage.OutputFile != "" {
+			outputName = seg.usage.OutputFile
+		}
+		if seg.usage.InOutput && outputName != "" {
+			s += fmt.Sprintf(",oname=\"%s\",ooffs=%d", outputName, seg.usage.FileOffset)
+		}
+		_, _ = fmt.Fprintln(w, s)
+	}
+
+	for id, span := range dw.spans {
+		_, _ = fmt.Fprintf(w, "span\tid=%d,seg=%d,start=%d,size=%d\n", id, span.segment, span.start, span.size)
+	}
+
+	for id, sc := range dw.scopes {
+		s := fmt.Sprintf("scope\tid=%d,name=\"%s\",mod=0", id, sc.scope.Name())
+		if sc.parent >= 0 {
+			s += ",type=scope"
+		}
+		if sc.size > 0 {
+			s += fmt.Sprintf(",size=%d", sc.size)
+		}
+		if sc.parent >= 0 {
+			s += fmt.Sprintf(",parent=%d", sc.parent)
+		}
+		if sc.symbol >= 0 {
+			s += fmt.Sprintf(",sym=%d", sc.symbol)
+		}
+		_, _ = fmt.Fprintln(w, s+spanList(sc.spans))
+	}
+
+	for id, sym := range dw.symbols {
+		_, _ = fmt.Fprintln(w, dw.symbolRecord(id, sym))
+	}
+}
+
+// symbolRecord returns the record of a symbol.
+func (dw *debugInfoWriter[T]) symbolRecord(id int, sym debugSymbol) string {
+	addrSize := "absolute"
+	if sym.value <= 0xff {
+		addrSize = "zeropage"
+	}
+
+	s := fmt.Sprintf("sym\tid=%d,name=\"%s\",addrsize=%s,scope=%d", id, sym.symbol.Name(), addrSize, sym.scope)
+	if sym.line >= 0 {
+		s += fmt.Sprintf(",def=%d", sym.line)
+	}
+	s += fmt.Sprintf(",val=0x%X", sym.value)
+	if sym.segment >= 0 {
+		s += fmt.Sprintf(",seg=%d", sym.segment)
+	}
+
+	switch sym.symbol.Type() {
+	case scope.LabelType, scope.FunctionType, scope.VariableType:
+		return s + ",type=lab"
+	default:
+		return s + ",type=equ"
+	}
+}
+
+// spanList returns the span attribute for the given span ids, it is empty if no span is given.
+func spanList(spans []int) string {
+	if len(spans) == 0 {
+		return ""
+	}
+	ids := make([]string, 0, len(spans))
+	for _, id := range spans {
+		ids = append(ids, fmt.Sprint(id))
+	}
+	return ",span=" + strings.Join(ids, "+")
+}
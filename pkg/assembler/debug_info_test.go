@@ -0,0 +1,79 @@
+package assembler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/retroenv/retroasm/pkg/arch/m6502"
+	"github.com/retroenv/retrogolib/assert"
+)
+
+var debugInfoTestConfig = `
+MEMORY {
+    ZP:     start = $0000,  size = $0100;
+    PRG:    start = $8000,  size = $4000;
+}
+
+SEGMENTS {
+    ZEROPAGE: load = ZP,  type = zp;
+    CODE:     load = PRG, type = ro;
+}
+`
+
+var debugInfoTestCode = `.segment "ZEROPAGE"
+counter: .res 1
+
+.segment "CODE"
+.include "defs.asm"
+.proc reset
+  lda #VALUE
+  sta counter
+  rts
+.endproc
+`
+
+var debugInfoTestExpected = `version	major=2,minor=0
+info	csym=0,file=2,lib=0,line=6,mod=1,scope=2,seg=2,span=7,sym=3,type=0
+file	id=0,name="main.asm",size=127,mtime=0x00000000,mod=0
+file	id=1,name="defs.asm",size=12,mtime=0x00000000,mod=0
+line	id=0,file=0,line=2,span=0
+line	id=1,file=0,line=7,span=1
+line	id=2,file=0,line=8,span=2
+line	id=3,file=0,line=9,span=3
+line	id=4,file=1,line=1
+line	id=5,file=0,line=6
+mod	id=0,name="main.asm",file=0
+seg	id=0,name="ZEROPAGE",start=0x000000,size=0x0001,addrsize=zeropage,type=rw
+seg	id=1,name="CODE",start=0x008000,size=0x0005,addrsize=absolute,type=ro,oname="game.nes",ooffs=0
+span	id=0,seg=0,start=0,size=1
+span	id=1,seg=1,start=0,size=2
+span	id=2,seg=1,start=2,size=2
+span	id=3,seg=1,start=4,size=1
+span	id=4,seg=0,start=0,size=1
+span	id=5,seg=1,start=0,size=5
+span	id=6,seg=1,start=0,size=5
+scope	id=0,name="",mod=0,size=6,span=4+5
+scope	id=1,name="reset",mod=0,type=scope,size=5,parent=0,sym=2,span=6
+sym	id=0,name="VALUE",addrsize=zeropage,scope=0,def=4,val=0x42,type=equ
+sym	id=1,name="counter",addrsize=zeropage,scope=0,def=0,val=0x0,seg=0,type=lab
+sym	id=2,name="reset",addrsize=absolute,scope=0,def=5,val=0x8000,seg=1,type=lab
+`
+
+func TestAssemblerWriteDebugInfo(t *testing.T) {
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(debugInfoTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	asm.SetSourceName("main.asm")
+	asm.fileReader = func(name string) ([]byte, error) {
+		assert.Equal(t, "defs.asm", name)
+		return []byte("VALUE = $42\n"), nil
+	}
+	assert.NoError(t, asm.Process(t.Context(), strings.NewReader(debugInfoTestCode)))
+
+	var debugInfo bytes.Buffer
+	assert.NoError(t, asm.WriteDebugInfo(&debugInfo, "game.nes"))
+	assert.Equal(t, debugInfoTestExpected, debugInfo.String())
+}
@@ -0,0 +1,32 @@
+package assembler
+
+import "github.com/retroenv/retrogolib/set"
+
+// dependencyRecorder records the names of all files that an assembler run reads,
+// to be able to write the dependencies of the output for build tools.
+// A nil recorder ignores all records.
+type dependencyRecorder struct {
+	files []string // names of all read files in the order of the first read
+	added set.Set[string]
+}
+
+func newDependencyRecorder() *dependencyRecorder {
+	return &dependencyRecorder{
+		added: set.New[string](),
+	}
+}
+
+// add records a read file, files without a name are not recorded.
+func (r *dependencyRecorder) add(name string) {
+	if r == nil || name == "" || r.added.Contains(name) {
+		return
+	}
+	r.added.Add(name)
+	r.files = append(r.files, name)
+}
+
+// Dependencies returns the names of all source files and included files that were read,
+// in the order of their first read. Call this after Process, ProcessFiles or ProcessAST.
+func (asm *Assembler[T]) Dependencies() []string {
+	return asm.dependencies.files
+}
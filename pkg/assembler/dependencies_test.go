@@ -0,0 +1,29 @@
+package assembler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/retroenv/retroasm/pkg/arch/m6502"
+	"github.com/retroenv/retrogolib/assert"
+)
+
+func TestAssemblerDependencies(t *testing.T) {
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(unitTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	asm.SetSourceName("main.asm")
+	asm.fileReader = func(name string) ([]byte, error) {
+		if name == "data.bin" {
+			return []byte{0x01}, nil
+		}
+		return []byte("nop\n"), nil
+	}
+
+	code := ".segment \"HEADER\"\n.include \"defs.asm\"\n.incbin \"data.bin\"\n.include \"defs.asm\"\n"
+	assert.NoError(t, asm.Process(t.Context(), strings.NewReader(code)))
+	assert.Equal(t, []string{"main.asm", "defs.asm", "data.bin"}, asm.Dependencies())
+}
@@ -0,0 +1,206 @@
+package assembler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/retroenv/retroasm/pkg/arch"
+	"github.com/retroenv/retroasm/pkg/lexer/token"
+	"github.com/retroenv/retroasm/pkg/parser"
+	"github.com/retroenv/retroasm/pkg/parser/ast"
+	"github.com/retroenv/retroasm/pkg/scope"
+)
+
+// Codes that categorize the diagnostics of an assembler run.
+const (
+	CodeError           = "error" // uncategorized error
+	CodeSyntax          = "syntax"
+	CodeUndefinedSymbol = "undefined-symbol"
+	CodeDuplicateSymbol = "duplicate-symbol"
+	CodeBranchRange     = "branch-out-of-range"
+	CodeLongBranch      = "long-branch" // branch that was expanded because its target is out of range
+)
+
+// errorCategories maps sentinel errors to the diagnostic code and hints of the category.
+var errorCategories = []struct {
+	err   error
+	code  string
+	hints []string
+}{
+	{
+		err:   scope.ErrSymbolNotFound,
+		code:  CodeUndefinedSymbol,
+		hints: []string{"check the spelling of the symbol name", "define the symbol or include the file that defines it"},
+	},
+	{
+		err:   scope.ErrSymbolExists,
+		code:  CodeDuplicateSymbol,
+		hints: []string{"rename one of the symbols", "use = to define a symbol that can be reassigned"},
+	},
+	{
+		err:   arch.ErrBranchOutOfRange,
+		code:  CodeBranchRange,
+		hints: []string{"move the branch target closer to the branch", "enable long branches to expand the branch with a jmp"},
+	},
+}
+
+// Error is an assembler error that is located at the source position of the node that caused it.
+type Error struct {
+	position token.Position
+	err      error
+}
+
+// Error returns the error message prefixed by the source position and the
+// include and macro expansions that led to it.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.position.Trace(), e.err)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Position returns the source position of the error.
+func (e *Error) Position() token.Position {
+	return e.position
+}
+
+// Diagnostic describes a single problem that was found during assembly.
+type Diagnostic struct {
+	Message  string
+	Position token.Position // zero if the problem is not related to a source position
+	Code     string
+	Hints    []string
+}
+
+// locatedError is implemented by errors that are located at a source position.
+type locatedError interface {
+	error
+	Position() token.Position
+	Unwrap() error
+}
+
+// Diagnostics returns a diagnostic for every located error that the error contains,
+// for example when multiple independent errors were found during a step. An error
+// that does not contain any located error results in a single diagnostic without
+// position. A nil error returns no diagnostics.
+func Diagnostics(err error) []Diagnostic {
+	if err == nil {
+		return nil
+	}
+
+	located := appendLocatedErrors(nil, err)
+	if len(located) == 0 {
+		code, hints := errorCategory(err)
+		return []Diagnostic{{Message: err.Error(), Code: code, Hints: hints}}
+	}
+
+	diagnostics := make([]Diagnostic, 0, len(located))
+	for _, e := range located {
+		e = innermostLocatedError(e)
+		code, hints := errorCategory(e)
+		diagnostics = append(diagnostics, Diagnostic{
+			Message:  diagnosticMessage(e.Unwrap()),
+			Position: e.Position(),
+			Code:     code,
+			Hints:    hints,
+		})
+	}
+	return diagnostics
+}
+
+// appendLocatedErrors appends the outermost located errors of the error tree to the list.
+func appendLocatedErrors(located []locatedError, err error) []locatedError {
+	if e, ok := err.(locatedError); ok { //nolint:errorlint // the error tree is traversed manually
+		return append(located, e)
+	}
+
+	switch e := err.(type) { //nolint:errorlint // the error tree is traversed manually
+	case interface{ Unwrap() []error }:
+		for _, wrapped := range e.Unwrap() {
+			located = appendLocatedErrors(located, wrapped)
+		}
+	case interface{ Unwrap() error }:
+		if wrapped := e.Unwrap(); wrapped != nil {
+			located = appendLocatedErrors(located, wrapped)
+		}
+	}
+	return located
+}
+
+// innermostLocatedError returns the innermost located error that the located error
+// wraps, or the error itself. The innermost error has the most specific position.
+func innermostLocatedError(e locatedError) locatedError {
+	for wrapped := e.Unwrap(); wrapped != nil; wrapped = unwrapSingle(wrapped) {
+		if inner, ok := wrapped.(locatedError); ok { //nolint:errorlint // the error chain is traversed manually
+			e = inner
+		}
+	}
+	return e
+}
+
+// diagnosticMessage returns the message of the error without the context that the
+// assembler steps added while returning it, like "evaluating node 3 in segment 0: ".
+// Errors that add details to the error that they wrap are kept.
+func diagnosticMessage(err error) string {
+	for {
+		wrapped := unwrapSingle(err)
+		if wrapped == nil {
+			return err.Error()
+		}
+		message, wrappedMessage := err.Error(), wrapped.Error()
+		if message != wrappedMessage && !strings.HasSuffix(message, ": "+wrappedMessage) {
+			return message
+		}
+		err = wrapped
+	}
+}
+
+// unwrapSingle returns the error that the error wraps, a joined error is only
+// unwrapped if it contains a single error.
+func unwrapSingle(err error) error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok { //nolint:errorlint // the error chain is traversed manually
+		if errs := joined.Unwrap(); len(errs) == 1 {
+			return errs[0]
+		}
+		return nil
+	}
+	return errors.Unwrap(err)
+}
+
+// errorCategory returns the diagnostic code and hints for the error.
+func errorCategory(err error) (string, []string) {
+	var parserErr *parser.Error
+	if errors.As(err, &parserErr) {
+		return CodeSyntax, nil
+	}
+
+	for _, category := range errorCategories {
+		if errors.Is(err, category.err) {
+			return category.code, category.hints
+		}
+	}
+	return CodeError, nil
+}
+
+// nodeError returns the error located at the source position of the node.
+// The error is returned unchanged if the node has no known position or the
+// error already contains a located error, which is more specific for errors
+// of included files and expanded macros.
+func nodeError(node ast.Node, err error) error {
+	positioner, ok := node.(interface{ Position() token.Position })
+	if !ok {
+		return err
+	}
+	pos := positioner.Position()
+	if pos.Line == 0 {
+		return err
+	}
+
+	if len(appendLocatedErrors(nil, err)) > 0 {
+		return err
+	}
+	return &Error{position: pos, err: err}
+}
@@ -0,0 +1,176 @@
+package assembler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/retroenv/retroasm/pkg/arch/m6502"
+	"github.com/retroenv/retroasm/pkg/lexer/token"
+	"github.com/retroenv/retroasm/pkg/scope"
+	"github.com/retroenv/retrogolib/assert"
+)
+
+func TestDiagnostics(t *testing.T) {
+	assert.Empty(t, Diagnostics(nil))
+
+	unlocated := errors.New("config error")
+	diagnostics := Diagnostics(unlocated)
+	assert.Len(t, diagnostics, 1)
+	assert.Equal(t, Diagnostic{Message: "config error", Code: CodeError}, diagnostics[0])
+
+	first := &instruction{position: token.Position{Line: 2, Column: 1}}
+	second := &data{position: token.Position{Line: 5, Column: 3}}
+	notFound := fmt.Errorf("getting symbol: %w", scope.ErrSymbolNotFound)
+	err := fmt.Errorf("executing step: %w", errors.Join(
+		nodeError(first, notFound),
+		nodeError(second, errors.New("invalid data")),
+	))
+
+	diagnostics = Diagnostics(err)
+	assert.Len(t, diagnostics, 2)
+	assert.Equal(t, first.position, diagnostics[0].Position)
+	assert.Equal(t, CodeUndefinedSymbol, diagnostics[0].Code)
+	assert.Equal(t, scope.ErrSymbolNotFound.Error(), diagnostics[0].Message)
+	assert.NotEmpty(t, diagnostics[0].Hints)
+	assert.Equal(t, second.position, diagnostics[1].Position)
+	assert.Equal(t, CodeError, diagnostics[1].Code)
+}
+
+func TestNodeError(t *testing.T) {
+	err := errors.New("test")
+
+	// nodes without a known position keep the error unchanged
+	assert.Equal(t, err, nodeError(&instruction{}, err))
+	assert.Equal(t, err, nodeError(scopeChange{}, err))
+
+	located := nodeError(&instruction{position: token.Position{Line: 3, Column: 2}}, err)
+	assert.Equal(t, "line 3 column 2: test", located.Error())
+	assert.ErrorIs(t, located, err)
+
+	// already located errors are not wrapped again
+	assert.Equal(t, located, nodeError(&data{position: token.Position{Line: 4}}, located))
+}
+
+func TestAssemblerMultipleErrors(t *testing.T) {
+	code := `
+.segment "HEADER"
+lda missing1
+nop
+sta missing2
+`
+	_, err := runAsm6Test(t, unitTestConfig, code)
+	assert.Error(t, err)
+
+	diagnostics := Diagnostics(err)
+	assert.Len(t, diagnostics, 2)
+	assert.Equal(t, 3, diagnostics[0].Position.Line)
+	assert.Equal(t, 5, diagnostics[1].Position.Line)
+}
+
+func TestAssemblerConditionError(t *testing.T) {
+	code := `.segment "HEADER"
+.if missing
+nop
+.else
+nop
+.endif
+`
+	_, err := runAsm6Test(t, unitTestConfig, code)
+	assert.Error(t, err)
+
+	// the else and endif directives of the failed condition are not reported
+	diagnostics := Diagnostics(err)
+	assert.Len(t, diagnostics, 1)
+	assert.Equal(t, 2, diagnostics[0].Position.Line)
+	assert.Equal(t, CodeUndefinedSymbol, diagnostics[0].Code)
+	assert.Equal(t, "symbol not found in scope: 'missing'", diagnostics[0].Message)
+}
+
+func TestAssemblerDuplicateMacroError(t *testing.T) {
+	code := `.segment "HEADER"
+MACRO store
+  nop
+ENDM
+MACRO store
+  nop
+ENDM
+`
+	_, err := runAsm6Test(t, unitTestConfig, code)
+	assert.Error(t, err)
+
+	diagnostics := Diagnostics(err)
+	assert.Len(t, diagnostics, 1)
+	assert.Equal(t, 5, diagnostics[0].Position.Line)
+}
+
+func TestAssemblerErrorExpansions(t *testing.T) {
+	code := `.segment "HEADER"
+.include "macros.asm"
+nop
+store
+`
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(unitTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	asm.SetSourceName("main.asm")
+	asm.fileReader = func(name string) ([]byte, error) {
+		assert.Equal(t, "macros.asm", name)
+		return []byte("MACRO store\n  sta missing\nENDM\n"), nil
+	}
+
+	err := asm.Process(t.Context(), strings.NewReader(code))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(),
+		"macros.asm:2:3 (included from main.asm:2:1, in macro 'store' used at main.asm:4:1)")
+
+	diagnostics := Diagnostics(err)
+	assert.Len(t, diagnostics, 1)
+	assert.Equal(t, "symbol not found in scope: 'missing'", diagnostics[0].Message)
+	pos := diagnostics[0].Position
+	assert.Equal(t, "macros.asm", pos.File)
+	assert.Equal(t, 2, pos.Line)
+
+	stack := pos.ExpansionStack()
+	assert.Len(t, stack, 2)
+	assert.Equal(t, token.IncludeExpansion, stack[0].Kind)
+	assert.Equal(t, "macros.asm", stack[0].Name)
+	assert.Equal(t, token.MacroExpansion, stack[1].Kind)
+	assert.Equal(t, "store", stack[1].Name)
+	assert.Equal(t, token.Position{File: "main.asm", Line: 4, Column: 1}, stack[1].Position)
+}
+
+func TestAssemblerErrorIncludeChain(t *testing.T) {
+	code := `.segment "HEADER"
+.include "defs.asm"
+`
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(unitTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	asm.SetSourceName("main.asm")
+	asm.fileReader = func(name string) ([]byte, error) {
+		switch name {
+		case "defs.asm":
+			return []byte("nop\n.include \"more.asm\"\n"), nil
+		case "more.asm":
+			return []byte("\n.unknown\n"), nil
+		default:
+			return nil, fmt.Errorf("unexpected include %q", name)
+		}
+	}
+
+	err := asm.Process(t.Context(), strings.NewReader(code))
+	assert.Error(t, err)
+
+	diagnostics := Diagnostics(err)
+	assert.Len(t, diagnostics, 1)
+	assert.Equal(t, CodeSyntax, diagnostics[0].Code)
+	pos := diagnostics[0].Position
+	assert.Equal(t, "more.asm:2:1 (included from defs.asm:2:1, included from main.asm:2:1)", pos.Trace())
+}
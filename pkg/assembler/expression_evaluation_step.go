@@ -41,6 +41,8 @@ func evaluateExpressionsStep[T any](_ context.Context, asm *Assembler[T]) error
 		},
 	}
 
+	var errs []error
+
 	for segNr, seg := range asm.segmentsOrder {
 		nodes := make([]ast.Node, 0, len(seg.nodes))
 
@@ -49,7 +51,8 @@ func evaluateExpressionsStep[T any](_ context.Context, asm *Assembler[T]) error
 			node := seg.nodes[nodeNr]
 			removeNode, err := evaluateNode[T](&expEval, seg, nodeNr, node)
 			if err != nil {
-				return fmt.Errorf("evaluating node %d in segment %d: %w", nodeNr, segNr, err)
+				errs = append(errs, nodeError(node, fmt.Errorf("evaluating node %d in segment %d: %w", nodeNr, segNr, err)))
+				continue
 			}
 			if !removeNode {
 				nodes = append(nodes, node)
@@ -60,9 +63,9 @@ func evaluateExpressionsStep[T any](_ context.Context, asm *Assembler[T]) error
 	}
 
 	if expEval.currentContext.parent != nil {
-		return errMissingEndif
+		errs = append(errs, errMissingEndif)
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 // evaluateNode evaluates a node and returns whether the node should be removed.
@@ -91,6 +94,11 @@ func evaluateNode[T any](expEval *expressionEvaluation[T], seg *segment, current
 		if expEval.currentContext.processNodes {
 			return true, errors.New(n.Message)
 		}
+	case scopeChange:
+		// scope changes are kept in skipped conditional blocks as well, the later
+		// steps track the scope of the remaining nodes by them
+		expEval.currentScope = n.scope
+		return false, nil
 	}
 
 	// skip processing nodes in case the if context condition is not met
@@ -228,7 +236,12 @@ func parseSymbolExpression[T any](expEval *expressionEvaluation[T], sym *symbol)
 		_, err := exp.Evaluate(expEval.currentScope, 1)
 		if errors.Is(err, scope.ErrForwardReference) {
 			// Aliases may depend on labels assigned in a later pass; retaining the
-			// unevaluated expression lets scope lookup resolve them afterward.
+			// unevaluated expression lets scope lookup resolve them afterward. The
+			// label addresses can change between the address assigning passes, so
+			// the value is not cached.
+			if e, ok := exp.(*expression.Expression); ok {
+				e.SetEvaluateOnce(false)
+			}
 			return nil
 		}
 		if err != nil {
@@ -267,26 +280,43 @@ func parseConfigExpression[T any](expEval *expressionEvaluation[T], cfg ast.Conf
 }
 
 func parseIfCondition[T any](expEval *expressionEvaluation[T], cond ast.If) error {
-	if cond.Condition.IsEvaluatedAtAddressAssign() {
-		return errExpressionCantReferenceProgramCounter
+	conditionMet, err := evaluateCondition(expEval, cond.Condition)
+
+	// a condition that can not be evaluated still opens a context that skips all
+	// branches, to match the following else and endif directives
+	ctx := &conditionalContext{
+		processNodes: conditionMet,
+		failed:       err != nil,
+		parent:       expEval.currentContext,
 	}
+	expEval.currentContext = ctx
+	return err
+}
 
-	value, err := cond.Condition.Evaluate(expEval.currentScope, expEval.arch.AddressWidth())
-	if err != nil {
-		return fmt.Errorf("evaluating if condition at program counter: %w", err)
+// evaluateCondition evaluates the condition of an if or elseif directive.
+func evaluateCondition[T any](expEval *expressionEvaluation[T], condition *expression.Expression) (bool, error) {
+	if condition.IsEvaluatedAtAddressAssign() {
+		return false, errExpressionCantReferenceProgramCounter
 	}
 
-	conditionMet, ok := value.(bool)
-	if !ok {
-		return fmt.Errorf("unsupported expression value type %T", value)
+	value, err := condition.Evaluate(expEval.currentScope, expEval.arch.AddressWidth())
+	if err != nil {
+		return false, fmt.Errorf("evaluating if condition at program counter: %w", err)
 	}
+	return conditionValue(value)
+}
 
-	ctx := &conditionalContext{
-		processNodes: conditionMet,
-		parent:       expEval.currentContext,
+// conditionValue returns whether the evaluated condition is met, a number is true
+// if it is not zero.
+func conditionValue(value any) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case int64:
+		return v != 0, nil
+	default:
+		return false, fmt.Errorf("unsupported expression value type %T", value)
 	}
-	expEval.currentContext = ctx
-	return nil
 }
 
 func parseIfdefCondition[T any](expEval *expressionEvaluation[T], cond ast.Ifdef) {
@@ -314,22 +344,14 @@ func parseElseIfCondition[T any](expEval *expressionEvaluation[T], cond ast.Else
 	if expEval.currentContext.parent == nil {
 		return errConditionOutsideIfContext
 	}
-	if cond.Condition.IsEvaluatedAtAddressAssign() {
-		return errExpressionCantReferenceProgramCounter
-	}
-
-	value, err := cond.Condition.Evaluate(expEval.currentScope, expEval.arch.AddressWidth())
-	if err != nil {
-		return fmt.Errorf("evaluating if condition at program counter: %w", err)
-	}
-
-	conditionMet, ok := value.(bool)
-	if !ok {
-		return fmt.Errorf("unsupported expression value type %T", value)
+	if expEval.currentContext.failed {
+		return nil
 	}
 
+	conditionMet, err := evaluateCondition(expEval, cond.Condition)
 	expEval.currentContext.processNodes = conditionMet
-	return nil
+	expEval.currentContext.failed = err != nil
+	return err
 }
 
 func parseRept[T any](expEval *expressionEvaluation[T], rept ast.Rept, seg *segment, currentNodeIndex int) error {
@@ -2,8 +2,10 @@ package assembler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/retroenv/retroasm/pkg/arch"
 	"github.com/retroenv/retroasm/pkg/scope"
 )
 
@@ -11,32 +13,26 @@ import (
 // references to their value or assigned addresses.
 func generateOpcodesStep[T any](_ context.Context, asm *Assembler[T]) error {
 	currentScope := asm.fileScope
-	arch := asm.cfg.Arch
+	var errs []error
+
+	if asm.object != nil {
+		asm.object.setImportPlaceholders()
+	}
 
 	for _, seg := range asm.segmentsOrder {
 		for _, node := range seg.nodes {
 			switch n := node.(type) {
 			case *data:
-				if err := generateDeferredDataBytes(currentScope, n); err != nil {
-					return fmt.Errorf("generating deferred data at $%x: %w", n.address, err)
+				if asm.object != nil {
+					asm.object.addDataRelocations(seg, currentScope, n)
 				}
-				if err := generateReferenceDataBytes(currentScope, n); err != nil {
-					return fmt.Errorf("generating data node opcode: %w", err)
-				}
-				if n.fill {
-					if err := generateDataFillBytes(n); err != nil {
-						return fmt.Errorf("generating data node opcode: %w", err)
-					}
+				if err := generateDataBytes(currentScope, n); err != nil {
+					errs = append(errs, nodeError(n, err))
 				}
 
 			case *instruction:
-				assigner := &addressAssign[T]{
-					arch:           arch,
-					currentScope:   currentScope,
-					programCounter: n.Address(),
-				}
-				if err := arch.GenerateInstructionOpcode(assigner, n); err != nil {
-					return fmt.Errorf("generating instruction '%s' at $%x opcode: %w", n.Name(), n.Address(), err)
+				if err := generateInstructionOpcode(asm, seg, currentScope, n); err != nil {
+					errs = append(errs, nodeError(n, err))
 				}
 
 			case scopeChange:
@@ -44,6 +40,77 @@ func generateOpcodesStep[T any](_ context.Context, asm *Assembler[T]) error {
 			}
 		}
 	}
+	return errors.Join(errs...)
+}
+
+// generateInstructionOpcode generates the opcodes of an instruction. In object mode the
+// argument of the instruction is recorded as possible relocation.
+func generateInstructionOpcode[T any](asm *Assembler[T], seg *segment, currentScope *scope.Scope,
+	ins *instruction) error {
+
+	arch := asm.cfg.Arch
+	if asm.object != nil {
+		if err := asm.object.checkRelativeImport(arch, currentScope, ins); err != nil {
+			return err
+		}
+	}
+
+	assigner := &addressAssign[T]{
+		arch:           arch,
+		currentScope:   currentScope,
+		programCounter: ins.Address(),
+	}
+	if ins.longBranch {
+		if err := generateLongBranchOpcode(asm, assigner, ins); err != nil {
+			return err
+		}
+	} else if err := arch.GenerateInstructionOpcode(assigner, ins); err != nil {
+		return fmt.Errorf("generating instruction '%s' at $%x opcode: %w", ins.Name(), ins.Address(), err)
+	}
+
+	if asm.object != nil {
+		return asm.object.addInstructionRelocation(arch, seg, currentScope, ins)
+	}
+	return nil
+}
+
+// generateLongBranchOpcode generates the opcodes of a branch whose target is out of range
+// and notes the expansion.
+func generateLongBranchOpcode[T any](asm *Assembler[T], assigner *addressAssign[T], ins *instruction) error {
+	expander, ok := asm.cfg.Arch.(arch.BranchExpander)
+	if !ok {
+		return errors.New("architecture does not support long branches")
+	}
+	if err := expander.GenerateExpandedBranchOpcode(assigner, ins); err != nil {
+		return fmt.Errorf("generating long branch '%s' at $%x opcode: %w", ins.Name(), ins.Address(), err)
+	}
+
+	target, err := assigner.ArgumentValue(ins.argument)
+	if err != nil {
+		return fmt.Errorf("getting branch target: %w", err)
+	}
+	asm.notes = append(asm.notes, Diagnostic{
+		Message: fmt.Sprintf("branch '%s' to $%04X is out of range and was expanded with a jmp",
+			ins.Name(), target),
+		Position: ins.position,
+		Code:     CodeLongBranch,
+	})
+	return nil
+}
+
+// generateDataBytes generates the bytes of a data node.
+func generateDataBytes(currentScope *scope.Scope, dat *data) error {
+	if err := generateDeferredDataBytes(currentScope, dat); err != nil {
+		return fmt.Errorf("generating deferred data at $%x: %w", dat.address, err)
+	}
+	if err := generateReferenceDataBytes(currentScope, dat); err != nil {
+		return fmt.Errorf("generating data node opcode: %w", err)
+	}
+	if dat.fill {
+		if err := generateDataFillBytes(dat); err != nil {
+			return fmt.Errorf("generating data node opcode: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -150,6 +217,9 @@ func generateReferenceDataBytes(currentScope *scope.Scope, d *data) error {
 			b = []byte{byte(address >> 8)}
 		case bankAddressByte:
 			b = []byte{byte(address >> 16)}
+			if bank, ok := sym.Bank(); ok {
+				b = []byte{byte(bank)}
+			}
 		default:
 			return fmt.Errorf("unsupported reference type %d", ref.typ)
 		}
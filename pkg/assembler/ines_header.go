@@ -0,0 +1,128 @@
+package assembler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/retroenv/retroasm/pkg/parser/ast"
+)
+
+const (
+	inesHeaderSize = 16
+	inesPrgUnit    = 16384 // PRG ROM size unit in bytes
+	inesChrUnit    = 8192  // CHR ROM size unit in bytes
+
+	inesMaxMapper     = 0xff
+	nes2MaxMapper     = 0xfff
+	nes2MaxSubMapper  = 0x0f
+	inesMaxSizeUnits  = 0xff
+	nes2MaxSizeUnits  = 0xeff // larger values switch to the exponent-multiplier notation
+	inesMaxMirrorBits = 0x0f
+)
+
+var errInesSizeMismatch = errors.New("iNES header ROM size does not match the emitted data size")
+
+// inesHeader collects the iNES configuration directives of the assembled program
+// that are used to generate the 16 byte iNES or NES 2.0 file header.
+type inesHeader struct {
+	used bool // whether any iNES directive was found
+
+	prgSize      uint64 // PRG ROM size in bytes
+	chrSize      uint64 // CHR ROM size in bytes
+	mapper       uint64
+	subMapper    uint64
+	subMapperSet bool
+	mirror       uint64 // lower nibble of flags 6, mirroring and four-screen bits
+	battery      bool
+}
+
+// setConfiguration applies a configuration node to the header and returns whether
+// the node is an iNES header setting, other configuration nodes are ignored.
+func (h *inesHeader) setConfiguration(cfg ast.Configuration) bool {
+	switch cfg.Item {
+	case ast.ConfigPrg:
+		h.prgSize = cfg.Value
+	case ast.ConfigChr:
+		h.chrSize = cfg.Value
+	case ast.ConfigMapper:
+		h.mapper = cfg.Value
+	case ast.ConfigSubMapper:
+		h.subMapper = cfg.Value
+		h.subMapperSet = true
+	case ast.ConfigMirror:
+		h.mirror = cfg.Value
+	case ast.ConfigBattery:
+		h.battery = cfg.Value != 0
+	default:
+		return false
+	}
+	h.used = true
+	return true
+}
+
+// isNES2 returns whether the header needs the NES 2.0 format to represent the configuration.
+func (h *inesHeader) isNES2() bool {
+	return h.subMapperSet || h.mapper > inesMaxMapper ||
+		h.prgSize/inesPrgUnit > inesMaxSizeUnits || h.chrSize/inesChrUnit > inesMaxSizeUnits
+}
+
+// validate checks that the configured ROM sizes match the number of bytes that
+// follow the header in the output file.
+func (h *inesHeader) validate(dataSize uint64) error {
+	if h.prgSize%inesPrgUnit != 0 {
+		return fmt.Errorf("PRG ROM size %d is not a multiple of %d bytes", h.prgSize, inesPrgUnit)
+	}
+	if h.chrSize%inesChrUnit != 0 {
+		return fmt.Errorf("CHR ROM size %d is not a multiple of %d bytes", h.chrSize, inesChrUnit)
+	}
+
+	if expected := h.prgSize + h.chrSize; expected != dataSize {
+		return fmt.Errorf("%w: %d bytes PRG and %d bytes CHR declared, %d bytes emitted",
+			errInesSizeMismatch, h.prgSize, h.chrSize, dataSize)
+	}
+	return nil
+}
+
+// bytes returns the encoded 16 byte header.
+func (h *inesHeader) bytes() ([]byte, error) {
+	nes2 := h.isNES2()
+	maxMapper, maxUnits := uint64(inesMaxMapper), uint64(inesMaxSizeUnits)
+	if nes2 {
+		maxMapper, maxUnits = nes2MaxMapper, nes2MaxSizeUnits
+	}
+
+	prgUnits := h.prgSize / inesPrgUnit
+	chrUnits := h.chrSize / inesChrUnit
+
+	switch {
+	case h.mapper > maxMapper:
+		return nil, fmt.Errorf("mapper %d exceeds maximum %d", h.mapper, maxMapper)
+	case h.subMapper > nes2MaxSubMapper:
+		return nil, fmt.Errorf("submapper %d exceeds maximum %d", h.subMapper, nes2MaxSubMapper)
+	case h.mirror > inesMaxMirrorBits:
+		return nil, fmt.Errorf("mirroring value %d exceeds maximum %d", h.mirror, inesMaxMirrorBits)
+	case prgUnits > maxUnits:
+		return nil, fmt.Errorf("PRG ROM size of %d units exceeds maximum %d", prgUnits, maxUnits)
+	case chrUnits > maxUnits:
+		return nil, fmt.Errorf("CHR ROM size of %d units exceeds maximum %d", chrUnits, maxUnits)
+	}
+
+	header := make([]byte, inesHeaderSize)
+	copy(header, "NES\x1a")
+	header[4] = byte(prgUnits)
+	header[5] = byte(chrUnits)
+
+	header[6] = byte(h.mirror) | byte(h.mapper&0x0f)<<4
+	if h.battery {
+		header[6] |= 0x02
+	}
+	header[7] = byte(h.mapper & 0xf0)
+
+	if nes2 {
+		header[7] |= 0x08 // NES 2.0 identifier
+		header[8] = byte(h.mapper>>8) | byte(h.subMapper)<<4
+		header[9] = byte(prgUnits>>8) | byte(chrUnits>>8)<<4
+	}
+
+	return header, nil
+}
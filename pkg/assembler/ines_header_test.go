@@ -0,0 +1,116 @@
+package assembler
+
+import (
+	"testing"
+
+	"github.com/retroenv/retroasm/pkg/parser/ast"
+	"github.com/retroenv/retrogolib/assert"
+)
+
+func TestInesHeaderBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   inesHeader
+		expected []byte
+	}{
+		{
+			name: "iNES",
+			header: inesHeader{
+				prgSize: 2 * inesPrgUnit,
+				chrSize: inesChrUnit,
+				mapper:  0x42,
+				mirror:  1,
+				battery: true,
+			},
+			expected: []byte{'N', 'E', 'S', 0x1a, 2, 1, 0x23, 0x40, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name: "NES 2.0 due to submapper",
+			header: inesHeader{
+				prgSize:      inesPrgUnit,
+				mapper:       4,
+				subMapper:    3,
+				subMapperSet: true,
+			},
+			expected: []byte{'N', 'E', 'S', 0x1a, 1, 0, 0x40, 0x08, 0x30, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name: "NES 2.0 due to mapper",
+			header: inesHeader{
+				prgSize: inesPrgUnit,
+				chrSize: inesChrUnit,
+				mapper:  0x123,
+			},
+			expected: []byte{'N', 'E', 'S', 0x1a, 1, 1, 0x30, 0x28, 0x01, 0, 0, 0, 0, 0, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.header.bytes()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, b)
+		})
+	}
+}
+
+func TestInesHeaderErrors(t *testing.T) {
+	h := inesHeader{mapper: 0x1000}
+	_, err := h.bytes()
+	assert.Error(t, err)
+
+	h = inesHeader{subMapper: 16, subMapperSet: true}
+	_, err = h.bytes()
+	assert.Error(t, err)
+
+	h = inesHeader{prgSize: 100}
+	assert.Error(t, h.validate(100))
+
+	h = inesHeader{prgSize: inesPrgUnit, chrSize: inesChrUnit}
+	assert.ErrorIs(t, h.validate(inesPrgUnit), errInesSizeMismatch)
+	assert.NoError(t, h.validate(inesPrgUnit+inesChrUnit))
+}
+
+func TestInesHeaderConfiguration(t *testing.T) {
+	var h inesHeader
+	fill := ast.NewConfiguration(ast.ConfigFillValue)
+	assert.False(t, h.setConfiguration(fill))
+	assert.False(t, h.used)
+
+	mapper := ast.NewConfiguration(ast.ConfigMapper)
+	mapper.Value = 1
+	assert.True(t, h.setConfiguration(mapper))
+	assert.True(t, h.used)
+	assert.Equal(t, 1, h.mapper)
+}
+
+var inesHeaderTestConfig = `
+MEMORY {
+    PRG:    start = $0000,  size = $4000,     type = ro;
+}
+
+SEGMENTS {
+    CODE:     load = PRG, type = ro;
+}
+`
+
+var inesHeaderTestCode = `
+.inesprg 1
+.ineschr 0
+.inesmap 1
+.inesmir 1
+
+.segment "CODE"
+.dsb $4000, $ea
+`
+
+func TestAssemblerInesHeader(t *testing.T) {
+	b, err := runAsm6Test(t, inesHeaderTestConfig, inesHeaderTestCode)
+	assert.NoError(t, err)
+	assert.Len(t, b, inesHeaderSize+inesPrgUnit)
+	assert.Equal(t, []byte{'N', 'E', 'S', 0x1a, 1, 0, 0x11, 0x00, 0, 0, 0, 0, 0, 0, 0, 0}, b[:inesHeaderSize])
+	assert.Equal(t, 0xea, b[inesHeaderSize])
+
+	_, err = runAsm6Test(t, inesHeaderTestConfig, ".inesprg 2\n.segment \"CODE\"\nnop\n")
+	assert.ErrorIs(t, err, errInesSizeMismatch)
+}
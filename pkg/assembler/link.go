@@ -0,0 +1,390 @@
+package assembler
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/retroenv/retroasm/pkg/expression"
+	"github.com/retroenv/retroasm/pkg/lexer/token"
+	"github.com/retroenv/retroasm/pkg/parser/ast"
+	"github.com/retroenv/retroasm/pkg/scope"
+	"github.com/retroenv/retrogolib/set"
+)
+
+var (
+	errDuplicateExport  = errors.New("symbol is exported by multiple files")
+	errUnresolvedImport = errors.New("imported symbol is not exported by any file")
+)
+
+// linkModule contains the scopes and symbols of a linked object file.
+type linkModule struct {
+	object *Object
+	scopes []*scope.Scope // maps object scope index to the created scope
+
+	// symbols with a segment, sorted by offset per segment name
+	segmentSymbols map[string][]linkSymbol
+}
+
+// linkSymbol is a symbol of an object file that is placed at an offset of a fragment.
+type linkSymbol struct {
+	symbol *scope.Symbol
+	offset uint64
+}
+
+// exportedExpression is the expression of an exported symbol. It evaluates the
+// expression in the scope of the file that exports the symbol.
+type exportedExpression struct {
+	expression *expression.Expression
+	scope      *scope.Scope
+}
+
+// Link places the segment fragments of the object files in the segments of the
+// configuration, resolves the imported symbols by the exported symbols of all objects
+// and writes the linked program to the output writer.
+func (asm *Assembler[T]) Link(ctx context.Context, objects []*Object) error {
+	p := &parseAST[T]{
+		cfg:          asm.cfg,
+		fileScope:    asm.fileScope,
+		currentScope: asm.fileScope,
+		segments:     map[string]*segment{},
+	}
+	if err := addSegmentDefineSymbols(asm.fileScope, asm.cfg.SegmentsOrdered); err != nil {
+		return err
+	}
+
+	var errs []error
+	modules := make([]*linkModule, 0, len(objects))
+
+	for _, obj := range objects {
+		mod, err := addLinkModule(p, asm.fileScope, obj)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("object file '%s': %w", obj.Source, err))
+			continue
+		}
+		modules = append(modules, mod)
+	}
+
+	for _, mod := range modules {
+		if err := addFragmentNodes(p, asm.fileScope, mod); err != nil {
+			errs = append(errs, fmt.Errorf("object file '%s': %w", mod.object.Source, err))
+		}
+	}
+
+	if err := addCondesTables(p, asm.fileScope); err != nil {
+		errs = append(errs, err)
+	}
+	if err := addConfigSymbols(asm.fileScope, asm.cfg.Symbols); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, mod := range modules {
+		for _, name := range mod.object.Imports {
+			if _, err := mod.scopes[0].GetSymbol(name); err != nil {
+				errs = append(errs, fmt.Errorf("object file '%s': %w: '%s'", mod.object.Source, errUnresolvedImport, name))
+			}
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	asm.segments = p.segments
+	asm.segmentsOrder = p.segmentsOrder
+	return asm.runSteps(ctx)
+}
+
+// addLinkModule creates the scopes and symbols of the object file and adds the exported
+// symbols to the global scope.
+func addLinkModule[T any](p *parseAST[T], global *scope.Scope, obj *Object) (*linkModule, error) {
+	mod := &linkModule{
+		object:         obj,
+		segmentSymbols: map[string][]linkSymbol{},
+	}
+
+	for i, objScope := range obj.Scopes {
+		parent := global
+		if i > 0 {
+			if objScope.Parent < 0 || objScope.Parent >= i {
+				return nil, fmt.Errorf("%w: scope %d has invalid parent %d", errInvalidObject, i, objScope.Parent)
+			}
+			parent = mod.scopes[objScope.Parent]
+		}
+		sc := scope.New(parent)
+		sc.SetName(objScope.Name)
+		mod.scopes = append(mod.scopes, sc)
+	}
+	if len(mod.scopes) == 0 {
+		return nil, fmt.Errorf("%w: missing file scope", errInvalidObject)
+	}
+
+	for _, objSym := range obj.Symbols {
+		if err := addLinkSymbol(mod, objSym); err != nil {
+			return nil, fmt.Errorf("symbol '%s': %w", objSym.Name, err)
+		}
+	}
+	for name, symbols := range mod.segmentSymbols {
+		slices.SortStableFunc(symbols, func(a, b linkSymbol) int {
+			return cmp.Compare(a.offset, b.offset)
+		})
+		mod.segmentSymbols[name] = symbols
+	}
+
+	if err := addLinkExports(global, mod); err != nil {
+		return nil, err
+	}
+
+	for _, condes := range obj.Condes {
+		if condes.Scope < 0 || condes.Scope >= len(mod.scopes) {
+			return nil, fmt.Errorf("%w: condes '%s' has invalid scope %d", errInvalidObject, condes.Name, condes.Scope)
+		}
+		p.condes = append(p.condes, condesEntry{
+			name:     condes.Name,
+			typ:      condes.Type,
+			priority: condes.Priority,
+			scope:    mod.scopes[condes.Scope],
+		})
+	}
+	return mod, nil
+}
+
+// addLinkSymbol creates a symbol of an object file in its scope.
+func addLinkSymbol(mod *linkModule, objSym ObjectSymbol) error {
+	if objSym.Scope < 0 || objSym.Scope >= len(mod.scopes) {
+		return fmt.Errorf("%w: invalid scope %d", errInvalidObject, objSym.Scope)
+	}
+	sc := mod.scopes[objSym.Scope]
+
+	switch {
+	case objSym.Segment != "":
+		sym, err := scope.NewSymbol(sc, objSym.Name, scope.LabelType)
+		if err != nil {
+			return fmt.Errorf("creating symbol: %w", err)
+		}
+		mod.segmentSymbols[objSym.Segment] = append(mod.segmentSymbols[objSym.Segment], linkSymbol{
+			symbol: sym,
+			offset: objSym.Value,
+		})
+
+	case len(objSym.Expression) > 0:
+		sym, err := scope.NewSymbol(sc, objSym.Name, scope.EquType)
+		if err != nil {
+			return fmt.Errorf("creating symbol: %w", err)
+		}
+		sym.SetExpression(expression.New(expressionTokens(objSym.Expression)...))
+
+	default:
+		if _, err := newConstantSymbol(sc, objSym.Name, objSym.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addLinkExports adds the exported symbols of the object file to the global scope.
+func addLinkExports(global *scope.Scope, mod *linkModule) error {
+	moduleScope := mod.scopes[0]
+
+	for _, name := range mod.object.Exports {
+		sym, err := moduleScope.GetSymbol(name)
+		if err != nil {
+			return fmt.Errorf("%w: exported symbol '%s' is not defined", errInvalidObject, name)
+		}
+		if err := exportSymbol(global, moduleScope, sym); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportSymbol adds an exported symbol of a file to the global scope. Expression symbols
+// are wrapped to resolve their symbols in the scope of the exporting file.
+func exportSymbol(global, sc *scope.Scope, sym *scope.Symbol) error {
+	if existing, err := global.GetSymbol(sym.Name()); err == nil && existing == sym {
+		return nil // symbol of the global scope like a config symbol
+	}
+
+	exp, ok := sym.Expression().(*expression.Expression)
+	if !ok || exp.IsEvaluatedOnce() {
+		// labels and constants do not reference other symbols and can be shared
+		if err := global.AddSymbol(sym); err != nil {
+			return fmt.Errorf("%w: '%s'", errDuplicateExport, sym.Name())
+		}
+		return nil
+	}
+
+	exported, err := scope.NewSymbol(global, sym.Name(), scope.EquType)
+	if err != nil {
+		return fmt.Errorf("%w: '%s'", errDuplicateExport, sym.Name())
+	}
+	exported.SetExpression(&exportedExpression{expression: exp, scope: sc})
+	return nil
+}
+
+// addFragmentNodes adds the nodes of the segment fragments of the object file to the
+// segments. Relocated values are added as data nodes with the relocation expression.
+func addFragmentNodes[T any](p *parseAST[T], global *scope.Scope, mod *linkModule) error {
+	usedSegments := set.New[string]()
+
+	for _, objSeg := range mod.object.Segments {
+		if usedSegments.Contains(objSeg.Name) {
+			return fmt.Errorf("%w: segment '%s' found twice", errInvalidObject, objSeg.Name)
+		}
+		usedSegments.Add(objSeg.Name)
+
+		if err := parseSegment(p, ast.NewSegment(objSeg.Name)); err != nil {
+			return fmt.Errorf("parsing segment: %w", err)
+		}
+		seg := p.currentSegment
+
+		seg.addNode(scopeChange{scope: mod.scopes[0]})
+		if err := addFragmentSegmentNodes(seg, mod, objSeg); err != nil {
+			return fmt.Errorf("segment '%s': %w", objSeg.Name, err)
+		}
+		seg.addNode(scopeChange{scope: global})
+	}
+
+	for name := range mod.segmentSymbols {
+		if !usedSegments.Contains(name) {
+			return fmt.Errorf("%w: symbol references missing segment '%s'", errInvalidObject, name)
+		}
+	}
+	return nil
+}
+
+// addFragmentSegmentNodes adds the data, symbol and relocation nodes of a segment fragment.
+func addFragmentSegmentNodes(seg *segment, mod *linkModule, objSeg ObjectSegment) error {
+	uninitialized := len(objSeg.Data) == 0
+	if !uninitialized && uint64(len(objSeg.Data)) != objSeg.Size {
+		return fmt.Errorf("%w: data size %d does not match segment size %d", errInvalidObject, len(objSeg.Data), objSeg.Size)
+	}
+
+	relocations := slices.Clone(objSeg.Relocations)
+	slices.SortStableFunc(relocations, func(a, b Relocation) int {
+		return cmp.Compare(a.Offset, b.Offset)
+	})
+	symbols := mod.segmentSymbols[objSeg.Name]
+
+	var offset uint64
+	for offset < objSeg.Size || len(symbols) > 0 {
+		for len(symbols) > 0 && symbols[0].offset == offset {
+			seg.addNode(&symbol{Symbol: symbols[0].symbol})
+			symbols = symbols[1:]
+		}
+		if offset >= objSeg.Size {
+			if len(symbols) > 0 {
+				return fmt.Errorf("%w: symbol '%s' offset %d is outside of the segment",
+					errInvalidObject, symbols[0].symbol.Name(), symbols[0].offset)
+			}
+			break
+		}
+
+		if len(relocations) > 0 && relocations[0].Offset == offset {
+			reloc := relocations[0]
+			relocations = relocations[1:]
+			if err := addRelocationNode(seg, mod, reloc, objSeg.Size, uninitialized); err != nil {
+				return err
+			}
+			offset += uint64(reloc.Size)
+			continue
+		}
+
+		// the next boundary is the next symbol, relocation or the end of the segment
+		end := objSeg.Size
+		if len(symbols) > 0 {
+			end = min(end, symbols[0].offset)
+		}
+		if len(relocations) > 0 {
+			if relocations[0].Offset < offset {
+				return fmt.Errorf("%w: relocation at offset %d overlaps", errInvalidObject, relocations[0].Offset)
+			}
+			end = min(end, relocations[0].Offset)
+		}
+
+		if uninitialized {
+			seg.addNode(&variable{v: ast.NewVariable("", int(end-offset))})
+		} else {
+			seg.addNode(&data{
+				width:  1,
+				size:   expression.New(),
+				values: []any{objSeg.Data[offset:end]},
+			})
+		}
+		offset = end
+	}
+
+	if len(relocations) > 0 {
+		return fmt.Errorf("%w: relocation offset %d is outside of the segment", errInvalidObject, relocations[0].Offset)
+	}
+	return nil
+}
+
+// addRelocationNode adds a data node that stores the value of the relocation expression.
+func addRelocationNode(seg *segment, mod *linkModule, reloc Relocation, segmentSize uint64, uninitialized bool) error {
+	switch {
+	case uninitialized:
+		return fmt.Errorf("%w: relocation at offset %d in uninitialized segment", errInvalidObject, reloc.Offset)
+	case reloc.Size < 1 || reloc.Offset+uint64(reloc.Size) > segmentSize:
+		return fmt.Errorf("%w: relocation at offset %d has invalid size %d", errInvalidObject, reloc.Offset, reloc.Size)
+	case reloc.Scope < 0 || reloc.Scope >= len(mod.scopes):
+		return fmt.Errorf("%w: relocation at offset %d has invalid scope %d", errInvalidObject, reloc.Offset, reloc.Scope)
+	}
+
+	dat := &data{
+		width:      reloc.Size,
+		size:       expression.New(),
+		expression: expression.New(expressionTokens(reloc.Expression)...),
+	}
+	if reloc.Scope == 0 {
+		seg.addNode(dat)
+		return nil
+	}
+	seg.addNode(scopeChange{scope: mod.scopes[reloc.Scope]})
+	seg.addNode(dat)
+	seg.addNode(scopeChange{scope: mod.scopes[0]})
+	return nil
+}
+
+// CopyExpression returns a copy of the exported expression.
+func (e *exportedExpression) CopyExpression() any {
+	return &exportedExpression{
+		expression: e.expression.Copy(),
+		scope:      e.scope,
+	}
+}
+
+// Evaluate evaluates the expression in the scope of the exporting file.
+func (e *exportedExpression) Evaluate(_ *scope.Scope, dataWidth int) (any, error) {
+	value, err := e.expression.Evaluate(e.scope, dataWidth)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating exported expression: %w", err)
+	}
+	return value, nil
+}
+
+// EvaluateAtProgramCounter evaluates the expression in the scope of the exporting file.
+func (e *exportedExpression) EvaluateAtProgramCounter(_ *scope.Scope, dataWidth int, programCounter uint64) (any, error) {
+	value, err := e.expression.EvaluateAtProgramCounter(e.scope, dataWidth, programCounter)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating exported expression: %w", err)
+	}
+	return value, nil
+}
+
+// IsEvaluatedAtAddressAssign returns whether the expression references the program counter.
+func (e *exportedExpression) IsEvaluatedAtAddressAssign() bool {
+	return e.expression.IsEvaluatedAtAddressAssign()
+}
+
+// IsEvaluatedOnce returns whether the expression is only evaluated once.
+func (e *exportedExpression) IsEvaluatedOnce() bool {
+	return e.expression.IsEvaluatedOnce()
+}
+
+// Tokens returns the tokens of the expression.
+func (e *exportedExpression) Tokens() []token.Token {
+	return e.expression.Tokens()
+}
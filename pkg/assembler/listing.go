@@ -0,0 +1,320 @@
+package assembler
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/retroenv/retroasm/pkg/arch"
+	"github.com/retroenv/retroasm/pkg/lexer/token"
+)
+
+const (
+	listingBytesPerRow = 4  // emitted bytes shown per listing row
+	listingMaxBytes    = 16 // emitted bytes shown per source line, longer data is truncated
+)
+
+var errListingSourceMissing = errors.New("listing needs the source that was read by Process")
+
+// ListingOptions configures the content of a listing.
+type ListingOptions struct {
+	Cycles bool // show the cycle counts of instructions, if supported by the architecture
+}
+
+// listingKey identifies a source line in a specific include or macro expansion context.
+type listingKey struct {
+	file      string
+	line      int
+	expansion *token.Expansion
+}
+
+// listingExpansion describes the expanded source lines of an include or macro usage.
+type listingExpansion struct {
+	expansion *token.Expansion
+	context   *token.Expansion // expansion of the expanded lines, includes the frames of a macro definition
+	file      string
+	firstLine int
+	lastLine  int // for includes the last line of the file
+}
+
+// listingEntry is a node that emits bytes or reserves space at a source line.
+type listingEntry struct {
+	address   uint64
+	data      []byte
+	cycles    int
+	pageCross bool // an additional cycle is needed when a page boundary is crossed
+}
+
+// sourceRecorder records the source files and expansions that were read during an
+// assembler run, to be able to write a listing of the program afterward.
+// A nil recorder ignores all records.
+type sourceRecorder struct {
+	files      []string                          // names of all read files in the order of reading
+	sizes      map[string]int                    // maps file name to its size in bytes
+	lines      map[string][]string               // maps file name to its lines
+	expansions map[listingKey][]listingExpansion // maps include or macro usage site to expansions
+}
+
+func newSourceRecorder() *sourceRecorder {
+	return &sourceRecorder{
+		sizes:      map[string]int{},
+		lines:      map[string][]string{},
+		expansions: map[listingKey][]listingExpansion{},
+	}
+}
+
+// addSource records the content of a source file.
+func (r *sourceRecorder) addSource(name string, data []byte) {
+	if r == nil {
+		return
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+
+	if _, ok := r.lines[name]; !ok {
+		r.files = append(r.files, name)
+	}
+	r.sizes[name] = len(data)
+	r.lines[name] = lines
+}
+
+// addInclude records the expansion of an included source file.
+func (r *sourceRecorder) addInclude(expansion *token.Expansion) {
+	if r == nil {
+		return
+	}
+
+	r.addExpansion(listingExpansion{
+		expansion: expansion,
+		context:   expansion,
+		file:      expansion.Name,
+		firstLine: 1,
+		lastLine:  len(r.lines[expansion.Name]),
+	})
+}
+
+// addMacro records the expansion of a macro usage, the expanded lines are determined
+// by the tokens of the macro definition.
+func (r *sourceRecorder) addMacro(expansion *token.Expansion, tokens []token.Token) {
+	if r == nil {
+		return
+	}
+
+	exp := listingExpansion{expansion: expansion}
+	for _, tok := range tokens {
+		pos := tok.Position
+		if tok.Type == token.EOL || !slices.Contains(pos.ExpansionStack(), expansion) {
+			continue // argument tokens are located at the macro usage
+		}
+		if exp.file == "" {
+			exp.file, exp.firstLine, exp.context = pos.File, pos.Line, pos.Expansion
+		}
+		exp.firstLine = min(exp.firstLine, pos.Line)
+		exp.lastLine = max(exp.lastLine, pos.Line)
+	}
+	if exp.lastLine > 0 {
+		r.addExpansion(exp)
+	}
+}
+
+func (r *sourceRecorder) addExpansion(exp listingExpansion) {
+	site := exp.expansion.Position
+	key := listingKey{file: site.File, line: site.Line, expansion: site.Expansion}
+	r.expansions[key] = append(r.expansions[key], exp)
+}
+
+// listingWriter writes the listing of an assembled program.
+type listingWriter[T any] struct {
+	arch    arch.Architecture[T]
+	options ListingOptions
+	sources *sourceRecorder
+	entries map[listingKey][]listingEntry
+	w       *bufio.Writer
+
+	addressDigits int
+}
+
+// WriteListing writes a listing of the assembled program that shows every source line
+// next to its assigned address and emitted bytes. Lines of included files and expanded
+// macros follow the line of the include directive or macro usage, multiple source files
+// are listed in the order of processing. Call this after Process or ProcessFiles.
+func (asm *Assembler[T]) WriteListing(writer io.Writer, options ListingOptions) error {
+	if len(asm.sourceNames) == 0 {
+		return errListingSourceMissing
+	}
+
+	lw := &listingWriter[T]{
+		arch:          asm.cfg.Arch,
+		options:       options,
+		sources:       asm.sources,
+		entries:       map[listingKey][]listingEntry{},
+		w:             bufio.NewWriter(writer),
+		addressDigits: max(4, asm.cfg.Arch.AddressWidth()/4),
+	}
+	for _, seg := range asm.segmentsOrder {
+		lw.addEntries(seg)
+	}
+
+	for _, name := range asm.sourceNames {
+		lw.writeLines(name, 1, len(asm.sources.lines[name]), nil, false)
+	}
+
+	if err := lw.w.Flush(); err != nil {
+		return fmt.Errorf("writing listing: %w", err)
+	}
+	return nil
+}
+
+// addEntries adds all nodes of the segment that emit bytes or reserve space.
+func (lw *listingWriter[T]) addEntries(seg *segment) {
+	for _, node := range seg.nodes {
+		var (
+			pos   token.Position
+			entry listingEntry
+		)
+
+		switch n := node.(type) {
+		case *data:
+			pos = n.position
+			entry.address = n.address
+			for _, val := range n.values {
+				if b, ok := val.([]byte); ok {
+					entry.data = append(entry.data, b...)
+				}
+			}
+
+		case *instruction:
+			pos = n.position
+			entry.address = n.address
+			entry.data = n.opcodes
+			if counter, ok := lw.arch.(arch.CycleCounter); ok && lw.options.Cycles {
+				entry.cycles, entry.pageCross = counter.InstructionCycles(n)
+			}
+
+		case *variable:
+			if n.v.UseOffsetCounter {
+				continue
+			}
+			pos = n.Position()
+			entry.address = n.address
+
+		default:
+			continue
+		}
+
+		key := listingKey{file: pos.File, line: pos.Line, expansion: pos.Expansion}
+		lw.entries[key] = append(lw.entries[key], entry)
+	}
+}
+
+// writeLines writes the given line range of a source file in the given expansion
+// context, followed by the expansions of every line. Lines of a macro expansion are
+// marked with a suffix.
+func (lw *listingWriter[T]) writeLines(file string, firstLine, lastLine int, expansion *token.Expansion,
+	macro bool) {
+
+	lines := lw.sources.lines[file]
+	lineSuffix := ""
+	if macro {
+		lineSuffix = "+"
+	}
+
+	for line := firstLine; line <= lastLine && line <= len(lines); line++ {
+		key := listingKey{file: file, line: line, expansion: expansion}
+		lw.writeLine(fmt.Sprintf("%d%s", line, lineSuffix), lw.entries[key], lines[line-1])
+
+		for _, exp := range lw.sources.expansions[key] {
+			if exp.expansion.Kind == token.IncludeExpansion {
+				lw.writeFileMarker(exp.file)
+			}
+			macro := exp.expansion.Kind == token.MacroExpansion
+			lw.writeLines(exp.file, exp.firstLine, exp.lastLine, exp.context, macro)
+			if exp.expansion.Kind == token.IncludeExpansion {
+				lw.writeFileMarker(file)
+			}
+		}
+	}
+}
+
+// writeFileMarker writes a row that marks the start of the listing of a source file.
+// Unnamed sources are not marked.
+func (lw *listingWriter[T]) writeFileMarker(file string) {
+	if file == "" {
+		return
+	}
+	_, _ = fmt.Fprintf(lw.w, "%7s ; file '%s'\n", "", file)
+}
+
+// writeLine writes the rows of a source line, long data continues in additional rows.
+func (lw *listingWriter[T]) writeLine(lineLabel string, entries []listingEntry, text string) {
+	var (
+		data      []byte
+		address   string
+		cycles    int
+		pageCross bool
+	)
+	if len(entries) > 0 {
+		address = fmt.Sprintf("%0*X", lw.addressDigits, entries[0].address)
+	}
+	for _, entry := range entries {
+		data = append(data, entry.data...)
+		cycles += entry.cycles
+		pageCross = pageCross || entry.pageCross
+	}
+
+	truncated := len(data) > listingMaxBytes
+	if truncated {
+		data = data[:listingMaxBytes]
+	}
+
+	rows := slices.Collect(slices.Chunk(data, listingBytesPerRow))
+	if len(rows) == 0 {
+		rows = [][]byte{nil}
+	}
+
+	for i, row := range rows {
+		hexBytes := make([]string, 0, len(row))
+		for _, b := range row {
+			hexBytes = append(hexBytes, fmt.Sprintf("%02X", b))
+		}
+		column := strings.Join(hexBytes, " ")
+		if truncated && i == len(rows)-1 {
+			column += " ..."
+		}
+
+		var s string
+		if i == 0 {
+			s = fmt.Sprintf("%6s  %-*s  %-15s", lineLabel, lw.addressDigits, address, column)
+			if lw.options.Cycles {
+				s += fmt.Sprintf("  %-3s", cycleColumn(cycles, pageCross))
+			}
+			s += "  " + text
+		} else {
+			// continuation rows only show the address of their first byte
+			rowAddress := entries[0].address + uint64(i*listingBytesPerRow)
+			s = fmt.Sprintf("%6s  %0*X  %s", "", lw.addressDigits, rowAddress, column)
+		}
+		_, _ = fmt.Fprintln(lw.w, strings.TrimRight(s, " "))
+	}
+}
+
+// cycleColumn returns the formatted cycle count, a + suffix marks an additional
+// cycle on page boundary crossing.
+func cycleColumn(cycles int, pageCross bool) string {
+	if cycles == 0 {
+		return ""
+	}
+	if pageCross {
+		return fmt.Sprintf("%d+", cycles)
+	}
+	return fmt.Sprint(cycles)
+}
@@ -0,0 +1,128 @@
+package assembler
+
+import (
+	"bytes"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/retroenv/retroasm/pkg/arch/m6502"
+	"github.com/retroenv/retrogolib/assert"
+)
+
+var listingTestCode = `.segment "HEADER"
+MACRO store value
+  lda #value
+  sta $10
+ENDM
+.include "defs.asm"
+start:
+  lda #VALUE ; load
+  store 2
+  .byte 1,2,3,4,5
+`
+
+var listingTestExpected = `     1                         .segment "HEADER"
+     2                         MACRO store value
+     3                           lda #value
+     4                           sta $10
+     5                         ENDM
+     6                         .include "defs.asm"
+        ; file 'defs.asm'
+     1                         VALUE = $42
+     2  0000  EA               nop
+        ; file 'main.asm'
+     7                         start:
+     8  0001  A9 42              lda #VALUE ; load
+     9                           store 2
+    3+  0003  A9 02              lda #value
+    4+  0005  85 10              sta $10
+    10  0007  01 02 03 04        .byte 1,2,3,4,5
+        000B  05
+`
+
+func TestAssemblerWriteListingIncludeDirs(t *testing.T) {
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(unitTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	asm.SetSourceName("src/main.asm")
+	asm.SetIncludeDirs([]string{"include"})
+	asm.fileReader = func(name string) ([]byte, error) {
+		if name != "include/defs.asm" {
+			return nil, fs.ErrNotExist
+		}
+		return []byte("nop\n"), nil
+	}
+	code := ".segment \"HEADER\"\n.include \"defs.asm\"\n"
+	assert.NoError(t, asm.Process(t.Context(), strings.NewReader(code)))
+
+	var listing bytes.Buffer
+	assert.NoError(t, asm.WriteListing(&listing, ListingOptions{}))
+	assert.Contains(t, listing.String(), "; file 'include/defs.asm'\n     1  0000  EA               nop\n")
+}
+
+func TestAssemblerWriteListingIncludedMacro(t *testing.T) {
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(unitTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	asm.SetSourceName("main.asm")
+	asm.fileReader = func(name string) ([]byte, error) {
+		assert.Equal(t, "macros.asm", name)
+		return []byte("MACRO store\n  sta $10\nENDM\n"), nil
+	}
+	code := ".segment \"HEADER\"\n.include \"macros.asm\"\nstore\n"
+	assert.NoError(t, asm.Process(t.Context(), strings.NewReader(code)))
+
+	var listing bytes.Buffer
+	assert.NoError(t, asm.WriteListing(&listing, ListingOptions{}))
+	assert.Contains(t, listing.String(), "     3                         store\n    2+  0000  85 10              sta $10\n")
+}
+
+func TestAssemblerWriteListing(t *testing.T) {
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(unitTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	asm.SetSourceName("main.asm")
+	asm.fileReader = func(name string) ([]byte, error) {
+		assert.Equal(t, "defs.asm", name)
+		return []byte("VALUE = $42\nnop\n"), nil
+	}
+	assert.NoError(t, asm.Process(t.Context(), strings.NewReader(listingTestCode)))
+
+	var listing bytes.Buffer
+	assert.NoError(t, asm.WriteListing(&listing, ListingOptions{}))
+	assert.Equal(t, listingTestExpected, listing.String())
+}
+
+func TestAssemblerWriteListingCycles(t *testing.T) {
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(unitTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	code := ".segment \"HEADER\"\nlda $10,x\nlda $1000,x\n.pc02\nphx\n"
+	assert.NoError(t, asm.Process(t.Context(), strings.NewReader(code)))
+
+	var listing bytes.Buffer
+	assert.NoError(t, asm.WriteListing(&listing, ListingOptions{Cycles: true}))
+	expected := `     1                              .segment "HEADER"
+     2  0000  B5 10            4    lda $10,x
+     3  0002  BD 00 10         4+   lda $1000,x
+     4                              .pc02
+     5  0005  DA               3    phx
+`
+	assert.Equal(t, expected, listing.String())
+}
+
+func TestAssemblerWriteListingWithoutSource(t *testing.T) {
+	cfg := m6502.New()
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	assert.ErrorIs(t, asm.WriteListing(&buf, ListingOptions{}), errListingSourceMissing)
+}
@@ -0,0 +1,120 @@
+package assembler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/retroenv/retroasm/pkg/arch"
+	"github.com/retroenv/retroasm/pkg/arch/m6502"
+	"github.com/retroenv/retrogolib/assert"
+)
+
+var longBranchTestConfig = `
+MEMORY {
+    PRG: start = $8000, size = $1000;
+}
+SEGMENTS {
+    CODE: load = PRG, type = ro;
+}
+`
+
+func runLongBranchTest(t *testing.T, code string, longBranches bool) ([]byte, []Diagnostic, error) {
+	t.Helper()
+
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(longBranchTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	asm.SetLongBranches(longBranches)
+	err := asm.Process(t.Context(), strings.NewReader(code))
+	return buf.Bytes(), asm.Notes(), err
+}
+
+func TestAssemblerLongBranches(t *testing.T) {
+	code := `.segment "CODE"
+start:
+  beq far
+  bne start
+  .res 200
+far:
+  bcc start
+`
+
+	output, notes, err := runLongBranchTest(t, code, true)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []byte{0xd0, 0x03, 0x4c, 0xcf, 0x80}, output[:5])   // bne *+5, jmp far
+	assert.Equal(t, []byte{0xd0, 0xf9}, output[5:7])                    // bne start
+	assert.Equal(t, []byte{0xb0, 0x03, 0x4c, 0x00, 0x80}, output[207:]) // bcs *+5, jmp start
+
+	assert.Len(t, notes, 2)
+	assert.Equal(t, CodeLongBranch, notes[0].Code)
+	assert.Equal(t, 3, notes[0].Position.Line)
+	assert.Equal(t, 7, notes[1].Position.Line)
+}
+
+func TestAssemblerLongBranchesFeature(t *testing.T) {
+	code := `.feature longbranch
+.segment "CODE"
+  bmi far
+  .res 200
+far:
+  rts
+`
+
+	output, notes, err := runLongBranchTest(t, code, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x10, 0x03, 0x4c, 0xcd, 0x80}, output[:5])
+	assert.Len(t, notes, 1)
+}
+
+func TestAssemblerLongBranchesFeatureInclude(t *testing.T) {
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(longBranchTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	asm.fileReader = func(_ string) ([]byte, error) {
+		return []byte(".feature longbranch\n"), nil
+	}
+	code := `.segment "CODE"
+.include "feat.inc"
+  beq far
+  .res 200
+far:
+  rts
+`
+	assert.NoError(t, asm.Process(t.Context(), strings.NewReader(code)))
+	assert.Equal(t, []byte{0xd0, 0x03, 0x4c, 0xcd, 0x80}, buf.Bytes()[:5])
+}
+
+func TestAssemblerLongBranchesUnconditional(t *testing.T) {
+	code := `.pc02
+.segment "CODE"
+  bra far
+  .res 200
+far:
+  rts
+`
+
+	output, notes, err := runLongBranchTest(t, code, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x4c, 0xcb, 0x80}, output[:3]) // jmp far
+	assert.Len(t, notes, 1)
+	assert.Equal(t, CodeLongBranch, notes[0].Code)
+}
+
+func TestAssemblerLongBranchesDisabled(t *testing.T) {
+	code := `.segment "CODE"
+  bvs far
+  .res 200
+far:
+  rts
+`
+
+	_, notes, err := runLongBranchTest(t, code, false)
+	assert.ErrorIs(t, err, arch.ErrBranchOutOfRange)
+	assert.Len(t, notes, 0)
+}
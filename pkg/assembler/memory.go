@@ -6,6 +6,7 @@ import "github.com/retroenv/retroasm/pkg/assembler/config"
 type memory struct {
 	start uint64
 	size  uint64
+	file  string // configured output file name
 	data  []byte
 }
 
@@ -14,6 +15,7 @@ func newMemory(cfg config.Memory) *memory {
 	o := &memory{
 		start: cfg.Start,
 		size:  cfg.Size,
+		file:  cfg.File,
 	}
 
 	if cfg.Fill {
@@ -26,11 +28,11 @@ func newMemory(cfg config.Memory) *memory {
 	return o
 }
 
-// write data using the offset address into the memory, the index will be calculated based on
-// the start address of the memory. If the memory config does not specify the fill flag,
+// write data at the load address into the memory, the output of the memory starts at
+// the index of the memory start address. If the memory config does not specify the fill flag,
 // the memory can not be preallocated but has to be written incrementally.
-func (o *memory) write(data []byte, offsetAddress, segmentStart uint64) {
-	index := int(offsetAddress - o.start + segmentStart)
+func (o *memory) write(data []byte, loadAddress uint64) {
+	index := int(loadAddress)
 
 	extendBuf := index - len(o.data) + len(data)
 	if extendBuf > 0 {
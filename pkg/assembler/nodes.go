@@ -42,8 +42,9 @@ type reference struct {
 
 // data of type []byte or string.
 type data struct {
-	address uint64 // assigned start address of the data
-	width   int    // data item width in bytes
+	position token.Position // source position of the data directive
+	address  uint64         // assigned start address of the data
+	width    int            // data item width in bytes
 	// flag whether data space is reserved and should be filled with the
 	// optional fill bytes in values. If the fill values are shorter than
 	// the reserved space, the fill values will be repeated.
@@ -67,7 +68,8 @@ type data struct {
 
 // instruction of the used architecture.
 type instruction struct {
-	address  uint64 // assigned start address of the instruction
+	position token.Position // source position of the instruction
+	address  uint64         // assigned start address of the instruction
 	size     int
 	opcodes  []byte
 	opcodeID uint8
@@ -75,12 +77,20 @@ type instruction struct {
 	name       string
 	addressing int
 	argument   any
+
+	// addressing of the source, the address assigning step resolves ambiguous
+	// addressing modes again in every pass
+	sourceAddressing int
+	// set once the branch target was out of range, the branch is replaced by its
+	// expanded form in all following passes
+	longBranch bool
 }
 
 type variable struct {
-	address uint64 // assigned start address of the instruction
+	address uint64 // assigned start address of the variable
 
-	v ast.Variable
+	v      ast.Variable
+	symbol *scope.Symbol // symbol of a named variable, can be nil
 }
 
 type scopeChange struct {
@@ -91,6 +101,14 @@ type macro struct {
 	name      string
 	arguments map[string]int // maps name to position
 	tokens    []token.Token
+	cpu       *ast.CPU       // CPU selection at the macro definition, nil for the default
+	position  token.Position // source position of the macro definition
+}
+
+// macroKey identifies a macro of a source file.
+type macroKey struct {
+	fileScope *scope.Scope
+	name      string
 }
 
 // wrap symbol to implement ast.Node interface and avoid cyclic import.
@@ -101,6 +119,7 @@ type symbol struct {
 // Copy returns a copy of the data node.
 func (d *data) Copy() ast.Node {
 	return &data{
+		position:   d.position,
 		address:    d.address,
 		width:      d.width,
 		fill:       d.fill,
@@ -113,6 +132,11 @@ func (d *data) Copy() ast.Node {
 func (d *data) SetComment(_ string) {
 }
 
+// Position returns the source position of the data node.
+func (d *data) Position() token.Position {
+	return d.position
+}
+
 func (i *instruction) Address() uint64 {
 	return i.address
 }
@@ -160,6 +184,7 @@ func (i *instruction) SetAddressing(addressing int) {
 // Copy returns a copy of the instruction node.
 func (i *instruction) Copy() ast.Node {
 	return &instruction{
+		position:   i.position,
 		address:    i.address,
 		size:       i.size,
 		opcodes:    i.opcodes,
@@ -167,23 +192,37 @@ func (i *instruction) Copy() ast.Node {
 		name:       i.name,
 		addressing: i.addressing,
 		argument:   i.argument,
+
+		sourceAddressing: i.sourceAddressing,
+		longBranch:       i.longBranch,
 	}
 }
 
 func (i *instruction) SetComment(_ string) {
 }
 
+// Position returns the source position of the instruction node.
+func (i *instruction) Position() token.Position {
+	return i.position
+}
+
 // Copy returns a copy of the variable node.
 func (v *variable) Copy() ast.Node {
 	return &variable{
 		address: v.address,
 		v:       v.v.Copy().(ast.Variable),
+		symbol:  v.symbol,
 	}
 }
 
 func (v *variable) SetComment(_ string) {
 }
 
+// Position returns the source position of the variable node.
+func (v *variable) Position() token.Position {
+	return v.v.Position()
+}
+
 // Copy returns a copy of the scope change node.
 func (s scopeChange) Copy() ast.Node {
 	return scopeChange{
@@ -200,12 +239,19 @@ func (m macro) Copy() ast.Node {
 		name:      m.name,
 		arguments: maps.Clone(m.arguments),
 		tokens:    slices.Clone(m.tokens),
+		cpu:       m.cpu,
+		position:  m.position,
 	}
 }
 
 func (m macro) SetComment(_ string) {
 }
 
+// Position returns the source position of the macro definition.
+func (m macro) Position() token.Position {
+	return m.position
+}
+
 // Copy returns a copy of the symbol node.
 func (s *symbol) Copy() ast.Node {
 	return &symbol{
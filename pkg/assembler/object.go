@@ -0,0 +1,124 @@
+package assembler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/retroenv/retroasm/pkg/lexer/token"
+)
+
+const (
+	objectFormat  = "retroasm-object"
+	objectVersion = 1
+)
+
+var errInvalidObject = errors.New("invalid object file")
+
+// Object is a relocatable object file of an assembled source file. It contains the
+// assembled bytes of every used segment as fragments that the linker places in the
+// segments of its configuration. Values that depend on the placement of the fragments
+// or on symbols of other object files are stored as relocations.
+type Object struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	Source  string `json:"source,omitempty"` // name of the assembled source file
+
+	Segments []ObjectSegment `json:"segments"`
+	Scopes   []ObjectScope   `json:"scopes"` // scopes of the symbols, the first one is the file scope
+	Symbols  []ObjectSymbol  `json:"symbols"`
+	Condes   []ObjectCondes  `json:"condes,omitempty"`
+
+	Exports []string `json:"exports,omitempty"` // symbols of the file scope that other objects can reference
+	Imports []string `json:"imports,omitempty"` // symbols that have to be exported by another object
+}
+
+// ObjectSegment is the fragment of a segment that an object file contributes.
+type ObjectSegment struct {
+	Name        string       `json:"name"`
+	Size        uint64       `json:"size"`
+	Data        []byte       `json:"data,omitempty"` // empty for segments that only reserve space
+	Relocations []Relocation `json:"relocations,omitempty"`
+}
+
+// Relocation is a value in a segment fragment that the linker calculates once all
+// fragments are placed.
+type Relocation struct {
+	Offset     uint64        `json:"offset"` // offset of the value in the fragment
+	Size       int           `json:"size"`   // size of the value in bytes
+	Scope      int           `json:"scope"`  // index of the scope that the expression is evaluated in
+	Expression []ObjectToken `json:"expression"`
+}
+
+// ObjectScope is a scope of an object file.
+type ObjectScope struct {
+	Name   string `json:"name,omitempty"`
+	Parent int    `json:"parent"` // index of the parent scope, -1 for the file scope
+}
+
+// ObjectSymbol is a symbol that is defined by an object file. Symbols with a segment
+// are relative to the start of the fragment of the segment, symbols with an expression
+// are evaluated by the linker and all other symbols are constants.
+type ObjectSymbol struct {
+	Name       string        `json:"name"`
+	Scope      int           `json:"scope"`
+	Segment    string        `json:"segment,omitempty"`
+	Value      uint64        `json:"value"`
+	Expression []ObjectToken `json:"expression,omitempty"`
+}
+
+// ObjectCondes is a function of a constructor, destructor or interruptor table.
+type ObjectCondes struct {
+	Name     string `json:"name"`
+	Scope    int    `json:"scope"`
+	Type     int    `json:"type"`
+	Priority int    `json:"priority"`
+}
+
+// ObjectToken is a token of an expression of an object file.
+type ObjectToken struct {
+	Type  token.Type `json:"type"`
+	Value string     `json:"value,omitempty"`
+}
+
+// ReadObject reads an object file that was written by an assembler in object mode.
+func ReadObject(reader io.Reader) (*Object, error) {
+	obj := &Object{}
+	if err := json.NewDecoder(reader).Decode(obj); err != nil {
+		return nil, fmt.Errorf("decoding object file: %w", err)
+	}
+	if obj.Format != objectFormat {
+		return nil, fmt.Errorf("%w: unsupported format '%s'", errInvalidObject, obj.Format)
+	}
+	if obj.Version != objectVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", errInvalidObject, obj.Version)
+	}
+	return obj, nil
+}
+
+// Write writes the object file to the writer.
+func (o *Object) Write(writer io.Writer) error {
+	if err := json.NewEncoder(writer).Encode(o); err != nil {
+		return fmt.Errorf("encoding object file: %w", err)
+	}
+	return nil
+}
+
+// objectTokens converts expression tokens to object file tokens.
+func objectTokens(tokens []token.Token) []ObjectToken {
+	result := make([]ObjectToken, 0, len(tokens))
+	for _, tok := range tokens {
+		result = append(result, ObjectToken{Type: tok.Type, Value: tok.Value})
+	}
+	return result
+}
+
+// expressionTokens converts object file tokens to expression tokens.
+func expressionTokens(tokens []ObjectToken) []token.Token {
+	result := make([]token.Token, 0, len(tokens))
+	for _, tok := range tokens {
+		result = append(result, token.Token{Type: tok.Type, Value: tok.Value})
+	}
+	return result
+}
@@ -0,0 +1,325 @@
+package assembler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/retroenv/retroasm/pkg/arch"
+	"github.com/retroenv/retroasm/pkg/expression"
+	"github.com/retroenv/retroasm/pkg/lexer/token"
+	"github.com/retroenv/retroasm/pkg/parser/ast"
+	"github.com/retroenv/retroasm/pkg/scope"
+)
+
+var errObjectArchitecture = errors.New("architecture does not support object files")
+
+// objectState contains the state of an assembler run that creates an object file.
+type objectState struct {
+	imports     map[string]*scope.Symbol // symbols that the source references but does not define
+	condes      []condesEntry            // declared functions of constructor, destructor and interruptor tables
+	relocations []relocationSite
+}
+
+// relocationSite is a value of an instruction or data node that references symbols.
+// The object writer stores it as relocation if the value depends on the placement of
+// a segment or on an imported symbol.
+type relocationSite struct {
+	segment        *segment
+	scope          *scope.Scope // scope to resolve the symbols of the expression in
+	address        uint64       // assigned address of the value
+	programCounter uint64       // assigned address of the node, the value of $ in the expression
+	size           int
+	relative       bool // value is encoded relative to the address after the instruction
+	tokens         []token.Token
+	position       token.Position
+}
+
+// SetObjectMode sets whether Process and ProcessAST write a relocatable object file
+// instead of a binary. Symbols declared by .export or .global are exported to the
+// other object files, symbols declared by .import or .global are imported from them
+// when linking.
+func (asm *Assembler[T]) SetObjectMode(enabled bool) {
+	if !enabled {
+		asm.object = nil
+		return
+	}
+	asm.object = &objectState{
+		imports: map[string]*scope.Symbol{},
+	}
+}
+
+// SetAutoImport sets whether symbols that the source references but does not define
+// are imported in object mode, like the ca65 .autoimport feature does. Otherwise every
+// imported symbol has to be declared by .import or .global.
+func (asm *Assembler[T]) SetAutoImport(enabled bool) {
+	asm.autoImport = enabled
+}
+
+// addObjectImportsStep creates an import symbol in the file scope for every symbol that
+// is referenced by the source but not defined or declared by a visibility directive.
+// Imports have no value until opcodes are generated, so that instructions referencing
+// them use absolute addressing.
+func addObjectImportsStep[T any](_ context.Context, asm *Assembler[T]) error {
+	currentScope := asm.fileScope
+	var errs []error
+
+	for _, seg := range asm.segmentsOrder {
+		for _, node := range seg.nodes {
+			if n, ok := node.(scopeChange); ok {
+				currentScope = n.scope
+				continue
+			}
+
+			for _, name := range referencedSymbolNames(node) {
+				if _, err := currentScope.GetSymbol(name); err == nil {
+					continue
+				}
+
+				if _, err := asm.object.addImport(asm.fileScope, name); err != nil {
+					errs = append(errs, nodeError(node, err))
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// addImport creates an import symbol in the file scope.
+func (o *objectState) addImport(fileScope *scope.Scope, name string) (*scope.Symbol, error) {
+	sym, err := scope.NewSymbol(fileScope, name, scope.LabelType)
+	if err != nil {
+		return nil, fmt.Errorf("creating import symbol: %w", err)
+	}
+	sym.SetVisibility(scope.ImportVisibility)
+	o.imports[name] = sym
+	return sym, nil
+}
+
+// setImportPlaceholders sets the value of all imported symbols to 0, the linker
+// replaces it by the value of the exported symbol.
+func (o *objectState) setImportPlaceholders() {
+	for _, sym := range o.imports {
+		sym.SetAddress(0)
+	}
+}
+
+// isImport returns whether the symbol is an import symbol.
+func (o *objectState) isImport(sym *scope.Symbol) bool {
+	return o.imports[sym.Name()] == sym
+}
+
+// addInstructionRelocation records the argument of a generated instruction if it
+// references symbols.
+func (o *objectState) addInstructionRelocation(architecture any, seg *segment, sc *scope.Scope,
+	ins *instruction) error {
+
+	tokens := argumentTokens(ins.argument)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	locator, ok := architecture.(arch.OperandLocator)
+	if !ok {
+		return errObjectArchitecture
+	}
+	offset, size, relative := locator.InstructionOperand(ins)
+	if size == 0 {
+		return nil
+	}
+
+	o.relocations = append(o.relocations, relocationSite{
+		segment:        seg,
+		scope:          sc,
+		address:        ins.address + uint64(offset),
+		programCounter: ins.address,
+		size:           size,
+		relative:       relative,
+		tokens:         tokens,
+		position:       ins.position,
+	})
+	return nil
+}
+
+// checkRelativeImport returns an error if the instruction encodes its argument relative
+// to the instruction address and references an imported symbol, as the distance to a
+// symbol of another object file is unknown until linking.
+func (o *objectState) checkRelativeImport(architecture any, sc *scope.Scope, ins *instruction) error {
+	locator, ok := architecture.(arch.OperandLocator)
+	if !ok {
+		return errObjectArchitecture
+	}
+	if _, _, relative := locator.InstructionOperand(ins); !relative {
+		return nil
+	}
+
+	for _, tok := range argumentTokens(ins.argument) {
+		if !isSymbolToken(tok) {
+			continue
+		}
+		if sym, err := sc.GetSymbol(tok.Value); err == nil && o.isImport(sym) {
+			return fmt.Errorf("%w: '%s'", errObjectRelativeRelocation, tok.Value)
+		}
+	}
+	return nil
+}
+
+// addDataRelocations records all values of a data node that reference symbols. It has
+// to be called before the references of the node are replaced by their values.
+func (o *objectState) addDataRelocations(seg *segment, sc *scope.Scope, dat *data) {
+	site := relocationSite{
+		segment:        seg,
+		scope:          sc,
+		address:        dat.address,
+		programCounter: dat.address,
+		size:           dat.width,
+		position:       dat.position,
+	}
+
+	if dat.deferred {
+		// every expression of the data list results in a value of the data width
+		for _, tokens := range splitExpressionList(dat.expression.Tokens()) {
+			site.tokens = tokens
+			o.relocations = append(o.relocations, site)
+			site.address += uint64(dat.width)
+		}
+		return
+	}
+
+	for _, value := range dat.values {
+		switch v := value.(type) {
+		case []byte:
+			site.address += uint64(len(v))
+
+		case reference:
+			site.size = 1
+			if v.typ == fullAddress {
+				site.size = 2
+			}
+			site.tokens = referenceTokens(v)
+			o.relocations = append(o.relocations, site)
+			site.address += uint64(site.size)
+		}
+	}
+}
+
+// referencedSymbolNames returns the names of all symbols that a node references.
+func referencedSymbolNames(node ast.Node) []string {
+	var tokens []token.Token
+
+	switch n := node.(type) {
+	case *instruction:
+		tokens = argumentTokens(n.argument)
+
+	case *data:
+		for _, value := range n.values {
+			if ref, ok := value.(reference); ok {
+				tokens = append(tokens, referenceTokens(ref)...)
+			}
+		}
+		if n.expression != nil {
+			tokens = append(tokens, n.expression.Tokens()...)
+		}
+		if n.size != nil {
+			tokens = append(tokens, n.size.Tokens()...)
+		}
+
+	case *symbol:
+		if exp := n.Expression(); exp != nil {
+			tokens = exp.Tokens()
+		}
+	}
+
+	var names []string
+	for _, tok := range tokens {
+		if isSymbolToken(tok) {
+			names = append(names, tok.Value)
+		}
+	}
+	return names
+}
+
+// isSymbolToken returns whether the token references a symbol.
+func isSymbolToken(tok token.Token) bool {
+	if tok.Type != token.Identifier || tok.Value == "" {
+		return false
+	}
+	if tok.Value[0] == '"' || tok.Value[0] == '\'' {
+		return false // string literal
+	}
+	return !expression.IsKeywordOperator(tok.Value)
+}
+
+// argumentTokens returns the expression tokens of an instruction argument that
+// references symbols.
+func argumentTokens(argument any) []token.Token {
+	switch arg := argument.(type) {
+	case reference:
+		return referenceTokens(arg)
+
+	case ast.Expression:
+		if arg.Value == nil {
+			return nil
+		}
+		return arg.Value.Tokens()
+
+	default:
+		return nil
+	}
+}
+
+// referenceTokens returns the expression tokens of a reference, including the
+// offset of the reference name and the byte selection of the reference type.
+func referenceTokens(ref reference) []token.Token {
+	var tokens []token.Token
+	switch ref.typ {
+	case lowAddressByte:
+		tokens = append(tokens, token.Token{Type: token.Lt})
+	case highAddressByte:
+		tokens = append(tokens, token.Token{Type: token.Gt})
+	case bankAddressByte:
+		tokens = append(tokens, token.Token{Type: token.Caret})
+	}
+
+	name, offset := parseReferenceOffset(ref.name)
+	tokens = append(tokens, token.Token{Type: token.Identifier, Value: name})
+
+	switch {
+	case offset > 0:
+		tokens = append(tokens,
+			token.Token{Type: token.Plus},
+			token.Token{Type: token.Number, Value: strconv.FormatInt(offset, 10)})
+	case offset < 0:
+		tokens = append(tokens,
+			token.Token{Type: token.Minus},
+			token.Token{Type: token.Number, Value: strconv.FormatInt(-offset, 10)})
+	}
+	return tokens
+}
+
+// splitExpressionList splits the tokens of a comma separated expression list into
+// the tokens of the single expressions.
+func splitExpressionList(tokens []token.Token) [][]token.Token {
+	var (
+		result [][]token.Token
+		start  int
+		depth  int
+	)
+
+	for i, tok := range tokens {
+		switch tok.Type {
+		case token.LeftParentheses:
+			depth++
+		case token.RightParentheses:
+			depth--
+		case token.Comma:
+			if depth == 0 {
+				result = append(result, tokens[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(result, tokens[start:])
+}
@@ -0,0 +1,245 @@
+package assembler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/retroenv/retroasm/pkg/arch/m6502"
+	"github.com/retroenv/retrogolib/assert"
+)
+
+var objectTestConfig = `
+MEMORY {
+    ZP:  start = $0000, size = $100;
+    PRG: start = $8000, size = $100;
+}
+SEGMENTS {
+    ZEROPAGE: load = ZP, type = zp;
+    CODE:     load = PRG, type = ro;
+}
+`
+
+func assembleObject(t *testing.T, source string) *Object {
+	t.Helper()
+
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(objectTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	asm.SetObjectMode(true)
+	assert.NoError(t, asm.Process(t.Context(), strings.NewReader(source)))
+
+	obj, err := ReadObject(&buf)
+	assert.NoError(t, err)
+	return obj
+}
+
+func linkObjects(t *testing.T, objects ...*Object) ([]byte, error) {
+	t.Helper()
+
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(objectTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	err := asm.Link(t.Context(), objects)
+	return buf.Bytes(), err
+}
+
+func TestObjectLink(t *testing.T) {
+	main := assembleObject(t, `.import sub, table, table_end
+.export start
+.segment "CODE"
+start:
+  jsr sub
+  lda #<table
+  ldx #>table
+  lda table_end
+  jmp start
+`)
+	assert.Equal(t, []string{"start"}, main.Exports)
+	assert.Equal(t, []string{"sub", "table", "table_end"}, main.Imports)
+
+	lib := assembleObject(t, `.import start
+.export sub, table, table_end
+.segment "CODE"
+table_end = table + 4
+sub:
+  rts
+table:
+  .word sub, start
+`)
+	assert.Equal(t, []string{"start"}, lib.Imports)
+
+	data, err := linkObjects(t, main, lib)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x20, 0x0d, 0x80, // jsr sub
+		0xa9, 0x0e, // lda #<table
+		0xa2, 0x80, // ldx #>table
+		0xad, 0x12, 0x80, // lda table_end
+		0x4c, 0x00, 0x80, // jmp start
+		0x60,       // rts
+		0x0d, 0x80, // .word sub
+		0x00, 0x80, // .word start
+	}, data)
+}
+
+func TestObjectLinkLocalSymbols(t *testing.T) {
+	main := assembleObject(t, `.import sub
+.export start
+.segment "CODE"
+start:
+loop: jsr sub
+  jmp loop
+`)
+	assert.Equal(t, []string{"start"}, main.Exports)
+
+	lib := assembleObject(t, `.export sub
+.segment "CODE"
+.proc sub
+loop: jmp loop
+.endproc
+`)
+	assert.Equal(t, []string{"sub"}, lib.Exports)
+
+	data, err := linkObjects(t, main, lib)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x20, 0x06, 0x80, // jsr sub
+		0x4c, 0x00, 0x80, // jmp loop
+		0x4c, 0x06, 0x80, // jmp sub::loop
+	}, data)
+}
+
+func TestObjectAutoImport(t *testing.T) {
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(objectTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	asm.SetObjectMode(true)
+	err := asm.Process(t.Context(), strings.NewReader(`.segment "CODE"
+  jsr sbu
+`))
+	assert.ErrorContains(t, err, "symbol not found in scope: 'sbu'")
+
+	buf.Reset()
+	asm = New(cfg, &buf)
+	asm.SetObjectMode(true)
+	asm.SetAutoImport(true)
+	assert.NoError(t, asm.Process(t.Context(), strings.NewReader(`.segment "CODE"
+loop:
+  jsr sub
+  jmp loop
+`)))
+	main, err := ReadObject(&buf)
+	assert.NoError(t, err)
+	assert.Empty(t, main.Exports)
+	assert.Equal(t, []string{"sub"}, main.Imports)
+
+	lib := assembleObject(t, `.export sub
+.segment "CODE"
+sub:
+loop:
+  jmp loop
+`)
+
+	// labels that are not exported do not conflict between object files
+	data, err := linkObjects(t, main, lib)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x20, 0x06, 0x80, // jsr sub
+		0x4c, 0x00, 0x80, // jmp loop
+		0x4c, 0x06, 0x80, // jmp loop
+	}, data)
+}
+
+func TestObjectLinkVisibility(t *testing.T) {
+	main := assembleObject(t, `.importzp ptr
+.import sub
+.export start
+.segment "CODE"
+start:
+  lda ptr
+  jsr sub
+`)
+	assert.Equal(t, []string{"start"}, main.Exports)
+	assert.Equal(t, []string{"ptr", "sub"}, main.Imports)
+
+	lib := assembleObject(t, `.exportzp ptr
+.global sub
+.segment "ZEROPAGE"
+unused: .res 1
+ptr: .res 2
+.segment "CODE"
+sub:
+  rts
+helper:
+  rts
+`)
+	assert.Equal(t, []string{"ptr", "sub"}, lib.Exports)
+
+	data, err := linkObjects(t, main, lib)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0xa5, 0x01, // lda ptr
+		0x20, 0x05, 0x80, // jsr sub
+		0x60, // rts
+		0x60, // rts
+	}, data)
+
+	helper := assembleObject(t, `.import helper
+.segment "CODE"
+  jmp helper
+`)
+	_, err = linkObjects(t, helper, lib)
+	assert.ErrorIs(t, err, errUnresolvedImport)
+}
+
+func TestObjectLinkErrors(t *testing.T) {
+	main := assembleObject(t, `.import sub
+.export start
+.segment "CODE"
+start:
+  jsr sub
+`)
+
+	_, err := linkObjects(t, main)
+	assert.ErrorIs(t, err, errUnresolvedImport)
+
+	_, err = linkObjects(t, main, main)
+	assert.ErrorIs(t, err, errDuplicateExport)
+}
+
+func TestObjectRelativeRelocation(t *testing.T) {
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(objectTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	asm.SetObjectMode(true)
+	err := asm.Process(t.Context(), strings.NewReader(`.import external
+.segment "CODE"
+  bne external
+`))
+	assert.ErrorIs(t, err, errObjectRelativeRelocation)
+}
+
+func TestObjectReadWrite(t *testing.T) {
+	obj := assembleObject(t, `.segment "CODE"
+start:
+  jmp start
+`)
+
+	var buf bytes.Buffer
+	assert.NoError(t, obj.Write(&buf))
+	read, err := ReadObject(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, obj, read)
+
+	_, err = ReadObject(strings.NewReader(`{"format":"other","version":1}`))
+	assert.ErrorIs(t, err, errInvalidObject)
+}
@@ -5,6 +5,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/retroenv/retroasm/pkg/assembler/config"
@@ -21,18 +24,43 @@ type parseAST[T any] struct {
 	cfg *config.Config[T]
 	// a function that reads in a file, for testing includes, defaults to os.ReadFile
 	fileReader    func(name string) ([]byte, error)
+	includeDirs   []string            // directories that included files are searched in
+	sources       *sourceRecorder     // records included files for the listing
+	dependencies  *dependencyRecorder // records all read files
 	includeActive set.Set[string]
 	includeStack  []string
 
+	fileScope      *scope.Scope // scope of the parsed source file
 	currentScope   *scope.Scope // current scope, can be a function scope with file scope as parent
 	currentSegment *segment     // the current segment being parsed
 
 	segments      map[string]*segment // maps segment name to segment
 	segmentsOrder []*segment          // sorted list of all parsed segments
+
+	condes       []condesEntry           // declared functions of constructor, destructor and interruptor tables
+	declarations []visibilityDeclaration // symbols of .export, .import and .global directives
+
+	cpu *ast.CPU // CPU selection of the parsed source, nil while the source did not select one
+
+	longBranches *bool // long branch setting of the assembler, applies to the whole program
 }
 
 var errNilInstructionArgument = errors.New("instruction argument cannot be nil")
 
+// enterFile starts parsing the nodes of a source file in the given file scope. Every
+// file starts without an active segment, unless the configuration has a single segment.
+func (asm *parseAST[T]) enterFile(fileScope *scope.Scope) {
+	asm.fileScope = fileScope
+	asm.currentScope = fileScope
+	asm.currentSegment = nil
+	asm.cpu = nil
+
+	if len(asm.cfg.SegmentsOrdered) == 1 {
+		asm.currentSegment = asm.segments[asm.cfg.SegmentsOrdered[0].SegmentName]
+		addSegmentScope(asm)
+	}
+}
+
 //nolint:cyclop,funlen // type switch with one case per AST node type
 func parseASTNode[T any](ctx context.Context, asm *parseAST[T], node ast.Node) ([]ast.Node, error) {
 	var (
@@ -69,10 +97,24 @@ func parseASTNode[T any](ctx context.Context, asm *parseAST[T], node ast.Node) (
 		nodes, err = parseInclude(ctx, asm, n)
 
 	case ast.Macro:
-		nodes, err = parseMacro(n)
+		nodes, err = parseMacro(asm, n)
+
+	case ast.CPU:
+		parseCPU(asm, n)
 
 	case ast.Variable:
-		parseVariable(n)
+		nodes, err = parseVariable(asm, n)
+
+	case ast.Condes:
+		parseCondes(asm, n)
+
+	case ast.SymbolVisibility:
+		parseSymbolVisibility(asm, n)
+
+	case ast.Configuration:
+		if !parseLongBranchFeature(asm, n) {
+			return []ast.Node{n}, nil
+		}
 
 		// default case for node types that do not have special handling at this point
 	default:
@@ -92,6 +134,7 @@ func parseSegment[T any](asm *parseAST[T], astSegment ast.Segment) error {
 	if ok {
 		// do not create a segment twice
 		asm.currentSegment = seg
+		addSegmentScope(asm)
 		return nil
 	}
 
@@ -107,14 +150,24 @@ func parseSegment[T any](asm *parseAST[T], astSegment ast.Segment) error {
 	asm.currentSegment = seg
 	asm.segments[seg.config.SegmentName] = seg
 	asm.segmentsOrder = append(asm.segmentsOrder, seg)
+	addSegmentScope(asm)
 	return nil
 }
 
+// addSegmentScope adds the active scope to the entered segment if a function or named
+// scope is active, as segments are processed in a different order than the source.
+func addSegmentScope[T any](asm *parseAST[T]) {
+	if asm.currentScope.Parent() != nil {
+		asm.currentSegment.addNode(scopeChange{scope: asm.currentScope})
+	}
+}
+
 func parseData(astData ast.Data) ([]ast.Node, error) {
 	dat := &data{
-		fill:  astData.Fill,
-		width: astData.Width,
-		size:  astData.Size,
+		position: astData.Position(),
+		fill:     astData.Fill,
+		width:    astData.Width,
+		size:     astData.Size,
 	}
 	if dat.size == nil {
 		dat.size = expression.New()
@@ -203,6 +256,7 @@ func parseAlias[T any](asm *parseAST[T], alias ast.Alias) ([]ast.Node, error) {
 		return nil, fmt.Errorf("creating symbol: %w", err)
 	}
 	sym.SetExpression(alias.Expression)
+	sym.SetPosition(alias.Position())
 	return []ast.Node{&symbol{Symbol: sym}}, nil
 }
 
@@ -211,16 +265,20 @@ func parseLabel[T any](asm *parseAST[T], label ast.Label) ([]ast.Node, error) {
 	if err != nil {
 		return nil, fmt.Errorf("creating symbol: %w", err)
 	}
+	sym.SetPosition(label.Position())
 
 	return []ast.Node{&symbol{Symbol: sym}}, nil
 }
 
 func parseInstruction(astInstruction ast.Instruction) ([]ast.Node, error) {
 	ins := &instruction{
+		position:   astInstruction.Position(),
 		addressing: astInstruction.Addressing,
 		argument:   astInstruction.Argument,
 		name:       astInstruction.Name,
 		opcodeID:   astInstruction.OpcodeID,
+
+		sourceAddressing: astInstruction.Addressing,
 	}
 
 	if astInstruction.Argument == nil {
@@ -370,25 +428,41 @@ func parseInclude[T any](ctx context.Context, asm *parseAST[T], inc ast.Include)
 	name := strings.Trim(inc.Name, "\"'")
 
 	if inc.Binary {
-		return parseBinaryInclude(asm, name)
+		return parseBinaryInclude(asm, name, inc.Position())
 	}
 
-	return parseSourceInclude(ctx, asm, name)
+	expansion := &token.Expansion{
+		Kind:     token.IncludeExpansion,
+		Name:     name,
+		Position: inc.Position(),
+	}
+	return parseSourceInclude(ctx, asm, name, expansion)
 }
 
-func parseBinaryInclude[T any](asm *parseAST[T], name string) ([]ast.Node, error) {
-	b, err := asm.fileReader(name)
+func parseBinaryInclude[T any](asm *parseAST[T], name string, pos token.Position) ([]ast.Node, error) {
+	_, b, err := readIncludeFile(asm, name, pos.File)
 	if err != nil {
-		return nil, fmt.Errorf("reading file '%s': %w", name, err)
+		return nil, err
 	}
 
-	dat := &data{size: expression.New()}
+	dat := &data{
+		position: pos,
+		size:     expression.New(),
+	}
 	dat.size.SetValue(1)
 	dat.values = append(dat.values, b)
 	return []ast.Node{dat}, nil
 }
 
-func parseSourceInclude[T any](ctx context.Context, asm *parseAST[T], name string) ([]ast.Node, error) {
+func parseSourceInclude[T any](ctx context.Context, asm *parseAST[T], name string,
+	expansion *token.Expansion) ([]ast.Node, error) {
+
+	name, b, err := readIncludeFile(asm, name, expansion.Position.File)
+	if err != nil {
+		return nil, err
+	}
+	expansion.Name = name // the listing looks up the lines of the read file
+
 	if asm.includeActive.Contains(name) {
 		chain := append(append([]string{}, asm.includeStack...), name)
 		return nil, fmt.Errorf("include cycle detected: %s", strings.Join(chain, " -> "))
@@ -400,12 +474,14 @@ func parseSourceInclude[T any](ctx context.Context, asm *parseAST[T], name strin
 		asm.includeStack = asm.includeStack[:len(asm.includeStack)-1]
 	}()
 
-	b, err := asm.fileReader(name)
-	if err != nil {
-		return nil, fmt.Errorf("reading file '%s': %w", name, err)
-	}
+	asm.sources.addSource(name, b)
+	asm.sources.addInclude(expansion)
 
 	pars := parser.New[T](asm.cfg.Arch, bytes.NewReader(b), asm.cfg.CompatibilityMode)
+	pars.SetSource(name, expansion)
+	if asm.cpu != nil {
+		pars.SetCPU(*asm.cpu)
+	}
 	if err := pars.Read(ctx); err != nil {
 		return nil, fmt.Errorf("parsing included file '%s': %w", name, err)
 	}
@@ -422,12 +498,12 @@ func parseSourceInclude[T any](ctx context.Context, asm *parseAST[T], name strin
 			continue
 		case ast.Segment:
 			if err := parseSegment(asm, n); err != nil {
-				return nil, fmt.Errorf("parsing segment in included file '%s': %w", name, err)
+				return nil, nodeError(n, fmt.Errorf("parsing segment in included file '%s': %w", name, err))
 			}
 		default:
 			newNodes, err := parseASTNode(ctx, asm, node)
 			if err != nil {
-				return nil, fmt.Errorf("processing node in included file '%s': %w", name, err)
+				return nil, nodeError(node, fmt.Errorf("processing node in included file '%s': %w", name, err))
 			}
 			result = append(result, newNodes...)
 		}
@@ -436,9 +512,64 @@ func parseSourceInclude[T any](ctx context.Context, asm *parseAST[T], name strin
 	return result, nil
 }
 
-func parseVariable(astVar ast.Variable) []ast.Node {
+// readIncludeFile reads an included file and returns its path. A relative name is
+// searched relative to the directory of the including file, then in the include
+// directories and last relative to the working directory.
+func readIncludeFile[T any](asm *parseAST[T], name, includingFile string) (string, []byte, error) {
+	var notFoundErr error
+
+	for _, path := range includePaths(name, includingFile, asm.includeDirs) {
+		b, err := asm.fileReader(path)
+		if err == nil {
+			asm.dependencies.add(path)
+			return path, b, nil
+		}
+		// paths outside of a virtual file system are invalid and can not contain the file
+		if !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, fs.ErrInvalid) {
+			return "", nil, fmt.Errorf("reading file '%s': %w", path, err)
+		}
+		if notFoundErr == nil {
+			notFoundErr = err
+		}
+	}
+
+	return "", nil, fmt.Errorf("reading file '%s': %w", name, notFoundErr)
+}
+
+// includePaths returns the paths that an included file is searched at, in order.
+func includePaths(name, includingFile string, includeDirs []string) []string {
+	if filepath.IsAbs(name) {
+		return []string{name}
+	}
+
+	candidates := []string{filepath.Join(filepath.Dir(includingFile), name)}
+	for _, dir := range includeDirs {
+		candidates = append(candidates, filepath.Join(dir, name))
+	}
+	candidates = append(candidates, filepath.Clean(name))
+
+	var paths []string
+	for _, path := range candidates {
+		if !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func parseVariable[T any](asm *parseAST[T], astVar ast.Variable) ([]ast.Node, error) {
 	v := &variable{v: astVar}
-	return []ast.Node{v}
+	if astVar.Name == "" {
+		return []ast.Node{v}, nil
+	}
+
+	sym, err := scope.NewSymbol(asm.currentScope, astVar.Name, scope.VariableType)
+	if err != nil {
+		return nil, fmt.Errorf("creating symbol: %w", err)
+	}
+	sym.SetPosition(astVar.Position())
+	v.symbol = sym
+	return []ast.Node{v}, nil
 }
 
 func parseFunction[T any](asm *parseAST[T], fun ast.Function) ([]ast.Node, error) {
@@ -446,8 +577,10 @@ func parseFunction[T any](asm *parseAST[T], fun ast.Function) ([]ast.Node, error
 	if err != nil {
 		return nil, fmt.Errorf("creating symbol: %w", err)
 	}
+	sym.SetPosition(fun.Position())
 
 	asm.currentScope = scope.New(asm.currentScope)
+	asm.currentScope.SetName(fun.Name)
 	newScope := scopeChange{
 		scope: asm.currentScope,
 	}
@@ -457,7 +590,7 @@ func parseFunction[T any](asm *parseAST[T], fun ast.Function) ([]ast.Node, error
 
 func parseFunctionEnd[T any](asm *parseAST[T], _ ast.FunctionEnd) ([]ast.Node, error) {
 	parentScope := asm.currentScope.Parent()
-	if parentScope == nil {
+	if parentScope == nil || asm.currentScope == asm.fileScope {
 		return nil, errors.New("unexpected function end, no parent scope found")
 	}
 
@@ -472,6 +605,7 @@ func parseFunctionEnd[T any](asm *parseAST[T], _ ast.FunctionEnd) ([]ast.Node, e
 
 func parseScope[T any](asm *parseAST[T], s ast.Scope) ([]ast.Node, error) {
 	asm.currentScope = scope.New(asm.currentScope)
+	asm.currentScope.SetName(s.Name)
 	newScope := scopeChange{
 		scope: asm.currentScope,
 	}
@@ -484,13 +618,14 @@ func parseScope[T any](asm *parseAST[T], s ast.Scope) ([]ast.Node, error) {
 	if err != nil {
 		return nil, fmt.Errorf("creating symbol: %w", err)
 	}
+	sym.SetPosition(s.Position())
 
 	return []ast.Node{newScope, &symbol{Symbol: sym}}, nil
 }
 
 func parseScopeEnd[T any](asm *parseAST[T], _ ast.ScopeEnd) ([]ast.Node, error) {
 	parentScope := asm.currentScope.Parent()
-	if parentScope == nil {
+	if parentScope == nil || asm.currentScope == asm.fileScope {
 		return nil, errors.New("unexpected scope end, no parent scope found")
 	}
 
@@ -503,11 +638,13 @@ func parseScopeEnd[T any](asm *parseAST[T], _ ast.ScopeEnd) ([]ast.Node, error)
 	return []ast.Node{newScope}, nil
 }
 
-func parseMacro(astMacro ast.Macro) ([]ast.Node, error) {
+func parseMacro[T any](asm *parseAST[T], astMacro ast.Macro) ([]ast.Node, error) {
 	mac := macro{
 		name:      astMacro.Name,
 		arguments: map[string]int{},
 		tokens:    astMacro.Token,
+		cpu:       asm.cpu,
+		position:  astMacro.Position(),
 	}
 
 	for i, argument := range astMacro.Arguments {
@@ -520,3 +657,21 @@ func parseMacro(astMacro ast.Macro) ([]ast.Node, error) {
 
 	return []ast.Node{mac}, nil
 }
+
+// parseCPU records the CPU selection of a CPU directive. Sources that are parsed later
+// continue the selection, included files the one at their include and macro expansions
+// the one at the macro definition.
+func parseCPU[T any](asm *parseAST[T], cpu ast.CPU) {
+	asm.cpu = &cpu
+}
+
+// parseLongBranchFeature applies the long branch feature configuration and returns
+// whether the configuration was handled. The feature applies to the whole program,
+// independent of the file or macro that enables it.
+func parseLongBranchFeature[T any](asm *parseAST[T], cfg ast.Configuration) bool {
+	if cfg.Item != ast.ConfigLongBranch {
+		return false
+	}
+	*asm.longBranches = cfg.Value != 0
+	return true
+}
@@ -1,6 +1,7 @@
 package assembler
 
 import (
+	"io/fs"
 	"strings"
 	"testing"
 
@@ -433,3 +434,67 @@ type testTypedInstructionArgument struct {
 	register string
 	width    int
 }
+
+func TestIncludePaths(t *testing.T) {
+	tests := []struct {
+		name          string
+		includingFile string
+		includeDirs   []string
+		expected      []string
+	}{
+		{
+			name:     "no including file",
+			expected: []string{"defs.asm"},
+		},
+		{
+			name:          "including file directory",
+			includingFile: "src/main.asm",
+			expected:      []string{"src/defs.asm", "defs.asm"},
+		},
+		{
+			name:          "include directories",
+			includingFile: "src/main.asm",
+			includeDirs:   []string{"include", "src"},
+			expected:      []string{"src/defs.asm", "include/defs.asm", "defs.asm"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, includePaths("defs.asm", tt.includingFile, tt.includeDirs))
+		})
+	}
+}
+
+func TestParseSourceIncludeDirs(t *testing.T) {
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(unitTestConfig)))
+
+	var read []string
+	p := &parseAST[*cpu6502.Instruction]{
+		cfg: cfg,
+		fileReader: func(name string) ([]byte, error) {
+			read = append(read, name)
+			switch name {
+			case "include/defs.asm":
+				return []byte(".include \"more.asm\"\n"), nil
+			case "include/more.asm":
+				return []byte("nop\n"), nil
+			default:
+				return nil, fs.ErrNotExist
+			}
+		},
+		includeDirs:   []string{"include"},
+		sources:       newSourceRecorder(),
+		includeActive: set.New[string](),
+		currentScope:  scope.New(nil),
+		segments:      map[string]*segment{},
+	}
+
+	_, err := parseASTNode(t.Context(), p, ast.NewInclude("defs.asm", false, 0, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"defs.asm", "include/defs.asm", "include/more.asm"}, read)
+
+	_, err = parseASTNode(t.Context(), p, ast.NewInclude("missing.asm", false, 0, 0))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -2,16 +2,23 @@ package assembler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/retroenv/retroasm/pkg/lexer/token"
 	"github.com/retroenv/retroasm/pkg/parser"
 	"github.com/retroenv/retroasm/pkg/parser/ast"
+	"github.com/retroenv/retroasm/pkg/scope"
 	"github.com/retroenv/retrogolib/set"
 )
 
 // processMacrosStep processes macro and rept nodes and replace them by their resolved nodes.
+// Macros are only visible in the source file that defines them.
 func processMacrosStep[T any](ctx context.Context, asm *Assembler[T]) error {
+	var errs []error
+	fileScope := asm.fileScope
+
 	for i, seg := range asm.segmentsOrder {
 		segmentNodesResolved := make([]ast.Node, 0, len(seg.nodes))
 
@@ -19,19 +26,26 @@ func processMacrosStep[T any](ctx context.Context, asm *Assembler[T]) error {
 			node := seg.nodes[j]
 
 			switch n := node.(type) {
+			case scopeChange:
+				fileScope = asm.sourceFileScope(n.scope)
+				segmentNodesResolved = append(segmentNodesResolved, n)
+
 			case ast.Identifier:
-				nodes, err := resolveMacroUsage(ctx, asm, n)
+				nodes, err := resolveMacroUsage(ctx, asm, fileScope, n)
 				if err != nil {
-					return fmt.Errorf("processing identifier '%s': %w", n.Name, err)
+					errs = append(errs, nodeError(n, fmt.Errorf("processing identifier '%s': %w", n.Name, err)))
+					continue
 				}
 				segmentNodesResolved = append(segmentNodesResolved, nodes...)
 
 			case macro:
-				_, ok := asm.macros[n.name]
+				key := macroKey{fileScope: fileScope, name: n.name}
+				_, ok := asm.macros[key]
 				if ok {
-					return fmt.Errorf("macro '%s' already exists", n.name)
+					errs = append(errs, nodeError(n, fmt.Errorf("macro '%s' already exists", n.name)))
+					continue
 				}
-				asm.macros[n.name] = n
+				asm.macros[key] = n
 
 			default:
 				segmentNodesResolved = append(segmentNodesResolved, n)
@@ -41,11 +55,24 @@ func processMacrosStep[T any](ctx context.Context, asm *Assembler[T]) error {
 		asm.segmentsOrder[i].nodes = segmentNodesResolved
 	}
 
-	return nil
+	return errors.Join(errs...)
+}
+
+// sourceFileScope returns the scope of the source file that the given scope belongs to.
+func (asm *Assembler[T]) sourceFileScope(sc *scope.Scope) *scope.Scope {
+	if !asm.childFileScopes {
+		return asm.fileScope
+	}
+	for sc != asm.fileScope && sc.Parent() != asm.fileScope {
+		sc = sc.Parent()
+	}
+	return sc
 }
 
-func resolveMacroUsage[T any](ctx context.Context, asm *Assembler[T], id ast.Identifier) ([]ast.Node, error) {
-	mac, ok := asm.macros[id.Name]
+func resolveMacroUsage[T any](ctx context.Context, asm *Assembler[T], fileScope *scope.Scope,
+	id ast.Identifier) ([]ast.Node, error) {
+
+	mac, ok := asm.macros[macroKey{fileScope: fileScope, name: id.Name}]
 	if !ok {
 		return nil, fmt.Errorf("unexpected identifier '%s' found", id.Name)
 	}
@@ -55,8 +82,19 @@ func resolveMacroUsage[T any](ctx context.Context, asm *Assembler[T], id ast.Ide
 			len(mac.arguments), len(id.Arguments))
 	}
 
-	// replace the macro placeholders with the passed values
+	expansion := &token.Expansion{
+		Kind:     token.MacroExpansion,
+		Name:     mac.name,
+		Position: id.Position(),
+	}
+
+	// replace the macro placeholders with the passed values in a copy of the tokens,
+	// the definition is shared by all usages of the macro
+	mac.tokens = slices.Clone(mac.tokens)
+	chained := map[*token.Expansion]*token.Expansion{}
 	for i, tok := range mac.tokens {
+		mac.tokens[i].Position.Expansion = chainExpansion(chained, tok.Position.Expansion, expansion)
+
 		if tok.Type != token.Identifier {
 			continue
 		}
@@ -78,12 +116,36 @@ func resolveMacroUsage[T any](ctx context.Context, asm *Assembler[T], id ast.Ide
 		}
 	}
 
-	return macroTokensToAStNodes(ctx, asm, mac.tokens)
+	asm.sources.addMacro(expansion, mac.tokens)
+
+	return macroTokensToAStNodes(ctx, asm, fileScope, mac)
 }
 
-func macroTokensToAStNodes[T any](ctx context.Context, asm *Assembler[T], tokens []token.Token) ([]ast.Node, error) {
+// chainExpansion returns a copy of the expansion chain of a macro definition token
+// that ends with the macro usage, to keep for example the include frame of a macro
+// that is defined in an included file. Copies are shared by all tokens of the usage.
+func chainExpansion(chained map[*token.Expansion]*token.Expansion, exp, usage *token.Expansion) *token.Expansion {
+	if exp == nil {
+		return usage
+	}
+	if c, ok := chained[exp]; ok {
+		return c
+	}
+
+	c := *exp
+	c.Position.Expansion = chainExpansion(chained, exp.Position.Expansion, usage)
+	chained[exp] = &c
+	return &c
+}
+
+func macroTokensToAStNodes[T any](ctx context.Context, asm *Assembler[T], fileScope *scope.Scope,
+	mac macro) ([]ast.Node, error) {
+
 	// convert the adjusted tokens to AST nodes
-	par := parser.NewWithTokens(asm.cfg.Arch, tokens, asm.cfg.CompatibilityMode)
+	par := parser.NewWithTokens(asm.cfg.Arch, mac.tokens, asm.cfg.CompatibilityMode)
+	if mac.cpu != nil {
+		par.SetCPU(*mac.cpu)
+	}
 	astNodes, err := par.TokensToAstNodes()
 	if err != nil {
 		return nil, fmt.Errorf("converting tokens to ast nodes: %w", err)
@@ -92,9 +154,15 @@ func macroTokensToAStNodes[T any](ctx context.Context, asm *Assembler[T], tokens
 	p := &parseAST[T]{
 		cfg:           asm.cfg,
 		fileReader:    asm.fileReader,
+		includeDirs:   asm.includeDirs,
+		sources:       asm.sources,
+		dependencies:  asm.dependencies,
 		includeActive: set.New[string](),
-		currentScope:  asm.fileScope,
+		fileScope:     fileScope,
+		currentScope:  fileScope,
 		segments:      map[string]*segment{},
+		cpu:           mac.cpu,
+		longBranches:  &asm.longBranches,
 	}
 
 	// process the AST nodes
@@ -9,8 +9,17 @@ import (
 type segment struct {
 	config *config.Segment
 	nodes  []ast.Node
+
+	start    uint64 // load address of the segment in its memory area, set when addresses are assigned
+	runStart uint64 // address that the segment is placed at during runtime, equals start without run memory
+	size     uint64 // size of the segment in bytes, including reserved space
 }
 
 func (seg *segment) addNode(node ast.Node) {
 	seg.nodes = append(seg.nodes, node)
 }
+
+// loadAddress returns the address in the load memory for an assigned address of the segment.
+func (seg *segment) loadAddress(address uint64) uint64 {
+	return address - seg.runStart + seg.start
+}
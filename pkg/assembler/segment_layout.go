@@ -0,0 +1,161 @@
+package assembler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/retroenv/retroasm/pkg/assembler/config"
+	"github.com/retroenv/retroasm/pkg/scope"
+	"github.com/retroenv/retrogolib/set"
+)
+
+const zeroPageEnd = 0x100
+
+// ca65DefaultSegments are the segments that ca65 creates in every object file, they
+// are never reported as missing.
+var ca65DefaultSegments = set.NewFromSlice([]string{"CODE", "RODATA", "BSS", "DATA", "ZEROPAGE", "NULL"})
+
+var (
+	errSegmentMissing  = errors.New("segment is not used by the program and not marked as optional")
+	errSegmentOverlap  = errors.New("segment start overlaps the previous segment")
+	errSegmentOverflow = errors.New("segment exceeds the size of its memory area")
+	errInitializedData = errors.New("segment of type bss or zp can not contain initialized data")
+)
+
+// memoryLayout places the segments one after another in their memory areas, in the
+// order of the segment configuration.
+type memoryLayout struct {
+	memories map[string]*config.Memory
+	next     map[string]uint64 // maps memory name to the next free address
+}
+
+func newMemoryLayout(memories map[string]*config.Memory) *memoryLayout {
+	return &memoryLayout{
+		memories: memories,
+		next:     map[string]uint64{},
+	}
+}
+
+// segmentStart returns the load and run start address of the segment. The load address
+// is the location of the segment in the output, the run address is used for all
+// labels of the segment.
+func (l *memoryLayout) segmentStart(cfg *config.Segment) (uint64, uint64, error) {
+	load := l.nextAddress(cfg.Memory.Name, cfg.Start)
+	if cfg.FixedStart {
+		if cfg.SegmentStart < load {
+			return 0, 0, fmt.Errorf("%w: start $%X is before the next free address $%X of memory '%s'",
+				errSegmentOverlap, cfg.SegmentStart, load, cfg.Memory.Name)
+		}
+		load = cfg.SegmentStart
+	}
+
+	runMemory, ok := l.memories[cfg.Run]
+	if !ok || cfg.Run == cfg.Memory.Name {
+		load = alignAddress(load, cfg.Align)
+		return load, load, nil
+	}
+
+	run := alignAddress(l.nextAddress(runMemory.Name, runMemory.Start), cfg.Align)
+	return load, run, nil
+}
+
+// finishSegment reserves the space of the placed segment in its load and run memory areas.
+func (l *memoryLayout) finishSegment(cfg *config.Segment, load, run, size uint64) {
+	l.next[cfg.Memory.Name] = load + size
+	if cfg.Run != "" && cfg.Run != cfg.Memory.Name {
+		l.next[cfg.Run] = run + size
+	}
+}
+
+// nextAddress returns the next free address of the memory area.
+func (l *memoryLayout) nextAddress(memoryName string, memoryStart uint64) uint64 {
+	if next, ok := l.next[memoryName]; ok {
+		return next
+	}
+	return memoryStart
+}
+
+// checkSegmentPlacement returns an error if the segment exceeds its load or run memory area.
+func checkSegmentPlacement(memories map[string]*config.Memory, seg *segment) error {
+	cfg := seg.config
+	if err := checkMemoryRange(memories[cfg.Memory.Name], seg.start, seg.size); err != nil {
+		return err
+	}
+	if cfg.Run != "" {
+		if err := checkMemoryRange(memories[cfg.Run], seg.runStart, seg.size); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Typ == config.TypeZeroPage && seg.runStart+seg.size > zeroPageEnd {
+		return fmt.Errorf("%w: zero page segment '%s' ends at $%X",
+			errSegmentOverflow, cfg.SegmentName, seg.runStart+seg.size)
+	}
+	return nil
+}
+
+// checkMemoryRange returns an error if the address range exceeds the memory area.
+func checkMemoryRange(mem *config.Memory, start, size uint64) error {
+	if mem == nil || mem.Size == 0 {
+		return nil
+	}
+	if end := start + size; end > mem.Start+mem.Size {
+		return fmt.Errorf("%w: memory '%s' ends at $%X, segment ends at $%X",
+			errSegmentOverflow, mem.Name, mem.Start+mem.Size, end)
+	}
+	return nil
+}
+
+// alignAddress returns the address rounded up to the next multiple of the alignment.
+func alignAddress(address, alignment uint64) uint64 {
+	if alignment <= 1 {
+		return address
+	}
+	if remainder := address % alignment; remainder != 0 {
+		return address + alignment - remainder
+	}
+	return address
+}
+
+// segmentDefineSymbols returns the names of the symbols that a segment with the define
+// attribute exports for its load address, run address and size.
+func segmentDefineSymbols(segmentName string) (string, string, string) {
+	return "__" + segmentName + "_LOAD__", "__" + segmentName + "_RUN__", "__" + segmentName + "_SIZE__"
+}
+
+// addSegmentDefineSymbols adds the symbols of all configured segments with the define
+// attribute to the scope, their values are set once the segments are placed.
+func addSegmentDefineSymbols(sc *scope.Scope, segments []*config.Segment) error {
+	for _, seg := range segments {
+		if !seg.Define {
+			continue
+		}
+
+		load, run, size := segmentDefineSymbols(seg.SegmentName)
+		for _, name := range []string{load, run, size} {
+			if _, err := scope.NewSymbol(sc, name, scope.LabelType); err != nil {
+				return fmt.Errorf("creating segment symbol: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// setSegmentDefineSymbols sets the values of the symbols of a segment with the define attribute.
+func setSegmentDefineSymbols(sc *scope.Scope, cfg *config.Segment, load, run, size uint64) error {
+	loadName, runName, sizeName := segmentDefineSymbols(cfg.SegmentName)
+	values := map[string]uint64{
+		loadName: load,
+		runName:  run,
+		sizeName: size,
+	}
+
+	for name, value := range values {
+		sym, err := sc.GetSymbol(name)
+		if err != nil {
+			return fmt.Errorf("getting segment symbol: %w", err)
+		}
+		sym.SetAddress(value)
+	}
+	return nil
+}
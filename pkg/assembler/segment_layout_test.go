@@ -0,0 +1,173 @@
+package assembler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/retroenv/retroasm/pkg/arch/m6502"
+	"github.com/retroenv/retrogolib/assert"
+)
+
+func TestAssemblerSegmentLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		code     string
+		expected []byte
+		symbols  map[string]uint64
+	}{
+		{
+			name: "sequential segments",
+			config: `
+MEMORY { PRG: start = $8000, size = $100; }
+SEGMENTS {
+    CODE:   load = PRG, type = ro;
+    RODATA: load = PRG, type = ro;
+}`,
+			code:     ".segment \"RODATA\"\ndata: .byte 1,2\n.segment \"CODE\"\ncode: nop\n",
+			expected: []byte{0xea, 0x01, 0x02},
+			symbols:  map[string]uint64{"code": 0x8000, "data": 0x8001},
+		},
+		{
+			name: "align and offset",
+			config: `
+MEMORY { PRG: start = $8000, size = $100; }
+SEGMENTS {
+    CODE:   load = PRG, type = ro;
+    RODATA: load = PRG, type = ro, align = $10;
+    TABLE:  load = PRG, type = ro, offset = $20;
+}`,
+			code:     ".segment \"CODE\"\nnop\n.segment \"RODATA\"\ndata: .byte 1\n.segment \"TABLE\"\ntable: .byte 2\n",
+			expected: append(append([]byte{0xea}, make([]byte, 15)...), append([]byte{0x01}, append(make([]byte, 15), 0x02)...)...),
+			symbols:  map[string]uint64{"data": 0x8010, "table": 0x8020},
+		},
+		{
+			name: "run address and define",
+			config: `
+MEMORY {
+    PRG: start = $8000, size = $100;
+    RAM: start = $0300, size = $100;
+}
+SEGMENTS {
+    CODE: load = PRG, type = ro;
+    DATA: load = PRG, run = RAM, type = rw, define = yes;
+}`,
+			code: `.segment "CODE"
+lda #<__DATA_LOAD__
+ldx #__DATA_SIZE__
+.segment "DATA"
+value: .byte 7, 8
+`,
+			expected: []byte{0xa9, 0x04, 0xa2, 0x02, 0x07, 0x08},
+			symbols: map[string]uint64{
+				"value":         0x0300,
+				"__DATA_LOAD__": 0x8004,
+				"__DATA_RUN__":  0x0300,
+				"__DATA_SIZE__": 2,
+			},
+		},
+		{
+			name: "bss emits no bytes",
+			config: `
+MEMORY {
+    RAM: start = $0300, size = $100;
+    PRG: start = $8000, size = $100;
+}
+SEGMENTS {
+    BSS:  load = RAM, type = bss;
+    CODE: load = PRG, type = ro;
+}`,
+			code:     ".segment \"BSS\"\nbuffer: .res 4\ncounter: .res 1\n.segment \"CODE\"\nlda counter\n",
+			expected: []byte{0xad, 0x04, 0x03},
+			symbols:  map[string]uint64{"buffer": 0x0300, "counter": 0x0304},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := m6502.New()
+			assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(tt.config)))
+
+			var buf bytes.Buffer
+			asm := New(cfg, &buf)
+			assert.NoError(t, asm.Process(t.Context(), strings.NewReader(tt.code)))
+			assert.Equal(t, tt.expected, buf.Bytes())
+
+			symbols := map[string]uint64{}
+			for _, sym := range asm.DefinedSymbols() {
+				symbols[sym.Name] = sym.Value
+			}
+			for name, value := range tt.symbols {
+				assert.Equal(t, value, symbols[name], name)
+			}
+		})
+	}
+}
+
+func TestAssemblerSegmentLayoutErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		code     string
+		expected error
+	}{
+		{
+			name: "missing segment",
+			config: `
+MEMORY { PRG: start = $8000, size = $100; }
+SEGMENTS {
+    CODE:    load = PRG, type = ro;
+    VECTORS: load = PRG, type = ro;
+}`,
+			code:     ".segment \"CODE\"\nnop\n",
+			expected: errSegmentMissing,
+		},
+		{
+			name: "overlapping start",
+			config: `
+MEMORY { PRG: start = $8000, size = $100; }
+SEGMENTS {
+    CODE:   load = PRG, type = ro;
+    RODATA: load = PRG, type = ro, start = $8001;
+}`,
+			code:     ".segment \"CODE\"\nlda #1\n.segment \"RODATA\"\n.byte 1\n",
+			expected: errSegmentOverlap,
+		},
+		{
+			name: "memory overflow",
+			config: `
+MEMORY { PRG: start = $8000, size = $2; }
+SEGMENTS { CODE: load = PRG, type = ro; }`,
+			code:     ".segment \"CODE\"\nlda $1234\n",
+			expected: errSegmentOverflow,
+		},
+		{
+			name: "zero page overflow",
+			config: `
+MEMORY { ZP: start = $00, size = $200; }
+SEGMENTS { ZEROPAGE: load = ZP, type = zp; }`,
+			code:     ".segment \"ZEROPAGE\"\n.res $101\n",
+			expected: errSegmentOverflow,
+		},
+		{
+			name: "initialized data in bss",
+			config: `
+MEMORY { RAM: start = $0300, size = $100; }
+SEGMENTS { BSS: load = RAM, type = bss; }`,
+			code:     ".segment \"BSS\"\n.byte 1\n",
+			expected: errInitializedData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := m6502.New()
+			assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(tt.config)))
+
+			var buf bytes.Buffer
+			asm := New(cfg, &buf)
+			assert.ErrorIs(t, asm.Process(t.Context(), strings.NewReader(tt.code)), tt.expected)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package assembler
+
+import "slices"
+
+// SegmentUsage describes the placement and content of a segment in the assembled program.
+type SegmentUsage struct {
+	Name           string
+	Memory         string // name of the memory area that the segment is loaded into
+	Start          uint64 // address of the first byte of the segment
+	Size           uint64 // number of bytes used by the segment, including gaps and reserved space
+	ConfiguredSize uint64 // size that is available to the segment in its memory area
+	Data           []byte // emitted bytes, gaps are filled like in the output memory
+
+	InOutput   bool   // whether the segment is part of an output file
+	OutputFile string // name of the additional output file of the segment, empty for the main output
+	FileOffset uint64 // offset of Start in the output file, only set if InOutput is set
+}
+
+// SegmentUsage returns the placement and content of all segments that the assembled
+// program uses, in the order of their first usage. Call this after ProcessAST or Process.
+func (asm *Assembler[T]) SegmentUsage() []SegmentUsage {
+	usage := make([]SegmentUsage, 0, len(asm.segmentsOrder))
+	for _, seg := range asm.segmentsOrder {
+		segUsage := seg.usage()
+		file, ok := asm.memoryFiles[seg.config.Memory.Name]
+		if ok && !seg.config.Uninitialized() {
+			// the output of a memory starts with the byte at its start address
+			segUsage.InOutput = true
+			segUsage.OutputFile = file.name
+			segUsage.FileOffset = file.offset + seg.loadAddress(segUsage.Start) - seg.config.Start
+		}
+		usage = append(usage, segUsage)
+	}
+	return usage
+}
+
+// segmentChunk is a block of bytes at an address of a segment.
+type segmentChunk struct {
+	address uint64
+	size    uint64
+	data    []byte // can be nil for reserved space
+}
+
+// usage returns the placement and emitted bytes of the segment.
+func (seg *segment) usage() SegmentUsage {
+	usage := SegmentUsage{
+		Name:           seg.config.SegmentName,
+		Memory:         seg.config.Memory.Name,
+		Start:          seg.runStart,
+		ConfiguredSize: seg.config.Size,
+	}
+
+	chunks := seg.chunks()
+	if len(chunks) == 0 {
+		return usage
+	}
+
+	start, end := chunks[0].address, uint64(0)
+	for _, chunk := range chunks {
+		start = min(start, chunk.address)
+		end = max(end, chunk.address+chunk.size)
+	}
+
+	var fillValue byte
+	if seg.config.Fill {
+		fillValue = seg.config.FillValue
+	}
+	data := slices.Repeat([]byte{fillValue}, int(end-start))
+	for _, chunk := range chunks {
+		copy(data[chunk.address-start:], chunk.data)
+	}
+
+	usage.Start = start
+	usage.Size = end - start
+	usage.Data = data
+	return usage
+}
+
+// chunks returns all blocks of emitted bytes or reserved space of the segment.
+func (seg *segment) chunks() []segmentChunk {
+	var chunks []segmentChunk
+
+	for _, node := range seg.nodes {
+		switch n := node.(type) {
+		case *data:
+			var b []byte
+			for _, val := range n.values {
+				if v, ok := val.([]byte); ok {
+					b = append(b, v...)
+				}
+			}
+			if len(b) > 0 {
+				chunks = append(chunks, segmentChunk{address: n.address, size: uint64(len(b)), data: b})
+			}
+
+		case *instruction:
+			if len(n.opcodes) > 0 {
+				chunks = append(chunks, segmentChunk{address: n.address, size: uint64(len(n.opcodes)), data: n.opcodes})
+			}
+
+		case *variable:
+			if !n.v.UseOffsetCounter && n.v.Size > 0 {
+				chunks = append(chunks, segmentChunk{address: n.address, size: uint64(n.v.Size)})
+			}
+		}
+	}
+
+	return chunks
+}
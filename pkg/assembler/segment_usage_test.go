@@ -0,0 +1,74 @@
+package assembler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/retroenv/retroasm/pkg/arch/m6502"
+	"github.com/retroenv/retrogolib/assert"
+)
+
+var segmentUsageTestConfig = `
+MEMORY {
+    ZP:     start = $0000,  size = $0100;
+    PRG:    start = $8000,  size = $4000;
+    VEC:    start = $FFFA,  size = $0006;
+}
+
+SEGMENTS {
+    ZEROPAGE: load = ZP,  type = zp;
+    CODE:     load = PRG, type = ro;
+    VECTORS:  load = VEC, type = ro;
+}
+`
+
+var segmentUsageTestCode = `
+.segment "ZEROPAGE"
+.res 2
+
+.segment "CODE"
+reset:
+  lda #1
+  rts
+
+.segment "VECTORS"
+.dw reset, reset, reset
+`
+
+func TestAssemblerSegmentUsage(t *testing.T) {
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(segmentUsageTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	assert.NoError(t, asm.Process(t.Context(), strings.NewReader(segmentUsageTestCode)))
+
+	usage := asm.SegmentUsage()
+	assert.Len(t, usage, 3)
+
+	assert.Equal(t, SegmentUsage{
+		Name:           "ZEROPAGE",
+		Memory:         "ZP",
+		Start:          0,
+		Size:           2,
+		ConfiguredSize: 0x100,
+		Data:           []byte{0, 0},
+	}, usage[0])
+
+	assert.Equal(t, SegmentUsage{
+		Name:           "CODE",
+		Memory:         "PRG",
+		Start:          0x8000,
+		Size:           3,
+		ConfiguredSize: 0x4000,
+		Data:           []byte{0xa9, 0x01, 0x60},
+		InOutput:       true,
+	}, usage[1])
+
+	assert.Equal(t, "VECTORS", usage[2].Name)
+	assert.Equal(t, 0xfffa, usage[2].Start)
+	assert.Equal(t, []byte{0x00, 0x80, 0x00, 0x80, 0x00, 0x80}, usage[2].Data)
+	assert.True(t, usage[2].InOutput)
+	assert.Equal(t, 3, usage[2].FileOffset)
+}
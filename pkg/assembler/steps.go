@@ -4,11 +4,24 @@ import "context"
 
 // Steps of the assembler to execute, in order.
 func (asm *Assembler[T]) Steps() []step[T] {
-	return []step[T]{
+	steps := []step[T]{
 		{
 			handler:       processMacrosStep[T],
 			errorTemplate: "processing macros",
 		},
+		{
+			handler:       resolveSymbolVisibilityStep[T],
+			errorTemplate: "resolving symbol visibility",
+		},
+	}
+	if asm.object != nil && asm.autoImport {
+		steps = append(steps, step[T]{
+			handler:       addObjectImportsStep[T],
+			errorTemplate: "adding imports",
+		})
+	}
+
+	steps = append(steps, []step[T]{
 		{
 			handler:       evaluateExpressionsStep[T],
 			errorTemplate: "evaluating expressions",
@@ -25,11 +38,18 @@ func (asm *Assembler[T]) Steps() []step[T] {
 			handler:       generateOpcodesStep[T],
 			errorTemplate: "generating opcodes",
 		},
-		{
-			handler:       writeOutputStep[T],
-			errorTemplate: "writing output",
-		},
+	}...)
+
+	if asm.object != nil {
+		return append(steps, step[T]{
+			handler:       writeObjectStep[T],
+			errorTemplate: "writing object",
+		})
 	}
+	return append(steps, step[T]{
+		handler:       writeOutputStep[T],
+		errorTemplate: "writing output",
+	})
 }
 
 type step[T any] struct {
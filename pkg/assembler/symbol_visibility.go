@@ -0,0 +1,124 @@
+package assembler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/retroenv/retroasm/pkg/lexer/token"
+	"github.com/retroenv/retroasm/pkg/parser/ast"
+	"github.com/retroenv/retroasm/pkg/scope"
+)
+
+var (
+	errExportUndefined = errors.New("exported symbol is not defined")
+	errImportDefined   = errors.New("imported symbol is also defined by the file")
+)
+
+// visibilityDeclaration is a symbol of a .export, .import or .global directive.
+type visibilityDeclaration struct {
+	name      string
+	typ       ast.VisibilityType
+	zeroPage  bool
+	scope     *scope.Scope // scope of the declaration, used to resolve the symbol name
+	fileScope *scope.Scope // scope of the source file of the declaration
+	position  token.Position
+}
+
+func parseSymbolVisibility[T any](asm *parseAST[T], visibility ast.SymbolVisibility) {
+	for _, name := range visibility.Names {
+		asm.declarations = append(asm.declarations, visibilityDeclaration{
+			name:      name,
+			typ:       visibility.Type,
+			zeroPage:  visibility.ZeroPage,
+			scope:     asm.currentScope,
+			fileScope: asm.fileScope,
+			position:  visibility.Position(),
+		})
+	}
+}
+
+// resolveSymbolVisibilityStep applies the declarations of the symbol visibility directives
+// once all symbols of the file are defined. Exported symbols have to be defined by the
+// file, imported symbols have to be defined by another file or the configuration.
+// In object mode, imported symbols are created as import symbols that the linker resolves.
+func resolveSymbolVisibilityStep[T any](_ context.Context, asm *Assembler[T]) error {
+	// the types of .global declarations depend on the symbols that the files define,
+	// they are determined before the exports of all files are resolved
+	types := make([]ast.VisibilityType, len(asm.declarations))
+	for i, decl := range asm.declarations {
+		types[i] = declarationType(decl)
+	}
+
+	var errs []error
+	for _, exports := range []bool{true, false} {
+		for i, decl := range asm.declarations {
+			if (types[i] == ast.VisibilityExport) != exports {
+				continue
+			}
+			if err := resolveVisibilityDeclaration(asm, decl, types[i]); err != nil {
+				errs = append(errs, &Error{position: decl.position, err: err})
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// declarationType returns the visibility type of the declaration, a .global declaration
+// exports the symbol if it is defined and imports it otherwise.
+func declarationType(decl visibilityDeclaration) ast.VisibilityType {
+	if decl.typ != ast.VisibilityGlobal {
+		return decl.typ
+	}
+	if _, err := decl.scope.GetSymbol(decl.name); err == nil {
+		return ast.VisibilityExport
+	}
+	return ast.VisibilityImport
+}
+
+// resolveVisibilityDeclaration applies the visibility declaration of a symbol.
+func resolveVisibilityDeclaration[T any](asm *Assembler[T], decl visibilityDeclaration,
+	typ ast.VisibilityType) error {
+
+	sym, err := decl.scope.GetSymbol(decl.name)
+	defined := err == nil
+	if defined && asm.object != nil && asm.object.isImport(sym) {
+		// the symbol was already imported by a previous declaration
+		sym.SetZeroPage(sym.ZeroPage() || decl.zeroPage)
+		return nil
+	}
+
+	switch {
+	case typ == ast.VisibilityExport:
+		if !defined {
+			return fmt.Errorf("%w: '%s'", errExportUndefined, decl.name)
+		}
+		sym.SetVisibility(scope.ExportVisibility)
+		if decl.fileScope != asm.fileScope {
+			// the file is part of a program of multiple files that share the global scope
+			if err := exportSymbol(asm.fileScope, decl.scope, sym); err != nil {
+				return err
+			}
+		}
+
+	case asm.object != nil:
+		if defined {
+			return fmt.Errorf("%w: '%s'", errImportDefined, decl.name)
+		}
+		sym, err = asm.object.addImport(asm.fileScope, decl.name)
+		if err != nil {
+			return err
+		}
+
+	default:
+		// symbols of the configuration or other files are resolved when the file is assembled
+		if !defined {
+			return fmt.Errorf("%w: '%s'", errUnresolvedImport, decl.name)
+		}
+	}
+
+	if decl.zeroPage {
+		sym.SetZeroPage(true)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package assembler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/retroenv/retroasm/pkg/arch/m6502"
+	"github.com/retroenv/retroasm/pkg/scope"
+	"github.com/retroenv/retrogolib/assert"
+)
+
+var visibilityTestConfig = `
+MEMORY { PRG: start = $8000, size = $100; }
+SEGMENTS { CODE: load = PRG, type = ro, define = yes; }
+`
+
+func TestAssemblerSymbolVisibility(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected []byte
+		err      error
+	}{
+		{
+			name:     "export defined symbols",
+			code:     ".segment \"CODE\"\n.export main, value\nvalue = 1\nmain: lda #value\n",
+			expected: []byte{0xa9, 0x01},
+		},
+		{
+			name:     "global defined symbol",
+			code:     ".segment \"CODE\"\n.global main\nmain: nop\n",
+			expected: []byte{0xea},
+		},
+		{
+			name:     "import linker symbol",
+			code:     ".segment \"CODE\"\n.import __CODE_LOAD__\nlda __CODE_LOAD__\n",
+			expected: []byte{0xad, 0x00, 0x80},
+		},
+		{
+			name: "export undefined symbol",
+			code: ".segment \"CODE\"\n.export main\nnop\n",
+			err:  errExportUndefined,
+		},
+		{
+			name: "import undefined symbol",
+			code: ".segment \"CODE\"\n.importzp ptr\nlda ptr\n",
+			err:  errUnresolvedImport,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := m6502.New()
+			assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(visibilityTestConfig)))
+
+			var buf bytes.Buffer
+			asm := New(cfg, &buf)
+			err := asm.Process(t.Context(), strings.NewReader(tt.code))
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.Bytes())
+		})
+	}
+}
+
+func TestAssemblerExportVisibility(t *testing.T) {
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(visibilityTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	code := ".segment \"CODE\"\n.exportzp ptr\nptr = $10\nlocal = $20\n"
+	assert.NoError(t, asm.Process(t.Context(), strings.NewReader(code)))
+
+	ptr, err := asm.fileScope.GetSymbol("ptr")
+	assert.NoError(t, err)
+	assert.Equal(t, scope.ExportVisibility, ptr.Visibility())
+	assert.True(t, ptr.ZeroPage())
+
+	local, err := asm.fileScope.GetSymbol("local")
+	assert.NoError(t, err)
+	assert.Equal(t, scope.LocalVisibility, local.Visibility())
+}
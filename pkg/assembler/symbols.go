@@ -0,0 +1,107 @@
+package assembler
+
+import (
+	"slices"
+
+	"github.com/retroenv/retroasm/pkg/lexer/token"
+	"github.com/retroenv/retroasm/pkg/scope"
+)
+
+// scopeSeparator separates the names of nested scopes in qualified symbol names.
+const scopeSeparator = "::"
+
+// Symbol describes a symbol that is defined by the assembled program.
+type Symbol struct {
+	Name     string // qualified name, symbols of named scopes are prefixed by the scope name
+	Value    uint64
+	Type     scope.SymbolType
+	Segment  string // name of the segment containing the symbol, empty for constants
+	Position token.Position
+
+	InOutput   bool   // whether the symbol is placed at a location of an output file
+	FileOffset uint64 // offset of the symbol in the output file of its segment, only set if InOutput is set
+}
+
+// DefinedSymbols returns all symbols that were defined by the assembled program,
+// including the symbols of nested function and named scopes.
+// Call this after ProcessAST or Process. Symbols that do not resolve to a number,
+// for example labels inside a conditional block that was not assembled, are skipped.
+func (asm *Assembler[T]) DefinedSymbols() []Symbol {
+	symbols := asm.appendScopeSymbols(nil, asm.fileScope, "")
+	if asm.object == nil {
+		return symbols
+	}
+
+	// imported symbols are defined by other object files
+	return slices.DeleteFunc(symbols, func(sym Symbol) bool {
+		_, ok := asm.object.imports[sym.Name]
+		return ok
+	})
+}
+
+// appendScopeSymbols appends all symbols of the given scope and its child scopes to the list.
+func (asm *Assembler[T]) appendScopeSymbols(symbols []Symbol, sc *scope.Scope, prefix string) []Symbol {
+	for _, sym := range sc.Symbols() {
+		value, ok := symbolNumericValue(sc, sym)
+		if !ok {
+			continue
+		}
+
+		fileOffset, inOutput := asm.symbolFileOffset(sym)
+		symbols = append(symbols, Symbol{
+			Name:       prefix + sym.Name(),
+			Value:      value,
+			Type:       sym.Type(),
+			Segment:    sym.Segment(),
+			Position:   sym.Position(),
+			InOutput:   inOutput,
+			FileOffset: fileOffset,
+		})
+	}
+
+	for _, child := range sc.Children() {
+		childPrefix := prefix
+		if name := child.Name(); name != "" {
+			childPrefix += name + scopeSeparator
+		}
+		symbols = asm.appendScopeSymbols(symbols, child, childPrefix)
+	}
+
+	return symbols
+}
+
+// symbolFileOffset returns the offset of the symbol in the output file of its segment
+// and whether the symbol is placed in an output file. The offset is based on the load
+// address of the symbol, which includes the bank of banked code.
+func (asm *Assembler[T]) symbolFileOffset(sym *scope.Symbol) (uint64, bool) {
+	loadAddress, ok := sym.LoadAddress()
+	if !ok {
+		return 0, false
+	}
+	seg, ok := asm.segments[sym.Segment()]
+	if !ok || seg.config.Uninitialized() {
+		return 0, false
+	}
+	file, ok := asm.memoryFiles[seg.config.Memory.Name]
+	if !ok {
+		return 0, false
+	}
+	return file.offset + loadAddress - seg.config.Start, true
+}
+
+// symbolNumericValue returns the value of the symbol if it resolves to a number.
+func symbolNumericValue(sc *scope.Scope, sym *scope.Symbol) (uint64, bool) {
+	value, err := sym.Value(sc)
+	if err != nil {
+		return 0, false
+	}
+
+	switch v := value.(type) {
+	case int64:
+		return uint64(v), true
+	case uint64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
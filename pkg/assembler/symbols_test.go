@@ -0,0 +1,89 @@
+package assembler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/retroenv/retroasm/pkg/arch/m6502"
+	"github.com/retroenv/retroasm/pkg/scope"
+	"github.com/retroenv/retrogolib/assert"
+)
+
+var definedSymbolsTestCode = `
+.segment "HEADER"
+count = 2
+limit EQU 3
+start:
+  nop
+.proc main
+loop:
+  jmp loop
+.endproc
+.ifdef undefined
+skipped:
+.endif
+`
+
+func TestAssemblerDefinedSymbols(t *testing.T) {
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(unitTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	assert.NoError(t, asm.Process(t.Context(), strings.NewReader(definedSymbolsTestCode)))
+
+	symbols := map[string]Symbol{}
+	for _, sym := range asm.DefinedSymbols() {
+		symbols[sym.Name] = sym
+	}
+	assert.Len(t, symbols, 5)
+
+	assert.Equal(t, 2, symbols["count"].Value)
+	assert.Equal(t, scope.AliasType, symbols["count"].Type)
+	assert.Equal(t, "", symbols["count"].Segment)
+
+	assert.Equal(t, 3, symbols["limit"].Value)
+	assert.Equal(t, scope.EquType, symbols["limit"].Type)
+
+	assert.Equal(t, 0, symbols["start"].Value)
+	assert.Equal(t, scope.LabelType, symbols["start"].Type)
+	assert.Equal(t, "HEADER", symbols["start"].Segment)
+	assert.Equal(t, 5, symbols["start"].Position.Line)
+
+	assert.Equal(t, 1, symbols["main"].Value)
+	assert.Equal(t, scope.FunctionType, symbols["main"].Type)
+
+	assert.Equal(t, 1, symbols["main::loop"].Value)
+	assert.Equal(t, scope.LabelType, symbols["main::loop"].Type)
+	assert.Equal(t, "HEADER", symbols["main::loop"].Segment)
+	assert.Equal(t, 8, symbols["main::loop"].Position.Line)
+}
+
+var nesasmVariableTestCode = `
+.segment "HEADER"
+  .rsset $0010
+ptr .rs 2
+counter .rs 1
+  lda counter
+`
+
+func TestAssemblerNesasmVariables(t *testing.T) {
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(unitTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	assert.NoError(t, asm.Process(t.Context(), strings.NewReader(nesasmVariableTestCode)))
+	assert.Equal(t, []byte{0xa5, 0x12}, buf.Bytes())
+
+	symbols := asm.DefinedSymbols()
+	assert.Len(t, symbols, 2)
+	assert.Equal(t, "counter", symbols[0].Name)
+	assert.Equal(t, 0x12, symbols[0].Value)
+	assert.Equal(t, scope.VariableType, symbols[0].Type)
+	assert.Equal(t, "HEADER", symbols[0].Segment)
+	assert.Equal(t, "ptr", symbols[1].Name)
+	assert.Equal(t, 0x10, symbols[1].Value)
+	assert.Equal(t, "HEADER", symbols[1].Segment)
+}
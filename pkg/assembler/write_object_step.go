@@ -0,0 +1,432 @@
+package assembler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/retroenv/retroasm/pkg/expression"
+	"github.com/retroenv/retroasm/pkg/lexer/token"
+	"github.com/retroenv/retroasm/pkg/scope"
+	"github.com/retroenv/retrogolib/set"
+)
+
+var (
+	errObjectRelativeRelocation = errors.New("relative branch target has to be in the same segment of the object file")
+	errObjectSegmentAddress     = errors.New("data outside of the segment start and end can not be relocated")
+	errObjectSymbolValue        = errors.New("symbol value can not be stored in an object file")
+)
+
+// objectWriter converts the assembled segments and symbols to an object file.
+type objectWriter struct {
+	state     *objectState
+	fileScope *scope.Scope
+
+	scopes         map[*scope.Scope]int       // maps scope to its index in the object file
+	symbolSegments map[*scope.Symbol]*segment // maps symbols with an address in a segment to the segment
+	usedImports    set.Set[string]
+	pcSegments     set.Set[string] // segments whose program counter is referenced by a relocation
+}
+
+// placement describes how a value depends on the placement of the segment fragments.
+type placement struct {
+	segment  *segment // the only segment that the value depends on
+	external bool     // the value depends on imported symbols or several segments
+}
+
+// writeObjectStep writes the assembled program as relocatable object file to the output stream.
+func writeObjectStep[T any](_ context.Context, asm *Assembler[T]) error {
+	w := &objectWriter{
+		state:          asm.object,
+		fileScope:      asm.fileScope,
+		scopes:         map[*scope.Scope]int{},
+		symbolSegments: map[*scope.Symbol]*segment{},
+		usedImports:    set.New[string](),
+		pcSegments:     set.New[string](),
+	}
+
+	obj := &Object{
+		Format:  objectFormat,
+		Version: objectVersion,
+		Source:  asm.sourceName,
+	}
+	obj.Scopes = w.addScopes(nil, asm.fileScope, -1)
+
+	segments, err := w.segments(asm.segmentsOrder)
+	if err != nil {
+		return err
+	}
+	obj.Segments = segments
+
+	symbols, err := w.symbols(asm.fileScope, nil)
+	if err != nil {
+		return err
+	}
+	for _, sym := range symbols {
+		if sym.Scope != 0 {
+			continue
+		}
+		exported, err := asm.fileScope.GetSymbol(sym.Name)
+		if err != nil || exported.Visibility() != scope.ExportVisibility {
+			continue
+		}
+		obj.Exports = append(obj.Exports, sym.Name)
+	}
+	for _, seg := range asm.segmentsOrder {
+		if w.pcSegments.Contains(seg.config.SegmentName) {
+			symbols = append(symbols, ObjectSymbol{
+				Name:    fragmentSymbolName(seg.config.SegmentName),
+				Segment: seg.config.SegmentName,
+			})
+		}
+	}
+	obj.Symbols = symbols
+	obj.Imports = w.usedImports.ToSlice()
+	slices.Sort(obj.Imports)
+
+	for _, entry := range w.state.condes {
+		obj.Condes = append(obj.Condes, ObjectCondes{
+			Name:     entry.name,
+			Scope:    w.scopes[entry.scope],
+			Type:     entry.typ,
+			Priority: entry.priority,
+		})
+	}
+
+	return obj.Write(asm.writer)
+}
+
+// fragmentSymbolName returns the name of the symbol that references the start of the
+// fragment of a segment in an object file.
+func fragmentSymbolName(segmentName string) string {
+	return "__" + segmentName + "_FRAGMENT__"
+}
+
+// addScopes adds the scope and all its child scopes to the list of object scopes.
+func (w *objectWriter) addScopes(scopes []ObjectScope, sc *scope.Scope, parent int) []ObjectScope {
+	w.scopes[sc] = len(scopes)
+	index := len(scopes)
+	scopes = append(scopes, ObjectScope{Name: sc.Name(), Parent: parent})
+
+	for _, child := range sc.Children() {
+		scopes = w.addScopes(scopes, child, index)
+	}
+	return scopes
+}
+
+// segments returns the fragments of all used segments with their relocations.
+func (w *objectWriter) segments(segments []*segment) ([]ObjectSegment, error) {
+	var errs []error
+
+	for _, seg := range segments {
+		for _, node := range seg.nodes {
+			switch n := node.(type) {
+			case *symbol:
+				exp := n.Expression()
+				if typ := n.Type(); typ == scope.LabelType || typ == scope.FunctionType ||
+					(exp != nil && exp.IsEvaluatedAtAddressAssign()) {
+
+					w.symbolSegments[n.Symbol] = seg
+				}
+
+			case *variable:
+				if n.symbol != nil && !n.v.UseOffsetCounter {
+					w.symbolSegments[n.symbol] = seg
+				}
+			}
+		}
+	}
+
+	result := make([]ObjectSegment, 0, len(segments))
+	indexes := map[*segment]int{}
+	for _, seg := range segments {
+		objSeg, err := segmentFragment(seg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("segment '%s': %w", seg.config.SegmentName, err))
+			continue
+		}
+		indexes[seg] = len(result)
+		result = append(result, objSeg)
+	}
+
+	for _, site := range w.state.relocations {
+		reloc, ok, err := w.relocation(site)
+		if err != nil {
+			errs = append(errs, &Error{position: site.position, err: err})
+			continue
+		}
+		if ok {
+			index := indexes[site.segment]
+			result[index].Relocations = append(result[index].Relocations, reloc)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// segmentFragment returns the fragment of the segment, starting at its run address.
+func segmentFragment(seg *segment) (ObjectSegment, error) {
+	objSeg := ObjectSegment{
+		Name: seg.config.SegmentName,
+		Size: seg.size,
+	}
+	if seg.config.Uninitialized() {
+		if errs := checkUninitializedSegment(seg); len(errs) > 0 {
+			return objSeg, errors.Join(errs...)
+		}
+		return objSeg, nil
+	}
+
+	objSeg.Data = make([]byte, seg.size)
+	for _, chunk := range seg.chunks() {
+		if chunk.data == nil {
+			continue
+		}
+		if chunk.address < seg.runStart || chunk.address+chunk.size > seg.runStart+seg.size {
+			return objSeg, fmt.Errorf("%w: address $%X", errObjectSegmentAddress, chunk.address)
+		}
+		copy(objSeg.Data[chunk.address-seg.runStart:], chunk.data)
+	}
+	return objSeg, nil
+}
+
+// relocation returns the relocation of the value and whether the value has to be relocated.
+func (w *objectWriter) relocation(site relocationSite) (Relocation, bool, error) {
+	pl := w.tokensPlacement(site.scope, site.tokens, site.segment, set.New[*scope.Symbol]())
+	if pl.segment == nil && !pl.external {
+		return Relocation{}, false, nil
+	}
+
+	if site.relative {
+		// the distance between two addresses of the same fragment does not change
+		if pl.external || pl.segment != site.segment {
+			return Relocation{}, false, errObjectRelativeRelocation
+		}
+		return Relocation{}, false, nil
+	}
+
+	tokens, err := w.relocationTokens(site.scope, site.tokens, site.segment, site.programCounter)
+	if err != nil {
+		return Relocation{}, false, err
+	}
+
+	return Relocation{
+		Offset:     site.address - site.segment.runStart,
+		Size:       site.size,
+		Scope:      w.scopes[site.scope],
+		Expression: tokens,
+	}, true, nil
+}
+
+// symbols returns the object symbols of the scope and all its child scopes.
+func (w *objectWriter) symbols(sc *scope.Scope, symbols []ObjectSymbol) ([]ObjectSymbol, error) {
+	for _, sym := range sc.Symbols() {
+		if w.state.isImport(sym) {
+			continue
+		}
+
+		objSym, ok, err := w.symbol(sc, sym)
+		if err != nil {
+			return nil, fmt.Errorf("symbol '%s': %w", sym.Name(), err)
+		}
+		if ok {
+			symbols = append(symbols, objSym)
+		}
+	}
+
+	for _, child := range sc.Children() {
+		var err error
+		symbols, err = w.symbols(child, symbols)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return symbols, nil
+}
+
+// symbol returns the object symbol of a symbol and whether the symbol has a value.
+func (w *objectWriter) symbol(sc *scope.Scope, sym *scope.Symbol) (ObjectSymbol, bool, error) {
+	objSym := ObjectSymbol{
+		Name:  sym.Name(),
+		Scope: w.scopes[sc],
+	}
+
+	if seg, ok := w.symbolSegments[sym]; ok {
+		value, ok := symbolNumericValue(sc, sym)
+		if !ok {
+			// symbols that use the program counter can only be evaluated at address assignment
+			exp, isExpression := sym.Expression().(*expression.Expression)
+			if !isExpression {
+				return objSym, false, nil
+			}
+			i, err := exp.IntValue()
+			if err != nil {
+				return objSym, false, nil //nolint:nilerr // symbol of a node that was not assembled
+			}
+			value = uint64(i)
+		}
+
+		objSym.Segment = seg.config.SegmentName
+		objSym.Value = value - seg.runStart
+		return objSym, true, nil
+	}
+
+	exp := sym.Expression()
+	if exp != nil && (sym.Type() == scope.EquType || sym.Type() == scope.AliasType) {
+		pl := w.tokensPlacement(sc, exp.Tokens(), nil, set.New[*scope.Symbol]())
+		if pl.segment != nil || pl.external {
+			tokens, err := w.relocationTokens(sc, exp.Tokens(), nil, 0)
+			if err != nil {
+				return objSym, false, err
+			}
+			objSym.Expression = tokens
+			return objSym, true, nil
+		}
+	}
+
+	value, ok := symbolNumericValue(sc, sym)
+	if !ok {
+		return objSym, false, nil
+	}
+	objSym.Value = value
+	return objSym, true, nil
+}
+
+// symbolPlacement returns how the value of the symbol depends on the placement of fragments.
+func (w *objectWriter) symbolPlacement(sc *scope.Scope, sym *scope.Symbol, visited set.Set[*scope.Symbol]) placement {
+	if w.state.isImport(sym) {
+		return placement{external: true}
+	}
+	if seg, ok := w.symbolSegments[sym]; ok {
+		return placement{segment: seg}
+	}
+
+	exp := sym.Expression()
+	if exp == nil || visited.Contains(sym) {
+		return placement{}
+	}
+	visited.Add(sym)
+	return w.tokensPlacement(sc, exp.Tokens(), nil, visited)
+}
+
+// tokensPlacement returns how the value of the expression tokens depends on the placement
+// of fragments. The program counter references the given segment.
+func (w *objectWriter) tokensPlacement(sc *scope.Scope, tokens []token.Token, seg *segment,
+	visited set.Set[*scope.Symbol]) placement {
+
+	var pl placement
+	for _, tok := range tokens {
+		var tokenPlacement placement
+
+		switch {
+		case tok.Type == token.Number && tok.Value == expression.ProgramCounterReference:
+			tokenPlacement = placement{segment: seg, external: seg == nil}
+
+		case isSymbolToken(tok):
+			sym, err := sc.GetSymbol(tok.Value)
+			if err != nil {
+				continue
+			}
+			tokenPlacement = w.symbolPlacement(sc, sym, visited)
+
+		default:
+			continue
+		}
+
+		pl = pl.combine(tokenPlacement)
+	}
+	return pl
+}
+
+// combine returns the placement of a value that depends on both placements.
+func (p placement) combine(other placement) placement {
+	switch {
+	case p.external || other.external:
+		return placement{external: true}
+	case p.segment == nil:
+		return other
+	case other.segment == nil || other.segment == p.segment:
+		return p
+	default:
+		return placement{external: true}
+	}
+}
+
+// relocationTokens returns the object tokens of an expression. Symbols that do not depend
+// on the placement of fragments are replaced by their values and the program counter
+// is replaced by its offset in the fragment of the segment.
+func (w *objectWriter) relocationTokens(sc *scope.Scope, tokens []token.Token, seg *segment,
+	programCounter uint64) ([]ObjectToken, error) {
+
+	result := make([]token.Token, 0, len(tokens))
+	for _, tok := range tokens {
+		if tok.Type == token.Number && tok.Value == expression.ProgramCounterReference {
+			if seg == nil {
+				return nil, errObjectSymbolValue
+			}
+			w.pcSegments.Add(seg.config.SegmentName)
+			result = append(result,
+				token.Token{Type: token.LeftParentheses},
+				token.Token{Type: token.Identifier, Value: fragmentSymbolName(seg.config.SegmentName)},
+				token.Token{Type: token.Plus},
+				token.Token{Type: token.Number, Value: strconv.FormatUint(programCounter-seg.runStart, 10)},
+				token.Token{Type: token.RightParentheses},
+			)
+			continue
+		}
+
+		if !isSymbolToken(tok) {
+			result = append(result, tok)
+			continue
+		}
+
+		sym, err := sc.GetSymbol(tok.Value)
+		if err != nil {
+			return nil, fmt.Errorf("getting symbol: %w", err)
+		}
+		if w.state.isImport(sym) {
+			w.usedImports.Add(sym.Name())
+		}
+
+		pl := w.symbolPlacement(sc, sym, set.New[*scope.Symbol]())
+		if pl.segment != nil || pl.external {
+			if exp := sym.Expression(); exp != nil && !w.state.isImport(sym) {
+				// mark the imports that the expression of the symbol references as used
+				if _, err := w.relocationTokens(sc, exp.Tokens(), nil, 0); err != nil {
+					return nil, err
+				}
+			}
+			result = append(result, tok)
+			continue
+		}
+
+		valueToken, err := symbolValueToken(sc, sym)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, valueToken)
+	}
+
+	return objectTokens(result), nil
+}
+
+// symbolValueToken returns a number token with the value of the symbol.
+func symbolValueToken(sc *scope.Scope, sym *scope.Symbol) (token.Token, error) {
+	value, err := sym.Value(sc)
+	if err != nil {
+		return token.Token{}, fmt.Errorf("getting symbol '%s' value: %w", sym.Name(), err)
+	}
+
+	tok := token.Token{Type: token.Number}
+	switch v := value.(type) {
+	case int64:
+		tok.Value = strconv.FormatInt(v, 10)
+	case uint64:
+		tok.Value = strconv.FormatUint(v, 10)
+	default:
+		return token.Token{}, fmt.Errorf("%w: '%s' has type %T", errObjectSymbolValue, sym.Name(), value)
+	}
+	return tok, nil
+}
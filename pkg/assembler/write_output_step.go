@@ -2,20 +2,123 @@ package assembler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/retroenv/retroasm/pkg/assembler/config"
+	"github.com/retroenv/retroasm/pkg/parser/ast"
 )
 
 // writeOutputStep writes the filled memory segments to the output stream.
 func writeOutputStep[T any](_ context.Context, asm *Assembler[T]) error {
+	var errs []error
+	for _, seg := range asm.segmentsOrder {
+		if err := checkSegmentPlacement(asm.cfg.Memories, seg); err != nil {
+			errs = append(errs, fmt.Errorf("placing segment '%s': %w", seg.config.SegmentName, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
 	memories, err := writeSegmentsToMemory(asm.cfg.SegmentsOrdered, asm.segments)
 	if err != nil {
 		return fmt.Errorf("writing segments to memory: %w", err)
 	}
 
-	for _, segOrdered := range asm.cfg.SegmentsOrdered {
-		seg, ok := asm.segments[segOrdered.SegmentName]
+	outputs, err := orderedMemoryData(asm.cfg.SegmentsOrdered, asm.segments, memories)
+	if err != nil {
+		return err
+	}
+
+	return asm.writeMemoryOutputs(outputs)
+}
+
+// writeMemoryOutputs writes the memory data to the output files of the memories. Memories
+// of the main output are written to the output stream, all others are collected as
+// additional output files.
+func (asm *Assembler[T]) writeMemoryOutputs(outputs []memoryOutput) error {
+	offsets := map[string]uint64{} // maps output file name to the current offset
+	if asm.inesHeader.used {
+		var buffers [][]byte
+		for _, out := range outputs {
+			if asm.outputFileName(out.file) == "" {
+				buffers = append(buffers, out.data)
+			}
+		}
+
+		header, err := inesHeaderData(&asm.inesHeader, buffers)
+		if err != nil {
+			return fmt.Errorf("generating iNES header: %w", err)
+		}
+		if _, err = asm.writer.Write(header); err != nil {
+			return fmt.Errorf("writing iNES header to output: %w", err)
+		}
+		offsets[""] = uint64(len(header))
+	}
+
+	asm.memoryFiles = map[string]memoryFile{}
+	asm.outputFiles = nil
+	fileIndex := map[string]int{} // maps output file name to its index in the additional output files
+
+	for _, out := range outputs {
+		if out.file == "" {
+			continue // the memory is not written to any file
+		}
+
+		name := asm.outputFileName(out.file)
+		if name == "" {
+			if _, err := asm.writer.Write(out.data); err != nil {
+				return fmt.Errorf("writing fill data to output: %w", err)
+			}
+		} else {
+			i, ok := fileIndex[name]
+			if !ok {
+				i = len(asm.outputFiles)
+				fileIndex[name] = i
+				asm.outputFiles = append(asm.outputFiles, OutputFile{Name: name})
+			}
+			asm.outputFiles[i].Data = append(asm.outputFiles[i].Data, out.data...)
+		}
+
+		if len(out.data) > 0 {
+			asm.memoryFiles[out.name] = memoryFile{name: name, offset: offsets[name]}
+		}
+		offsets[name] += uint64(len(out.data))
+	}
+
+	return nil
+}
+
+// outputFileName returns the name of the output file of a memory file attribute with the
+// %O output name reference expanded. The name is empty for the main output.
+func (asm *Assembler[T]) outputFileName(file string) string {
+	if file == config.OutputNameReference {
+		return ""
+	}
+	name := strings.ReplaceAll(file, config.OutputNameReference, asm.outputName)
+	if name == asm.outputName {
+		return ""
+	}
+	return name
+}
+
+// memoryOutput is the data of a memory area that is written to an output file.
+type memoryOutput struct {
+	name string // name of the memory area
+	file string // configured output file name of the memory area
+	data []byte
+}
+
+// orderedMemoryData returns the data of all used memories in the order
+// of the first segment referencing them.
+func orderedMemoryData(configSegmentsOrdered []*config.Segment, segments map[string]*segment,
+	memories map[string]*memory) ([]memoryOutput, error) {
+
+	var outputs []memoryOutput
+	for _, segOrdered := range configSegmentsOrdered {
+		seg, ok := segments[segOrdered.SegmentName]
 		if !ok {
 			continue
 		}
@@ -27,34 +130,55 @@ func writeOutputStep[T any](_ context.Context, asm *Assembler[T]) error {
 			continue
 		}
 
-		dataLen := uint64(len(mem.data))
-		if dataLen-mem.start > mem.size {
-			return fmt.Errorf("memory '%s' exceeds size limit %d, %d bytes written",
-				memName, mem.size, len(mem.data))
+		out := memoryOutput{
+			name: memName,
+			file: mem.file,
 		}
-
-		buf := mem.data[mem.start:]
-		_, err = asm.writer.Write(buf)
-		if err != nil {
-			return fmt.Errorf("writing fill data to output: %w", err)
+		dataLen := uint64(len(mem.data))
+		if dataLen > mem.start {
+			if dataLen-mem.start > mem.size {
+				return nil, fmt.Errorf("memory '%s' exceeds size limit %d, %d bytes written",
+					memName, mem.size, len(mem.data))
+			}
+			out.data = mem.data[mem.start:]
 		}
 
+		outputs = append(outputs, out)
 		delete(memories, memName)
 	}
 
-	return nil
+	return outputs, nil
+}
+
+// inesHeaderData validates the header configuration against the data that
+// follows the header and returns the encoded header.
+func inesHeaderData(header *inesHeader, buffers [][]byte) ([]byte, error) {
+	var dataSize uint64
+	for _, buf := range buffers {
+		dataSize += uint64(len(buf))
+	}
+
+	if err := header.validate(dataSize); err != nil {
+		return nil, err
+	}
+	return header.bytes()
 }
 
 func writeSegmentsToMemory(configSegmentsOrdered []*config.Segment,
 	segments map[string]*segment) (map[string]*memory, error) {
 
 	memories := map[string]*memory{}
+	var errs []error
 
 	for _, segOrdered := range configSegmentsOrdered {
 		seg, ok := segments[segOrdered.SegmentName]
 		if !ok {
 			continue
 		}
+		if seg.config.Uninitialized() {
+			errs = append(errs, checkUninitializedSegment(seg)...)
+			continue
+		}
 
 		memName := seg.config.Memory.Name
 		mem, ok := memories[memName]
@@ -63,24 +187,51 @@ func writeSegmentsToMemory(configSegmentsOrdered []*config.Segment,
 			memories[memName] = mem
 		}
 
-		for _, node := range seg.nodes {
-			switch n := node.(type) {
-			case *data:
-				offset := n.address
-				for _, val := range n.values {
-					b, ok := val.([]byte)
-					if !ok {
-						return nil, fmt.Errorf("unsupported node value type %T", val)
-					}
-					mem.write(b, offset, seg.config.SegmentStart)
-					offset += uint64(len(b))
-				}
+		if err := writeSegmentNodes(mem, seg); err != nil {
+			return nil, err
+		}
+	}
 
-			case *instruction:
-				mem.write(n.opcodes, n.address, seg.config.SegmentStart)
+	return memories, errors.Join(errs...)
+}
+
+// writeSegmentNodes writes the emitted bytes of all nodes of the segment into the memory.
+func writeSegmentNodes(mem *memory, seg *segment) error {
+	var bank bankSelection // the bank numbers were validated when addresses were assigned
+
+	for _, node := range seg.nodes {
+		switch n := node.(type) {
+		case ast.Bank:
+			bank.active = true
+			bank.number = uint64(n.Number)
+
+		case *data:
+			offset := bank.loadAddress(seg, n.address)
+			for _, val := range n.values {
+				b, ok := val.([]byte)
+				if !ok {
+					return fmt.Errorf("unsupported node value type %T", val)
+				}
+				mem.write(b, offset)
+				offset += uint64(len(b))
 			}
+
+		case *instruction:
+			mem.write(n.opcodes, bank.loadAddress(seg, n.address))
 		}
 	}
+	return nil
+}
 
-	return memories, nil
+// checkUninitializedSegment returns an error for every node of a bss or zp segment
+// that emits bytes, these segments can only reserve space.
+func checkUninitializedSegment(seg *segment) []error {
+	var errs []error
+	for _, node := range seg.nodes {
+		switch n := node.(type) {
+		case *data, *instruction:
+			errs = append(errs, nodeError(n, fmt.Errorf("%w: segment '%s'", errInitializedData, seg.config.SegmentName)))
+		}
+	}
+	return errs
 }
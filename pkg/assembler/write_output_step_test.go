@@ -0,0 +1,66 @@
+package assembler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/retroenv/retroasm/pkg/arch/m6502"
+	"github.com/retroenv/retrogolib/assert"
+)
+
+var outputFilesTestConfig = `
+MEMORY {
+    PRG:  start = $8000, size = $4;
+    CHR:  start = $0000, size = $2, file = "%O.chr";
+    SAVE: start = $6000, size = $2, file = "save.bin";
+    RAM:  start = $0300, size = $2, file = "";
+    PRG2: start = $c000, size = $2, file = %O;
+}
+SEGMENTS {
+    CODE:   load = PRG, type = ro;
+    CHARS:  load = CHR, type = ro;
+    SRAM:   load = SAVE, type = rw;
+    DATA:   load = RAM, type = rw;
+    BANK2:  load = PRG2, type = ro;
+}
+`
+
+var outputFilesTestCode = `.segment "CODE"
+nop
+nop
+.segment "CHARS"
+.byte 1, 2
+.segment "SRAM"
+.byte 3
+.segment "DATA"
+.byte 4
+.segment "BANK2"
+bank2: .byte 5
+`
+
+func TestAssemblerOutputFiles(t *testing.T) {
+	cfg := m6502.New()
+	assert.NoError(t, cfg.ReadCa65Config(strings.NewReader(outputFilesTestConfig)))
+
+	var buf bytes.Buffer
+	asm := New(cfg, &buf)
+	asm.SetOutputName("game.nes")
+	assert.NoError(t, asm.Process(t.Context(), strings.NewReader(outputFilesTestCode)))
+
+	assert.Equal(t, []byte{0xea, 0xea, 0x05}, buf.Bytes())
+	assert.Equal(t, []OutputFile{
+		{Name: "game.nes.chr", Data: []byte{1, 2}},
+		{Name: "save.bin", Data: []byte{3}},
+	}, asm.OutputFiles())
+
+	files := map[string]SegmentUsage{}
+	for _, usage := range asm.SegmentUsage() {
+		files[usage.Name] = usage
+	}
+	assert.Equal(t, "", files["CODE"].OutputFile)
+	assert.Equal(t, "game.nes.chr", files["CHARS"].OutputFile)
+	assert.False(t, files["DATA"].InOutput)
+	assert.True(t, files["BANK2"].InOutput)
+	assert.Equal(t, uint64(2), files["BANK2"].FileOffset)
+}
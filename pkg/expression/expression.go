@@ -199,7 +199,7 @@ func (e *Expression) evaluate(scope *scope.Scope, dataWidth int, programCounter
 //nolint:funlen,cyclop // Shunting Yard algorithm with one case per token type
 func parseToRPN(scope *scope.Scope, nodes []token.Token, programCounter uint64) ([]token.Token, error) {
 	// x816 uses the comparison tokens as unary byte selectors when an operand is expected.
-	nodes = normalizeUnaryAddressOperators(nodes)
+	nodes = normalizeUnaryAddressOperators(scope, nodes)
 
 	values := &stack[token.Token]{}
 	operators := &stack[token.Token]{}
@@ -275,8 +275,9 @@ func flushRPNOperators(values, operators *stack[token.Token]) {
 }
 
 // normalizeUnaryAddressOperators rewrites x816 low, high, and bank selectors
-// without changing binary comparison operators that use the same tokens.
-func normalizeUnaryAddressOperators(nodes []token.Token) []token.Token {
+// without changing binary comparison operators that use the same tokens. The bank
+// selector of a symbol that is placed in a bank is replaced by the bank number.
+func normalizeUnaryAddressOperators(scope *scope.Scope, nodes []token.Token) []token.Token {
 	normalized := make([]token.Token, 0, len(nodes))
 	operandExpected := true
 
@@ -284,6 +285,12 @@ func normalizeUnaryAddressOperators(nodes []token.Token) []token.Token {
 		tok := nodes[i]
 		if operandExpected && i+1 < len(nodes) && isUnaryAddressOperator(tok.Type) {
 			operand := nodes[i+1]
+			if bank, ok := symbolBank(scope, tok, operand); ok {
+				normalized = append(normalized, bank)
+				i++
+				operandExpected = false
+				continue
+			}
 			if operand.Type == token.Identifier || operand.Type == token.Number {
 				normalized = append(normalized, unaryAddressExpression(tok, operand)...)
 				i++
@@ -304,6 +311,24 @@ func normalizeUnaryAddressOperators(nodes []token.Token) []token.Token {
 	return normalized
 }
 
+// symbolBank returns the bank number as token if the bank selector references a
+// symbol that is placed in a bank.
+func symbolBank(scope *scope.Scope, prefix, operand token.Token) (token.Token, bool) {
+	if prefix.Type != token.Caret || operand.Type != token.Identifier || scope == nil {
+		return token.Token{}, false
+	}
+
+	sym, err := scope.GetSymbol(operand.Value)
+	if err != nil {
+		return token.Token{}, false
+	}
+	bank, ok := sym.Bank()
+	if !ok {
+		return token.Token{}, false
+	}
+	return token.Token{Position: prefix.Position, Type: token.Number, Value: strconv.FormatUint(bank, 10)}, true
+}
+
 func isUnaryAddressOperator(typ token.Type) bool {
 	return typ == token.Lt || typ == token.Gt || typ == token.Caret
 }
@@ -36,3 +36,10 @@ func resolveKeywordOperator(tok token.Token) token.Token {
 	}
 	return tok
 }
+
+// IsKeywordOperator returns whether the identifier is a keyword operator like SHL or AND
+// instead of a symbol reference.
+func IsKeywordOperator(name string) bool {
+	_, ok := keywordOperators[strings.ToUpper(name)]
+	return ok
+}
@@ -1,9 +1,34 @@
 package token
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Position defines the position in the input stream.
 type Position struct {
+	File   string // name of the source file, can be empty for an unnamed input stream
 	Line   int
 	Column int
+
+	// Expansion is set for positions of source code that was pulled in by an include
+	// directive or a macro usage and describes where the expansion happened.
+	Expansion *Expansion
+}
+
+// ExpansionKind defines how source code was expanded into another source location.
+type ExpansionKind int
+
+const (
+	IncludeExpansion ExpansionKind = iota
+	MacroExpansion
+)
+
+// Expansion describes an include directive or macro usage that source code was expanded from.
+type Expansion struct {
+	Kind     ExpansionKind
+	Name     string   // name of the included file or the expanded macro
+	Position Position // position of the include directive or macro usage
 }
 
 // NextLine advances the position to the next line and column 0.
@@ -11,3 +36,45 @@ func (p *Position) NextLine() {
 	p.Line++
 	p.Column = 0
 }
+
+// String returns the position in the file:line:column notation. If no file is set,
+// only the line and column are returned.
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("line %d column %d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// Trace returns the position followed by the expansion stack that led to it,
+// for example "macros.asm:4:5 (in macro 'store' used at main.asm:8:3)".
+func (p Position) Trace() string {
+	stack := p.ExpansionStack()
+	if len(stack) == 0 {
+		return p.String()
+	}
+
+	descriptions := make([]string, 0, len(stack))
+	for _, exp := range stack {
+		descriptions = append(descriptions, exp.String())
+	}
+	return fmt.Sprintf("%s (%s)", p, strings.Join(descriptions, ", "))
+}
+
+// ExpansionStack returns all expansions that led to the position, starting with
+// the innermost include or macro usage.
+func (p Position) ExpansionStack() []*Expansion {
+	var stack []*Expansion
+	for exp := p.Expansion; exp != nil; exp = exp.Position.Expansion {
+		stack = append(stack, exp)
+	}
+	return stack
+}
+
+// String returns a description of the expansion and its position.
+func (e *Expansion) String() string {
+	if e.Kind == MacroExpansion {
+		return fmt.Sprintf("in macro '%s' used at %s", e.Name, e.Position)
+	}
+	return "included from " + e.Position.String()
+}
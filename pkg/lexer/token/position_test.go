@@ -16,3 +16,33 @@ func TestPosition(t *testing.T) {
 	assert.Equal(t, 2, p.Line)
 	assert.Equal(t, 0, p.Column)
 }
+
+func TestPositionString(t *testing.T) {
+	assert.Equal(t, "line 3 column 5", Position{Line: 3, Column: 5}.String())
+	assert.Equal(t, "main.asm:3:5", Position{File: "main.asm", Line: 3, Column: 5}.String())
+}
+
+func TestPositionExpansionStack(t *testing.T) {
+	include := &Expansion{
+		Kind:     IncludeExpansion,
+		Name:     "macros.asm",
+		Position: Position{File: "main.asm", Line: 2, Column: 1},
+	}
+	macro := &Expansion{
+		Kind:     MacroExpansion,
+		Name:     "store",
+		Position: Position{File: "macros.asm", Line: 8, Column: 3, Expansion: include},
+	}
+	p := Position{File: "macros.asm", Line: 4, Column: 5, Expansion: macro}
+
+	stack := p.ExpansionStack()
+	assert.Len(t, stack, 2)
+	assert.Equal(t, macro, stack[0])
+	assert.Equal(t, include, stack[1])
+
+	assert.Equal(t, "in macro 'store' used at macros.asm:8:3", macro.String())
+	assert.Equal(t, "included from main.asm:2:1", include.String())
+	assert.Equal(t, "macros.asm:4:5 (in macro 'store' used at macros.asm:8:3, included from main.asm:2:1)", p.Trace())
+	assert.Equal(t, "main.asm:2:1", include.Position.Trace())
+	assert.Empty(t, Position{}.ExpansionStack())
+}
@@ -14,8 +14,9 @@
 //
 // # Position Tracking
 //
-// Each token includes position information (line and column) for accurate
-// error reporting and debugging support.
+// Each token includes position information (file, line and column) for accurate
+// error reporting and debugging support. Tokens of included files and expanded
+// macros reference the include directive or macro usage that they originate from.
 package token
 
 const (
@@ -0,0 +1,55 @@
+package ast
+
+// Condes table types of the ca65 .condes directive.
+const (
+	CondesConstructor = 0
+	CondesDestructor  = 1
+	CondesInterruptor = 2
+
+	CondesMaxType = 6 // highest supported table type number
+)
+
+// Condes priorities, the entries of a table are sorted by their priority.
+const (
+	CondesMinPriority     = 1
+	CondesMaxPriority     = 32
+	CondesDefaultPriority = 7
+)
+
+// CondesTypes maps the names of the condes table types to their type numbers.
+var CondesTypes = map[string]int{
+	"constructor": CondesConstructor,
+	"destructor":  CondesDestructor,
+	"interruptor": CondesInterruptor,
+}
+
+// Condes represents the declaration of a constructor, destructor or interruptor
+// function (.constructor, .destructor, .interruptor, .condes). The linker collects
+// the addresses of all declared functions of a type in a table.
+type Condes struct {
+	*node
+
+	Name     string
+	Type     int
+	Priority int
+}
+
+// NewCondes returns a new condes node.
+func NewCondes(name string, typ, priority int) Condes {
+	return Condes{
+		node:     &node{},
+		Name:     name,
+		Type:     typ,
+		Priority: priority,
+	}
+}
+
+// Copy returns a copy of the condes node.
+func (c Condes) Copy() Node {
+	return Condes{
+		node:     c.node,
+		Name:     c.Name,
+		Type:     c.Type,
+		Priority: c.Priority,
+	}
+}
@@ -16,6 +16,7 @@ const (
 	ConfigBattery
 	ConfigMirror
 	ConfigFillValue
+	ConfigLongBranch // expand out of range branches, value 1 enables and 0 disables it
 )
 
 // Configuration represents an assembler configuration directive (mapper, PRG, CHR, etc.).
@@ -0,0 +1,31 @@
+package ast
+
+// CPU selects the CPU variant and the undocumented instructions that the parser accepts.
+// The parser completes the node to the selection that is active after the directive.
+type CPU struct {
+	*node
+
+	Name           string // CPU variant selected by .setcpu, empty for the default CPU
+	Undocumented   bool   // accept the stable undocumented instructions
+	Unstable       bool   // accept the undocumented instructions with unstable results
+	HighlyUnstable bool   // accept the undocumented instructions with highly unstable results
+}
+
+// NewCPU returns a new CPU node.
+func NewCPU(name string) CPU {
+	return CPU{
+		node: &node{},
+		Name: name,
+	}
+}
+
+// Copy returns a copy of the CPU node.
+func (c CPU) Copy() Node {
+	return CPU{
+		node:           c.node,
+		Name:           c.Name,
+		Undocumented:   c.Undocumented,
+		Unstable:       c.Unstable,
+		HighlyUnstable: c.HighlyUnstable,
+	}
+}
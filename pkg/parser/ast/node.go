@@ -11,6 +11,8 @@
 // macro expansion and comment attachment for documentation.
 package ast
 
+import "github.com/retroenv/retroasm/pkg/lexer/token"
+
 // Node represents a single element in the assembly language AST.
 //
 // All AST nodes must support deep copying for macro expansion (.rept directives)
@@ -22,11 +24,36 @@ type Node interface {
 	SetComment(message string)
 }
 
+// Positioner is implemented by nodes that store the source position they were parsed from.
+type Positioner interface {
+	// Position returns the source position of the node.
+	Position() token.Position
+	// SetPosition sets the source position of the node.
+	SetPosition(pos token.Position)
+}
+
 type node struct {
-	comment Comment
+	comment  Comment
+	position token.Position
 }
 
 // SetComment sets the comment for the node.
 func (n *node) SetComment(message string) {
 	n.comment.Message = message
 }
+
+// Position returns the source position of the node.
+func (n *node) Position() token.Position {
+	if n == nil {
+		return token.Position{}
+	}
+	return n.position
+}
+
+// SetPosition sets the source position of the node. Nodes that were created
+// without a constructor do not store a position.
+func (n *node) SetPosition(pos token.Position) {
+	if n != nil {
+		n.position = pos
+	}
+}
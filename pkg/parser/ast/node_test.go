@@ -165,3 +165,17 @@ func TestAST_EdgeCases(t *testing.T) {
 		assert.Equal(t, -5, variable.Size)
 	})
 }
+
+func TestNode_Position(t *testing.T) {
+	label := NewLabel("main")
+	label.SetPosition(token.Position{Line: 3, Column: 1})
+	assert.Equal(t, token.Position{Line: 3, Column: 1}, label.Position())
+
+	copied, ok := label.Copy().(Label)
+	assert.True(t, ok)
+	assert.Equal(t, 3, copied.Position().Line)
+
+	var empty Label
+	empty.SetPosition(token.Position{Line: 1})
+	assert.Equal(t, token.Position{}, empty.Position())
+}
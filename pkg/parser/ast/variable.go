@@ -6,7 +6,7 @@ type Variable struct {
 
 	Name             string
 	Size             int
-	UseOffsetCounter bool // TODO support
+	UseOffsetCounter bool // NESASM .rs variable that is placed at the offset counter
 }
 
 // NewVariable returns a new variable node.
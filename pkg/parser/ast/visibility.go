@@ -0,0 +1,54 @@
+package ast
+
+// VisibilityType defines the type of a symbol visibility directive.
+type VisibilityType int
+
+const (
+	VisibilityExport VisibilityType = iota // symbols are defined by the file and visible to other files
+	VisibilityImport                       // symbols are defined by another file
+	VisibilityGlobal                       // exported if the file defines the symbols, imported otherwise
+)
+
+// VisibilityTypes maps the names of the symbol visibility directives to their type
+// and whether the symbols are zero page addresses.
+var VisibilityTypes = map[string]struct {
+	Type     VisibilityType
+	ZeroPage bool
+}{
+	"export":   {Type: VisibilityExport},
+	"exportzp": {Type: VisibilityExport, ZeroPage: true},
+	"global":   {Type: VisibilityGlobal},
+	"globalzp": {Type: VisibilityGlobal, ZeroPage: true},
+	"import":   {Type: VisibilityImport},
+	"importzp": {Type: VisibilityImport, ZeroPage: true},
+}
+
+// SymbolVisibility represents the declaration of the visibility of symbols (.export,
+// .import, .global and their zero page variants .exportzp, .importzp and .globalzp).
+type SymbolVisibility struct {
+	*node
+
+	Names    []string
+	Type     VisibilityType
+	ZeroPage bool
+}
+
+// NewSymbolVisibility returns a new symbol visibility node.
+func NewSymbolVisibility(names []string, typ VisibilityType, zeroPage bool) SymbolVisibility {
+	return SymbolVisibility{
+		node:     &node{},
+		Names:    names,
+		Type:     typ,
+		ZeroPage: zeroPage,
+	}
+}
+
+// Copy returns a copy of the symbol visibility node.
+func (v SymbolVisibility) Copy() Node {
+	return SymbolVisibility{
+		node:     v.node,
+		Names:    v.Names,
+		Type:     v.Type,
+		ZeroPage: v.ZeroPage,
+	}
+}
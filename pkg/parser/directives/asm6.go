@@ -0,0 +1,10 @@
+package directives
+
+func asm6Handlers() map[string]Handler {
+	// asm6f selects its undocumented instructions by directives that other
+	// dialects do not know.
+	return map[string]Handler{
+		"hunstable": HighlyUnstable,
+		"unstable":  Unstable,
+	}
+}
@@ -0,0 +1,82 @@
+package directives
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/retroenv/retroasm/pkg/arch"
+	"github.com/retroenv/retroasm/pkg/lexer/token"
+	"github.com/retroenv/retroasm/pkg/number"
+	"github.com/retroenv/retroasm/pkg/parser/ast"
+)
+
+// Condes parses a .constructor, .destructor, .interruptor or .condes directive that
+// declares a function for a table that the linker creates. The .condes directive
+// expects the table type as second parameter, all directives accept an optional priority.
+func Condes(p arch.Parser) (ast.Node, error) {
+	directive := strings.ToLower(p.NextToken(1).Value)
+	name := p.NextToken(2)
+	if name.Type != token.Identifier {
+		return nil, errMissingParameter
+	}
+	p.AdvanceReadPosition(2)
+
+	var params []token.Token
+	for p.NextToken(1).Type == token.Comma {
+		param := p.NextToken(2)
+		if param.Type != token.Identifier && param.Type != token.Number {
+			return nil, errMissingParameter
+		}
+		params = append(params, param)
+		p.AdvanceReadPosition(2)
+	}
+
+	typ, ok := ast.CondesTypes[directive]
+	if !ok {
+		if len(params) == 0 {
+			return nil, errMissingParameter
+		}
+
+		var err error
+		typ, err = parseCondesType(params[0])
+		if err != nil {
+			return nil, err
+		}
+		params = params[1:]
+	}
+
+	priority := ast.CondesDefaultPriority
+	switch len(params) {
+	case 0:
+	case 1:
+		i, err := number.Parse(params[0].Value)
+		if err != nil {
+			return nil, fmt.Errorf("parsing priority '%s': %w", params[0].Value, err)
+		}
+		if i < ast.CondesMinPriority || i > ast.CondesMaxPriority {
+			return nil, fmt.Errorf("priority %d is outside of the range %d to %d",
+				i, ast.CondesMinPriority, ast.CondesMaxPriority)
+		}
+		priority = int(i)
+	default:
+		return nil, errUnexpectedParameter
+	}
+
+	return ast.NewCondes(name.Value, typ, priority), nil
+}
+
+// parseCondesType parses a table type, given as name or number.
+func parseCondesType(tok token.Token) (int, error) {
+	if typ, ok := ast.CondesTypes[strings.ToLower(tok.Value)]; ok {
+		return typ, nil
+	}
+
+	i, err := number.Parse(tok.Value)
+	if err != nil {
+		return 0, fmt.Errorf("unsupported condes type '%s'", tok.Value)
+	}
+	if i > ast.CondesMaxType {
+		return 0, fmt.Errorf("condes type %d exceeds maximum %d", i, ast.CondesMaxType)
+	}
+	return int(i), nil
+}
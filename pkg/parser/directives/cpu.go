@@ -0,0 +1,61 @@
+package directives
+
+import (
+	"strings"
+
+	"github.com/retroenv/retroasm/pkg/arch"
+	"github.com/retroenv/retroasm/pkg/parser/ast"
+)
+
+// SetCPU parses a .setcpu directive that selects the CPU variant.
+func SetCPU(p arch.Parser) (ast.Node, error) {
+	next := p.NextToken(2)
+	if next.Type.IsTerminator() {
+		return nil, errMissingParameter
+	}
+	p.AdvanceReadPosition(2)
+
+	name := strings.ToLower(strings.Trim(next.Value, "\"'"))
+	return ast.NewCPU(name), nil
+}
+
+// P02 parses a .p02 directive that selects the NMOS 6502 CPU.
+func P02(p arch.Parser) (ast.Node, error) {
+	return selectCPU(p, "6502"), nil
+}
+
+// PC02 parses a .pc02 directive that selects the 65C02 CPU.
+func PC02(p arch.Parser) (ast.Node, error) {
+	return selectCPU(p, "65c02"), nil
+}
+
+// PSC02 parses a .psc02 directive that selects the 65SC02 CPU.
+func PSC02(p arch.Parser) (ast.Node, error) {
+	return selectCPU(p, "65sc02"), nil
+}
+
+// Unstable parses an asm6f .unstable directive that enables the undocumented instructions
+// with unstable results.
+func Unstable(p arch.Parser) (ast.Node, error) {
+	p.AdvanceReadPosition(1)
+
+	cpu := ast.NewCPU("")
+	cpu.Unstable = true
+	return cpu, nil
+}
+
+// HighlyUnstable parses an asm6f .hunstable directive that enables the undocumented
+// instructions with highly unstable results.
+func HighlyUnstable(p arch.Parser) (ast.Node, error) {
+	p.AdvanceReadPosition(1)
+
+	cpu := ast.NewCPU("")
+	cpu.HighlyUnstable = true
+	return cpu, nil
+}
+
+// selectCPU returns the node of a directive without parameters that selects a CPU variant.
+func selectCPU(p arch.Parser, name string) ast.CPU {
+	p.AdvanceReadPosition(1)
+	return ast.NewCPU(name)
+}
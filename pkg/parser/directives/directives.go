@@ -9,6 +9,7 @@
 //   - Macros: .macro/.endm, .rept/.endr (code generation)
 //   - Includes: .include, .incbin (file inclusion)
 //   - Configuration: .segment, .bank, .setcpu (assembler settings)
+//   - Symbols: .export, .import, .global (symbol visibility)
 //
 // BuildHandlers provides the dispatch mechanism for directive-specific parsing.
 // Each handler receives a parser instance and returns the corresponding AST node.
@@ -35,10 +36,13 @@ type Handler func(arch.Parser) (ast.Node, error)
 // BuildHandlers returns an independent directive handler map for a compatibility mode.
 func BuildHandlers(mode config.CompatibilityMode) map[string]Handler {
 	handlers := baseHandlers()
-	if mode == config.CompatX816 {
-		// Apply the dialect overlay to a fresh map so remapped spellings such as
-		// .dl cannot change the defaults used by other parser instances.
+	// Apply the dialect overlays to a fresh map so remapped spellings such as
+	// .dl cannot change the defaults used by other parser instances.
+	switch mode {
+	case config.CompatX816:
 		maps.Copy(handlers, x816Handlers())
+	case config.CompatAsm6:
+		maps.Copy(handlers, asm6Handlers())
 	}
 	return handlers
 }
@@ -48,14 +52,6 @@ var directiveBinaryIncludes = set.NewFromSlice([]string{
 	"incbin", // asm6
 })
 
-// SetCPU skips the .setcpu directive as it is not currently used.
-//
-//nolint:nilnil // directive is intentionally ignored
-func SetCPU(p arch.Parser) (ast.Node, error) {
-	p.AdvanceReadPosition(2)
-	return nil, nil
-}
-
 // NoOp consumes a directive without producing an AST node.
 //
 //nolint:nilnil // directive is intentionally ignored
@@ -70,52 +66,66 @@ func NoOp(p arch.Parser) (ast.Node, error) {
 
 func baseHandlers() map[string]Handler {
 	return map[string]Handler{
-		"addr":       Addr,
-		"align":      Align, // asm6
-		"bank":       Bank,
-		"base":       Base,
-		"bin":        Include, // asm6
-		"byt":        Data,
-		"byte":       Data,     // asm6
-		"db":         Data,     // asm6
-		"dcb":        Data,     // asm6
-		"dcw":        Data,     // asm6
-		"dh":         AddrHigh, // asm6
-		"dl":         AddrLow,  // asm6
-		"dsb":        DataStorage,
-		"dsw":        DataStorage,
-		"dw":         Data,   // asm6
-		"else":       Else,   // asm6
-		"elseif":     Elseif, // asm6
-		"endif":      Endif,  // asm6
-		"ende":       Ende,   // asm6
-		"endproc":    EndProc,
-		"endr":       Endr,      // asm6
-		"enum":       Enum,      // asm6
-		"error":      Error,     // asm6
-		"fillvalue":  FillValue, // asm6
-		"hex":        Hex,       // asm6
-		"if":         If,        // asm6
-		"ifdef":      Ifdef,     // asm6
-		"ifndef":     Ifndef,    // asm6
-		"incbin":     Include,   // asm6
-		"include":    Include,   // asm6
-		"incsrc":     Include,   // asm6
-		"inesbat":    NesasmConfig,
-		"ineschr":    NesasmConfig,
-		"inesmap":    NesasmConfig,
-		"inesmir":    NesasmConfig,
-		"inesprg":    NesasmConfig,
-		"inessubmap": NesasmConfig,
-		"macro":      Macro,   // asm6
-		"org":        Base,    // asm6
-		"pad":        Padding, // asm6
-		"proc":       Proc,
-		"rept":       Rept, // asm6
-		"res":        Res,
-		"rsset":      NesasmOffsetCounter,
-		"segment":    Segment,
-		"setcpu":     SetCPU,
-		"word":       Data, // asm6
+		"addr":        Addr,
+		"align":       Align, // asm6
+		"bank":        Bank,
+		"base":        Base,
+		"bin":         Include, // asm6
+		"byt":         Data,
+		"byte":        Data, // asm6
+		"condes":      Condes,
+		"constructor": Condes,
+		"db":          Data, // asm6
+		"dcb":         Data, // asm6
+		"dcw":         Data, // asm6
+		"destructor":  Condes,
+		"dh":          AddrHigh, // asm6
+		"dl":          AddrLow,  // asm6
+		"dsb":         DataStorage,
+		"dsw":         DataStorage,
+		"dw":          Data,   // asm6
+		"else":        Else,   // asm6
+		"elseif":      Elseif, // asm6
+		"endif":       Endif,  // asm6
+		"ende":        Ende,   // asm6
+		"endproc":     EndProc,
+		"endr":        Endr,  // asm6
+		"enum":        Enum,  // asm6
+		"error":       Error, // asm6
+		"export":      Visibility,
+		"exportzp":    Visibility,
+		"feature":     Feature,
+		"fillvalue":   FillValue, // asm6
+		"global":      Visibility,
+		"globalzp":    Visibility,
+		"hex":         Hex,    // asm6
+		"if":          If,     // asm6
+		"ifdef":       Ifdef,  // asm6
+		"ifndef":      Ifndef, // asm6
+		"import":      Visibility,
+		"importzp":    Visibility,
+		"incbin":      Include, // asm6
+		"include":     Include, // asm6
+		"incsrc":      Include, // asm6
+		"inesbat":     NesasmConfig,
+		"interruptor": Condes,
+		"ineschr":     NesasmConfig,
+		"inesmap":     NesasmConfig,
+		"inesmir":     NesasmConfig,
+		"inesprg":     NesasmConfig,
+		"inessubmap":  NesasmConfig,
+		"macro":       Macro, // asm6
+		"org":         Base,  // asm6
+		"p02":         P02,
+		"pad":         Padding, // asm6
+		"pc02":        PC02,
+		"proc":        Proc,
+		"psc02":       PSC02,
+		"rept":        Rept, // asm6
+		"res":         Res,
+		"rsset":       NesasmOffsetCounter,
+		"segment":     Segment,
+		"setcpu":      SetCPU,
+		"word":        Data, // asm6
 	}
 }
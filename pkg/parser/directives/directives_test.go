@@ -17,10 +17,147 @@ func TestSetCPU(t *testing.T) {
 
 	node, err := SetCPU(parser)
 	assert.NoError(t, err)
-	assert.Nil(t, node)                 // SetCPU returns nil node
+	cpu, ok := node.(ast.CPU)
+	assert.True(t, ok)
+	assert.Equal(t, "6502", cpu.Name)
 	assert.Equal(t, 2, parser.position) // Should advance position by 2
 }
 
+func TestSetCPUQuoted(t *testing.T) {
+	parser := newMockParser([]token.Token{
+		{Type: token.Dot, Value: "."},
+		{Type: token.Identifier, Value: "setcpu"},
+		{Type: token.Identifier, Value: `"6502X"`},
+	})
+
+	node, err := SetCPU(parser)
+	assert.NoError(t, err)
+	cpu, ok := node.(ast.CPU)
+	assert.True(t, ok)
+	assert.Equal(t, "6502x", cpu.Name)
+}
+
+func TestUnstable(t *testing.T) {
+	tests := []struct {
+		name           string
+		handler        Handler
+		unstable       bool
+		highlyUnstable bool
+	}{
+		{name: "unstable", handler: Unstable, unstable: true},
+		{name: "highly unstable", handler: HighlyUnstable, highlyUnstable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := newMockParser([]token.Token{
+				{Type: token.Dot, Value: "."},
+				{Type: token.Identifier, Value: tt.name},
+				{Type: token.EOL},
+			})
+
+			node, err := tt.handler(parser)
+			assert.NoError(t, err)
+			cpu, ok := node.(ast.CPU)
+			assert.True(t, ok)
+			assert.Equal(t, "", cpu.Name)
+			assert.Equal(t, tt.unstable, cpu.Unstable)
+			assert.Equal(t, tt.highlyUnstable, cpu.HighlyUnstable)
+			assert.Equal(t, 1, parser.position)
+		})
+	}
+}
+
+func TestProcessorDirectives(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler Handler
+		cpu     string
+	}{
+		{name: "p02", handler: P02, cpu: "6502"},
+		{name: "pc02", handler: PC02, cpu: "65c02"},
+		{name: "psc02", handler: PSC02, cpu: "65sc02"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := newMockParser([]token.Token{
+				{Type: token.Dot, Value: "."},
+				{Type: token.Identifier, Value: tt.name},
+				{Type: token.EOL},
+			})
+
+			node, err := tt.handler(parser)
+			assert.NoError(t, err)
+			cpu, ok := node.(ast.CPU)
+			assert.True(t, ok)
+			assert.Equal(t, tt.cpu, cpu.Name)
+			assert.Equal(t, 1, parser.position)
+		})
+	}
+}
+
+func TestFeature(t *testing.T) {
+	tests := []struct {
+		name     string
+		tokens   []token.Token
+		node     bool   // whether a configuration node is returned
+		value    uint64 // value of the configuration node
+		position int
+	}{
+		{
+			name: "long branch",
+			tokens: []token.Token{
+				{Type: token.Identifier, Value: "longbranch"},
+			},
+			node:     true,
+			value:    1,
+			position: 2,
+		},
+		{
+			name: "long branch disabled",
+			tokens: []token.Token{
+				{Type: token.Identifier, Value: "long_jsr_jmp_rts"},
+				{Type: token.Comma},
+				{Type: token.Identifier, Value: "LONGBRANCH"},
+				{Type: token.Minus},
+			},
+			node:     true,
+			position: 5,
+		},
+		{
+			name: "ignored feature",
+			tokens: []token.Token{
+				{Type: token.Identifier, Value: "labels_without_colons"},
+			},
+			position: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := append([]token.Token{
+				{Type: token.Dot, Value: "."},
+				{Type: token.Identifier, Value: "feature"},
+			}, tt.tokens...)
+			parser := newMockParser(append(tokens, token.Token{Type: token.EOL}))
+
+			node, err := Feature(parser)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.position, parser.position)
+			if !tt.node {
+				assert.Nil(t, node)
+				return
+			}
+
+			cfg, ok := node.(ast.Configuration)
+			assert.True(t, ok)
+			assert.Equal(t, ast.ConfigLongBranch, cfg.Item)
+			assert.Equal(t, tt.value, cfg.Value)
+		})
+	}
+}
+
 // Test integration with mock parser.
 func TestDirectiveIntegration(t *testing.T) {
 	t.Run("data_directive", func(t *testing.T) {
@@ -146,3 +283,149 @@ func (p *mockParser) ResolveUnnamedLabel(_ bool, _ int) string {
 func (p *mockParser) ScopeLocalLabel(name string) string {
 	return p.scopePrefix + name
 }
+
+func TestCondes(t *testing.T) {
+	tests := []struct {
+		name     string
+		tokens   []token.Token
+		expected ast.Condes
+		err      bool
+	}{
+		{
+			name: "constructor with default priority",
+			tokens: []token.Token{
+				{Type: token.Identifier, Value: "constructor"},
+				{Type: token.Identifier, Value: "init"},
+			},
+			expected: ast.NewCondes("init", ast.CondesConstructor, ast.CondesDefaultPriority),
+		},
+		{
+			name: "destructor with priority",
+			tokens: []token.Token{
+				{Type: token.Identifier, Value: "destructor"},
+				{Type: token.Identifier, Value: "done"},
+				{Type: token.Comma},
+				{Type: token.Number, Value: "20"},
+			},
+			expected: ast.NewCondes("done", ast.CondesDestructor, 20),
+		},
+		{
+			name: "condes with type number",
+			tokens: []token.Token{
+				{Type: token.Identifier, Value: "condes"},
+				{Type: token.Identifier, Value: "handler"},
+				{Type: token.Comma},
+				{Type: token.Number, Value: "5"},
+			},
+			expected: ast.NewCondes("handler", 5, ast.CondesDefaultPriority),
+		},
+		{
+			name: "condes without type",
+			tokens: []token.Token{
+				{Type: token.Identifier, Value: "condes"},
+				{Type: token.Identifier, Value: "handler"},
+			},
+			err: true,
+		},
+		{
+			name: "priority out of range",
+			tokens: []token.Token{
+				{Type: token.Identifier, Value: "interruptor"},
+				{Type: token.Identifier, Value: "irq"},
+				{Type: token.Comma},
+				{Type: token.Number, Value: "33"},
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := append([]token.Token{{Type: token.Dot, Value: "."}}, tt.tokens...)
+			node, err := Condes(newMockParser(tokens))
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			condes, ok := node.(ast.Condes)
+			assert.True(t, ok)
+			assert.Equal(t, tt.expected.Name, condes.Name)
+			assert.Equal(t, tt.expected.Type, condes.Type)
+			assert.Equal(t, tt.expected.Priority, condes.Priority)
+		})
+	}
+}
+
+func TestVisibility(t *testing.T) {
+	tests := []struct {
+		name     string
+		tokens   []token.Token
+		expected ast.SymbolVisibility
+		err      bool
+	}{
+		{
+			name: "export list",
+			tokens: []token.Token{
+				{Type: token.Identifier, Value: "export"},
+				{Type: token.Identifier, Value: "reset"},
+				{Type: token.Comma},
+				{Type: token.Identifier, Value: "nmi"},
+			},
+			expected: ast.NewSymbolVisibility([]string{"reset", "nmi"}, ast.VisibilityExport, false),
+		},
+		{
+			name: "zero page import",
+			tokens: []token.Token{
+				{Type: token.Identifier, Value: "importzp"},
+				{Type: token.Identifier, Value: "ptr"},
+			},
+			expected: ast.NewSymbolVisibility([]string{"ptr"}, ast.VisibilityImport, true),
+		},
+		{
+			name: "global",
+			tokens: []token.Token{
+				{Type: token.Identifier, Value: "GLOBAL"},
+				{Type: token.Identifier, Value: "main"},
+			},
+			expected: ast.NewSymbolVisibility([]string{"main"}, ast.VisibilityGlobal, false),
+		},
+		{
+			name: "missing symbol",
+			tokens: []token.Token{
+				{Type: token.Identifier, Value: "import"},
+				{Type: token.EOL},
+			},
+			err: true,
+		},
+		{
+			name: "missing symbol after comma",
+			tokens: []token.Token{
+				{Type: token.Identifier, Value: "export"},
+				{Type: token.Identifier, Value: "reset"},
+				{Type: token.Comma},
+				{Type: token.Number, Value: "1"},
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := append([]token.Token{{Type: token.Dot, Value: "."}}, tt.tokens...)
+			node, err := Visibility(newMockParser(tokens))
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			visibility, ok := node.(ast.SymbolVisibility)
+			assert.True(t, ok)
+			assert.Equal(t, tt.expected.Names, visibility.Names)
+			assert.Equal(t, tt.expected.Type, visibility.Type)
+			assert.Equal(t, tt.expected.ZeroPage, visibility.ZeroPage)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package directives
+
+import (
+	"strings"
+
+	"github.com/retroenv/retroasm/pkg/arch"
+	"github.com/retroenv/retroasm/pkg/lexer/token"
+	"github.com/retroenv/retroasm/pkg/parser/ast"
+)
+
+// featureLongBranch is the name of the feature that expands out of range branches.
+const featureLongBranch = "longbranch"
+
+// Feature parses a .feature directive. The longbranch feature returns a configuration
+// node, all other features are ignored. A feature that is followed by - is disabled.
+func Feature(p arch.Parser) (ast.Node, error) {
+	var node ast.Node
+
+	offset := 2
+	for ; !p.NextToken(offset).Type.IsTerminator(); offset++ {
+		tok := p.NextToken(offset)
+		if tok.Type != token.Identifier || !strings.EqualFold(tok.Value, featureLongBranch) {
+			continue
+		}
+
+		cfg := ast.NewConfiguration(ast.ConfigLongBranch)
+		cfg.Value = 1
+		if p.NextToken(offset+1).Type == token.Minus {
+			cfg.Value = 0
+		}
+		node = cfg
+	}
+
+	if offset == 2 {
+		return nil, errMissingParameter
+	}
+	p.AdvanceReadPosition(offset - 1)
+	return node, nil
+}
@@ -59,8 +59,10 @@ func TestBuildHandlers(t *testing.T) {
 
 			_, byteFound := handlers["byte"]
 			_, orgFound := handlers["org"]
+			_, unstableFound := handlers["unstable"]
 			assert.True(t, byteFound)
 			assert.True(t, orgFound)
+			assert.Equal(t, mode == config.CompatAsm6, unstableFound)
 		})
 	}
 }
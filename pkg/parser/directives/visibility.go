@@ -0,0 +1,31 @@
+package directives
+
+import (
+	"strings"
+
+	"github.com/retroenv/retroasm/pkg/arch"
+	"github.com/retroenv/retroasm/pkg/lexer/token"
+	"github.com/retroenv/retroasm/pkg/parser/ast"
+)
+
+// Visibility parses a .export, .import or .global directive or one of their zero page
+// variants that declare the visibility of a comma separated list of symbols.
+func Visibility(p arch.Parser) (ast.Node, error) {
+	visibility := ast.VisibilityTypes[strings.ToLower(p.NextToken(1).Value)]
+
+	var names []string
+	for {
+		name := p.NextToken(2)
+		if name.Type != token.Identifier {
+			return nil, errMissingParameter
+		}
+		names = append(names, name.Value)
+		p.AdvanceReadPosition(2)
+
+		if p.NextToken(1).Type != token.Comma {
+			break
+		}
+	}
+
+	return ast.NewSymbolVisibility(names, visibility.Type, visibility.ZeroPage), nil
+}
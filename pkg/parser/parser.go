@@ -32,7 +32,32 @@ import (
 	"github.com/retroenv/retroasm/pkg/parser/directives"
 )
 
-var errMissingParameter = errors.New("missing parameter")
+var (
+	errMissingParameter = errors.New("missing parameter")
+	errUnsupportedCPU   = errors.New("unsupported CPU")
+)
+
+// Error is a parser error for a token of the input.
+type Error struct {
+	Token token.Token // token that caused the error
+	Err   error
+}
+
+// Error returns the error message including the token and its position.
+func (e *Error) Error() string {
+	return fmt.Sprintf("parser error for token '%s' of type %s found at %s: %s",
+		e.Token.Value, e.Token.Type.String(), e.Token.Position.Trace(), e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Position returns the source position of the token that caused the error.
+func (e *Error) Position() token.Position {
+	return e.Token.Position
+}
 
 // Parser is the input stream parser.
 type Parser[T any] struct {
@@ -44,6 +69,11 @@ type Parser[T any] struct {
 	readPosition  int
 	programLength int
 
+	fileName  string           // name of the read source file, set in the token positions
+	expansion *token.Expansion // include that the read source file was pulled in by
+
+	cpu ast.CPU // selected CPU variant and undocumented instructions
+
 	// Direction-specific counters keep repeated x816 anonymous definitions
 	// unique without coupling forward and backward label namespaces.
 	anonForwardCount  int
@@ -63,6 +93,7 @@ func New[T any](arch arch.Architecture[T], reader io.Reader, mode config.Compati
 		compatMode: mode,
 		handlers:   directives.BuildHandlers(mode),
 		lexer:      lexer.New(lexerCfg, reader),
+		cpu:        defaultCPU(mode),
 	}
 }
 
@@ -74,9 +105,31 @@ func NewWithTokens[T any](arch arch.Architecture[T], tokens []token.Token, mode
 		handlers:      directives.BuildHandlers(mode),
 		program:       tokens,
 		programLength: len(tokens),
+		cpu:           defaultCPU(mode),
 	}
 }
 
+// defaultCPU returns the CPU selection that the parser starts with in the compatibility mode.
+func defaultCPU(mode config.CompatibilityMode) ast.CPU {
+	cpu := ast.NewCPU("")
+	cpu.Undocumented = mode.UndocumentedInstructions()
+	return cpu
+}
+
+// SetSource sets the file name of the source and the include directive that the
+// source was pulled in by, which can be nil. Both are set in the positions of all
+// tokens that are read afterwards.
+func (p *Parser[T]) SetSource(fileName string, expansion *token.Expansion) {
+	p.fileName = fileName
+	p.expansion = expansion
+}
+
+// SetCPU sets the selected CPU variant and undocumented instructions, for parsing a source
+// that continues the selection of the source that pulled it in.
+func (p *Parser[T]) SetCPU(cpu ast.CPU) {
+	p.cpu = cpu
+}
+
 // Read all tokens of the lexer.
 func (p *Parser[T]) Read(ctx context.Context) error {
 	if err := p.parseTokens(ctx); err != nil {
@@ -150,11 +203,14 @@ func (p *Parser[T]) ScopeLocalLabel(name string) string {
 //   - Comments (attached to previous node if on same line)
 //
 // The method maintains parsing state and provides detailed error context including
-// line and column numbers for debugging.
+// line and column numbers for debugging. After an error, parsing continues at the
+// next line so that all independent errors of the input are returned as *Error
+// values joined into one error.
 func (p *Parser[T]) TokensToAstNodes() ([]ast.Node, error) {
 	var (
 		nodes        = make([]ast.Node, 0, p.programLength/2) // Pre-allocate with estimated capacity
 		previousNode ast.Node
+		errs         []error
 	)
 
 	for p.readPosition < p.programLength {
@@ -162,19 +218,36 @@ func (p *Parser[T]) TokensToAstNodes() ([]ast.Node, error) {
 		entry, err := p.parseToken(tok, previousNode)
 
 		if err != nil {
-			return nil, fmt.Errorf("parser error for token '%s' of type %s found at line %d column %d: %w",
-				tok.Value, tok.Type.String(), tok.Position.Line, tok.Position.Column, err)
+			errs = append(errs, &Error{Token: tok, Err: err})
+			p.skipToEndOfLine()
+			previousNode = nil
+			p.readPosition++
+			continue
 		}
 		if entry != nil {
+			if positioner, ok := entry.(ast.Positioner); ok {
+				positioner.SetPosition(tok.Position)
+			}
 			nodes = append(nodes, entry)
 		}
 		previousNode = entry
 		p.readPosition++
 	}
 
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
 	return nodes, nil
 }
 
+// skipToEndOfLine advances the read position to the end of the current line,
+// to resume parsing after an error.
+func (p *Parser[T]) skipToEndOfLine() {
+	for p.readPosition < p.programLength && p.program[p.readPosition].Type != token.EOL {
+		p.readPosition++
+	}
+}
+
 func (p *Parser[T]) parseToken(tok token.Token, previousNode ast.Node) (ast.Node, error) {
 	switch tok.Type {
 	case token.Dot:
@@ -234,9 +307,10 @@ func (p *Parser[T]) parseTokens(ctx context.Context) error {
 		if err != nil {
 			return fmt.Errorf("reading next token: %w", err)
 		}
+		tok.Position.File = p.fileName
+		tok.Position.Expansion = p.expansion
 		if tok.Type == token.Illegal {
-			return fmt.Errorf("illegal token '%s' found at line %d column %d",
-				tok.Value, tok.Position.Line, tok.Position.Column)
+			return fmt.Errorf("illegal token '%s' found at %s", tok.Value, tok.Position.Trace())
 		}
 		if tok.Type == token.EOF {
 			break
@@ -244,10 +318,46 @@ func (p *Parser[T]) parseTokens(ctx context.Context) error {
 		p.program = append(p.program, tok)
 	}
 
+	p.program = p.rewriteBankFunctions(p.program)
 	p.programLength = len(p.program)
 	return nil
 }
 
+// rewriteBankFunctions replaces the .bank(label) function and the NESASM BANK(label)
+// function by the bank byte selector ^label, which returns the bank of the label.
+func (p *Parser[T]) rewriteBankFunctions(tokens []token.Token) []token.Token {
+	result := make([]token.Token, 0, len(tokens))
+
+	for i := 0; i < len(tokens); i++ {
+		nameIndex := i // index of the function name
+		if tokens[i].Type == token.Dot {
+			nameIndex++
+		} else if !p.compatMode.BankFunction() {
+			result = append(result, tokens[i])
+			continue
+		}
+
+		if isBankFunction(tokens, nameIndex) {
+			result = append(result, token.Token{Type: token.Caret, Position: tokens[i].Position}, tokens[nameIndex+2])
+			i = nameIndex + 3
+			continue
+		}
+		result = append(result, tokens[i])
+	}
+
+	return result
+}
+
+// isBankFunction returns whether the tokens at the index are a bank function call with
+// a label argument.
+func isBankFunction(tokens []token.Token, index int) bool {
+	return index+3 < len(tokens) &&
+		tokens[index].Type == token.Identifier && strings.EqualFold(tokens[index].Value, "bank") &&
+		tokens[index+1].Type == token.LeftParentheses &&
+		tokens[index+2].Type == token.Identifier &&
+		tokens[index+3].Type == token.RightParentheses
+}
+
 // parseComment returns a new comment AST node or attaches the comment to the previous node if the comment is on the
 // same line.
 func (p *Parser[T]) parseComment(tok token.Token, previousNode ast.Node) ast.Node {
@@ -274,7 +384,37 @@ func (p *Parser[T]) parseDot() (ast.Node, error) {
 		return nil, fmt.Errorf("unsupported directive '%s'", next.Value)
 	}
 
-	return handler(p)
+	node, err := handler(p)
+	if cpu, ok := node.(ast.CPU); ok && err == nil {
+		return p.selectCPU(cpu)
+	}
+	return node, err
+}
+
+// selectCPU applies a CPU directive to the selection of the parser and returns the node
+// completed to the resulting selection. The selected undocumented instructions stay
+// enabled when another CPU variant is selected.
+func (p *Parser[T]) selectCPU(cpu ast.CPU) (ast.Node, error) {
+	if cpu.Name == "" {
+		cpu.Name = p.cpu.Name
+	} else if selector, ok := p.arch.(arch.CPUSelector[T]); ok && !selector.SupportsCPU(cpu.Name) {
+		return nil, fmt.Errorf("%w '%s'", errUnsupportedCPU, cpu.Name)
+	}
+	cpu.Undocumented = cpu.Undocumented || p.cpu.Undocumented
+	cpu.Unstable = cpu.Unstable || p.cpu.Unstable
+	cpu.HighlyUnstable = cpu.HighlyUnstable || p.cpu.HighlyUnstable
+
+	p.cpu = cpu
+	return cpu, nil
+}
+
+// instruction returns the instruction with the given name if it is accepted by the
+// selected CPU.
+func (p *Parser[T]) instruction(name string) (T, bool) {
+	if selector, ok := p.arch.(arch.CPUSelector[T]); ok {
+		return selector.CPUInstruction(p.cpu, name)
+	}
+	return p.arch.Instruction(name)
 }
 
 // parseIdentifier handles identifier tokens which can represent:
@@ -307,7 +447,7 @@ func (p *Parser[T]) parseIdentifier(tok token.Token) (ast.Node, error) {
 	}
 
 	instructionName := strings.ToLower(tok.Value)
-	ins, ok := p.arch.Instruction(instructionName)
+	ins, ok := p.instruction(instructionName)
 	if !ok {
 		if p.compatMode.ColonOptionalLabels() && p.isColonOptionalLabel(tok, next) {
 			return ast.NewLabel(tok.Value), nil
@@ -417,7 +557,7 @@ func (p *Parser[T]) isColonOptionalLabel(tok, next token.Token) bool {
 	}
 
 	nextName := strings.ToLower(next.Value)
-	if _, ok := p.arch.Instruction(nextName); ok {
+	if _, ok := p.instruction(nextName); ok {
 		return true
 	}
 	_, ok := p.handlers[nextName]
@@ -50,6 +50,7 @@ func TestParserAsm6(t *testing.T) {
 		assert.NoError(t, parser.Read(t.Context()))
 		nodes, err := parser.TokensToAstNodes()
 		assert.NoError(t, err, "input: "+tt.input)
+		resetPositions(nodes)
 
 		expectedNodes := tt.expected()
 		assert.Len(t, nodes, len(expectedNodes), "input: "+tt.input)
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 
@@ -48,6 +49,7 @@ func TestParser_Instruction(t *testing.T) {
 		assert.NoError(t, parser.Read(t.Context()))
 		nodes, err := parser.TokensToAstNodes()
 		assert.NoError(t, err)
+		resetPositions(nodes)
 
 		expectedNodes := tt.expected()
 		assert.Len(t, nodes, len(expectedNodes), "input: "+tt.input)
@@ -115,6 +117,23 @@ func TestParser_ErrorConditions(t *testing.T) {
 		assert.Contains(t, err.Error(), "missing parameter")
 	})
 
+	t.Run("multiple errors", func(t *testing.T) {
+		parser := New(cfg.Arch, strings.NewReader(".unknown1\nnop\n.unknown2 1\n"), config.CompatDefault)
+		assert.NoError(t, parser.Read(t.Context()))
+		_, err := parser.TokensToAstNodes()
+		assert.Error(t, err)
+
+		joined, ok := err.(interface{ Unwrap() []error }) //nolint:errorlint // checking joined errors
+		assert.True(t, ok)
+		errs := joined.Unwrap()
+		assert.Len(t, errs, 2)
+
+		var parserErr *Error
+		assert.True(t, errors.As(errs[1], &parserErr))
+		assert.Equal(t, 3, parserErr.Position().Line)
+		assert.Contains(t, parserErr.Error(), "unsupported directive")
+	})
+
 	t.Run("unexpected token type", func(t *testing.T) {
 		parser := New(cfg.Arch, strings.NewReader("@"), config.CompatDefault)
 		// The lexer may handle @ as an illegal token before parser sees it
@@ -230,6 +249,76 @@ func TestParser_LabelResolvers(t *testing.T) {
 	})
 }
 
+func TestParser_NodePositions(t *testing.T) {
+	cfg := m6502Arch.New()
+	parser := New(cfg.Arch, strings.NewReader("main:\n  lda #1\n"), config.CompatDefault)
+	assert.NoError(t, parser.Read(t.Context()))
+
+	nodes, err := parser.TokensToAstNodes()
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 2)
+
+	label, ok := nodes[0].(ast.Label)
+	assert.True(t, ok)
+	assert.Equal(t, 1, label.Position().Line)
+	assert.Equal(t, 1, label.Position().Column)
+
+	instruction, ok := nodes[1].(ast.Instruction)
+	assert.True(t, ok)
+	assert.Equal(t, 2, instruction.Position().Line)
+	assert.Equal(t, 3, instruction.Position().Column)
+}
+
+func TestParser_BankFunctions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		mode     config.CompatibilityMode
+		expected []token.Type
+	}{
+		{
+			name:     "ca65 bank function",
+			input:    "lda #.bank(main)",
+			mode:     config.CompatCa65,
+			expected: []token.Type{token.Identifier, token.Number, token.Caret, token.Identifier},
+		},
+		{
+			name:     "NESASM bank function",
+			input:    "lda #BANK(main)",
+			mode:     config.CompatNesasm,
+			expected: []token.Type{token.Identifier, token.Number, token.Caret, token.Identifier},
+		},
+		{
+			name:     "bank directive",
+			input:    ".bank 1",
+			mode:     config.CompatNesasm,
+			expected: []token.Type{token.Dot, token.Identifier, token.Number},
+		},
+		{
+			name:  "bank function without dot",
+			input: "lda #bank(main)",
+			mode:  config.CompatCa65,
+			expected: []token.Type{token.Identifier, token.Number, token.Identifier,
+				token.LeftParentheses, token.Identifier, token.RightParentheses},
+		},
+	}
+
+	cfg := m6502Arch.New()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := New(cfg.Arch, strings.NewReader(tt.input), tt.mode)
+			assert.NoError(t, parser.Read(t.Context()))
+
+			types := make([]token.Type, 0, len(parser.program))
+			for _, tok := range parser.program {
+				types = append(types, tok.Type)
+			}
+			assert.Equal(t, tt.expected, types)
+		})
+	}
+}
+
 func TestParser_PreallocationBenefit(t *testing.T) {
 	cfg := m6502Arch.New()
 
@@ -243,6 +332,16 @@ func TestParser_PreallocationBenefit(t *testing.T) {
 	assert.Len(t, nodes, 1000)
 }
 
+// resetPositions clears the source positions of the parsed nodes to allow comparing
+// them to expected nodes that were created without a position.
+func resetPositions(nodes []ast.Node) {
+	for _, node := range nodes {
+		if positioner, ok := node.(ast.Positioner); ok {
+			positioner.SetPosition(token.Position{})
+		}
+	}
+}
+
 func m6502Instruction(name string, addressing int, arg ast.Node) ast.Instruction {
 	node := ast.NewInstruction(name, addressing, arg, nil)
 	node.OpcodeID = uint8(m6502.NameToOpcodeID[name])
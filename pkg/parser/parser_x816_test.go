@@ -203,5 +203,6 @@ func parseX816(t *testing.T, input string) []ast.Node {
 	assert.NoError(t, p.Read(t.Context()))
 	nodes, err := p.TokensToAstNodes()
 	assert.NoError(t, err)
+	resetPositions(nodes)
 	return nodes
 }
@@ -3,6 +3,7 @@ package retroasm
 import (
 	"context"
 	"io"
+	"io/fs"
 
 	"github.com/retroenv/retroasm/pkg/parser/ast"
 )
@@ -12,12 +13,14 @@ const (
 	FormatAsm6   = "asm6"
 	FormatCa65   = "ca65"
 	FormatNesasm = "nesasm"
+	FormatX816   = "x816"
 )
 
 // Assembler is the main interface for assembly operations.
 type Assembler interface {
 	AssembleAST(ctx context.Context, input *ASTInput) (*AssemblyOutput, error)
 	AssembleText(ctx context.Context, input *TextInput) (*AssemblyOutput, error)
+	Link(ctx context.Context, input *LinkInput) (*AssemblyOutput, error)
 	RegisterArchitecture(name string, arch Architecture) error
 	SetConfiguration(config Configuration) error
 }
@@ -25,27 +28,98 @@ type Assembler interface {
 // ASTInput represents direct AST input.
 type ASTInput struct {
 	AST        []ast.Node
-	Symbols    map[string]uint64
+	Symbols    map[string]uint64 // constants that are defined before the AST is assembled
 	SourceName string
 	BaseAddr   uint64
+	FS         fs.FS // file system that included files are read from, the disk is used if nil
+
+	// replace conditional branches whose target is out of range by an inverted branch
+	// over a jump, every replaced branch is reported as info diagnostic
+	LongBranches bool
 }
 
 // TextInput represents text-based assembly input.
 type TextInput struct {
 	Source     io.Reader
 	SourceName string
-	Format     string // "asm6", "ca65", "nesasm"
-	ConfigFile string // optional ca65 config file path
+	OutputName string            // name of the output file, replaces %O in the file names of memory areas
+	Format     string            // "asm6", "ca65", "nesasm", "x816", detected from the source if empty
+	ConfigFile string            // optional ca65 config file path
+	Symbols    map[string]uint64 // constants that are defined before the source is parsed
+	Listing    *ListingOptions   // generate a listing of the assembled program if set
+	DebugInfo  *DebugInfoOptions // generate a ca65 debug info file if set
+	Object     bool              // output a relocatable object file for Link instead of a binary
+	AutoImport bool              // import undefined symbols in object mode instead of requiring .import
+	FS         fs.FS             // file system that included files and the config file are read from, the disk is used if nil
+
+	// directories that included files are searched in if they are not found relative
+	// to the including file
+	IncludeDirs []string
+
+	// additional source files that are assembled together with Source into one binary,
+	// every file has its own file scope and sees the symbols that the other files export
+	Files []SourceFile
+
+	// replace conditional branches whose target is out of range by an inverted branch
+	// over a jump, every replaced branch is reported as info diagnostic
+	LongBranches bool
+}
+
+// SourceFile is an additional source file of a program that is assembled from multiple files.
+type SourceFile struct {
+	Name   string
+	Source io.Reader
+}
+
+// LinkInput represents object files that are linked to a binary.
+type LinkInput struct {
+	Objects    []ObjectFile
+	OutputName string // name of the output file, replaces %O in the file names of memory areas
+	ConfigFile string // optional ca65 config file path that the segments are placed by
 	Symbols    map[string]uint64
 }
 
+// ObjectFile is an object file that was created by assembling text input in object mode.
+type ObjectFile struct {
+	Name string
+	Data []byte
+}
+
+// OutputFile is an additional output file of the assembled program. Memory areas of the
+// config are written to it if their file attribute names a different file than the output.
+type OutputFile struct {
+	Name string
+	Data []byte
+}
+
+// ListingOptions configures the listing that shows every source line next to its
+// assigned address and emitted bytes.
+type ListingOptions struct {
+	Cycles bool // show the cycle counts of instructions
+}
+
+// DebugInfoOptions configures the ld65 compatible debug info file that describes the
+// source files, lines, segments, scopes and symbols of the assembled program.
+type DebugInfoOptions struct {
+	OutputName string // name of the output binary that segments reference, defaults to TextInput.OutputName
+}
+
 // AssemblyOutput contains the results of assembly.
+// If the assembly fails, the output is returned together with the error and
+// contains a diagnostic for every problem that was found.
 type AssemblyOutput struct {
 	Binary      []byte
+	Files       []OutputFile // additional output files, in the order of their first memory area
 	AST         []ast.Node
 	Symbols     map[string]Symbol
 	Segments    []Segment
 	Diagnostics []Diagnostic
+	Listing     string // listing of the assembled program, if requested by the input
+	DebugInfo   string // ca65 debug info file content, if requested by the input
+
+	// names of all files that were read, the source files, included files and the config
+	// file, in the order of their first read
+	Dependencies []string
 }
 
 // Symbol represents a symbol definition.
@@ -55,6 +129,9 @@ type Symbol struct {
 	Type     SymbolType
 	Segment  string
 	Location SourceLocation
+
+	InOutput   bool   // whether the symbol is located in the output binary or an additional output file
+	FileOffset uint64 // offset of the symbol in the output file of its segment, only set if InOutput is set
 }
 
 // SymbolType represents the type of a symbol.
@@ -64,23 +141,37 @@ const (
 	SymbolTypeLabel SymbolType = iota
 	SymbolTypeConstant
 	SymbolTypeVariable
+	SymbolTypeFunction
+	SymbolTypeAlias // reassignable symbol defined by =
 )
 
 // Segment represents a memory segment.
 type Segment struct {
-	Name      string
-	StartAddr uint64
-	Size      uint64
-	Data      []byte
+	Name           string
+	Memory         string // memory area that the segment is loaded into
+	StartAddr      uint64
+	Size           uint64 // used size in bytes
+	ConfiguredSize uint64 // available size in bytes
+	Data           []byte
+	InOutput       bool   // whether the segment is part of the output binary or an additional output file
+	OutputFile     string // name of the additional output file of the segment, empty for the output binary
+	FileOffset     uint64 // offset of StartAddr in its output file, only set if InOutput is set
 }
 
 // Diagnostic represents a warning or error from assembly.
 type Diagnostic struct {
-	Level    DiagnosticLevel
-	Message  string
+	Level      DiagnosticLevel
+	Message    string
+	Location   SourceLocation
+	Expansions []Expansion // include and macro usages that led to Location, innermost first
+	Code       string
+	Hints      []string
+}
+
+// Expansion describes an include directive or macro usage that source code was expanded from.
+type Expansion struct {
+	Macro    string // name of the expanded macro, empty for an include
 	Location SourceLocation
-	Code     string
-	Hints    []string
 }
 
 // DiagnosticLevel represents the severity of a diagnostic.
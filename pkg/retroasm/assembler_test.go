@@ -3,10 +3,15 @@ package retroasm
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
 
 	"github.com/retroenv/retroasm/pkg/arch/m6502"
+	"github.com/retroenv/retroasm/pkg/assembler/config"
 	"github.com/retroenv/retroasm/pkg/parser/ast"
 	"github.com/retroenv/retrogolib/arch"
 	cpu "github.com/retroenv/retrogolib/arch/cpu/m6502"
@@ -161,6 +166,23 @@ func TestTextAssembly(t *testing.T) {
 			},
 			expectedBinary: []byte{0xA9, 0x01}, // LDA #$01
 		},
+		{
+			name: "detected format",
+			input: &TextInput{
+				Source:     strings.NewReader(".segment \"CODE\"\nLDA #$01"),
+				SourceName: testFilename,
+			},
+			expectedBinary: []byte{0xA9, 0x01}, // LDA #$01
+		},
+		{
+			name: "invalid format",
+			input: &TextInput{
+				Source:     strings.NewReader("LDA #$01"),
+				SourceName: testFilename,
+				Format:     "unknown",
+			},
+			expectedErr: config.ErrInvalidCompatibilityMode,
+		},
 		{
 			name:        "nil input",
 			input:       nil,
@@ -199,6 +221,543 @@ func TestTextAssembly(t *testing.T) {
 	}
 }
 
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"nesasm header", ".inesprg 1 ; 1x 16KB PRG\n.segment \"CODE\"\n", FormatNesasm},
+		{"ca65 segment", "  .segment \"CODE\"\nlda #1\n", FormatCa65},
+		{"ca65 proc", "main: .PROC\n.endproc\n", FormatCa65},
+		{"x816 mem", ".mem 8\n.index 16\nlda #1\n", FormatX816},
+		{"x816 rom mode", "  .hirom\n", FormatX816},
+		{"asm6f unstable", ".unstable\nslo $10\n", FormatAsm6},
+		{"marker in comment", "nop ; .segment\n", ""},
+		{"no marker", "lda #1\n", ""},
+		{"marker after long line", ".byte " + strings.Repeat("0,", 40000) + "0\n.segment \"CODE\"\n", FormatCa65},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DetectFormat([]byte(tt.source)))
+		})
+	}
+}
+
+func TestTextAssemblySymbols(t *testing.T) {
+	const source = `.segment "CODE"
+SIZE = 4
+.proc main
+loop:
+  jmp loop
+.endproc
+`
+
+	assembler := New()
+	output, err := assembler.AssembleText(t.Context(), &TextInput{
+		Source:     strings.NewReader(source),
+		SourceName: testFilename,
+		Symbols:    map[string]uint64{"input": 1},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, output.Symbols, 4)
+
+	assert.Equal(t, SymbolTypeConstant, output.Symbols["input"].Type)
+	assert.Equal(t, SymbolTypeAlias, output.Symbols["SIZE"].Type)
+	assert.Equal(t, uint64(4), output.Symbols["SIZE"].Value)
+
+	main := output.Symbols["main"]
+	assert.Equal(t, SymbolTypeFunction, main.Type)
+	assert.Equal(t, uint64(0x8000), main.Value)
+	assert.Equal(t, "CODE", main.Segment)
+
+	loop := output.Symbols["main::loop"]
+	assert.Equal(t, SymbolTypeLabel, loop.Type)
+	assert.Equal(t, uint64(0x8000), loop.Value)
+	assert.Equal(t, SourceLocation{Filename: testFilename, Line: 4, Column: 1}, loop.Location)
+}
+
+func TestTextAssemblyDefines(t *testing.T) {
+	const source = `.segment "CODE"
+.ifdef PAL
+  lda #REGION
+.endif
+.if DEBUG
+  brk
+.endif
+`
+
+	assembler := New()
+	output, err := assembler.AssembleText(t.Context(), &TextInput{
+		Source:     strings.NewReader(source),
+		SourceName: testFilename,
+		Symbols:    map[string]uint64{"PAL": 1, "REGION": 2, "DEBUG": 0},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xa9, 0x02}, output.Binary)
+	assert.Equal(t, SymbolTypeConstant, output.Symbols["REGION"].Type)
+}
+
+func TestTextAssemblyConcurrent(t *testing.T) {
+	const source = `.segment "CODE"
+  lda #REGION
+`
+
+	// all runs share the config of the registered architecture adapter
+	assembler := New()
+	m6502Arch := m6502.New()
+	adapter := NewArchitectureAdapter(string(arch.M6502), m6502Arch, m6502Arch)
+	assert.NoError(t, assembler.RegisterArchitecture(string(arch.M6502), adapter))
+
+	formats := []string{"asm6", "ca65", "nesasm", "x816"}
+	errs := make([]error, 16)
+	binaries := make([][]byte, len(errs))
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			output, err := assembler.AssembleText(t.Context(), &TextInput{
+				Source:     strings.NewReader(source),
+				SourceName: testFilename,
+				Format:     formats[i%len(formats)],
+				Symbols:    map[string]uint64{"REGION": uint64(i)},
+			})
+			errs[i] = err
+			if err == nil {
+				binaries[i] = output.Binary
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0xa9, byte(i)}, binaries[i])
+	}
+}
+
+func TestTextAssemblyLongBranches(t *testing.T) {
+	const source = `.segment "CODE"
+  bne far
+  .res 200
+far:
+`
+
+	assembler := New()
+	output, err := assembler.AssembleText(t.Context(), &TextInput{
+		Source:       strings.NewReader(source),
+		SourceName:   testFilename,
+		LongBranches: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xf0, 0x03, 0x4c, 0xcd, 0x80}, output.Binary[:5])
+
+	assert.Len(t, output.Diagnostics, 1)
+	diag := output.Diagnostics[0]
+	assert.Equal(t, DiagnosticInfo, diag.Level)
+	assert.Equal(t, "long-branch", diag.Code)
+	assert.Equal(t, SourceLocation{Filename: testFilename, Line: 2, Column: 3}, diag.Location)
+}
+
+func TestTextAssemblySegments(t *testing.T) {
+	assembler := New()
+	output, err := assembler.AssembleText(t.Context(), &TextInput{
+		Source:     strings.NewReader(".segment \"CODE\"\nLDA #$01\nSTA $0200\n"),
+		SourceName: testFilename,
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, output.Segments, 1)
+	assert.Equal(t, Segment{
+		Name:           "CODE",
+		Memory:         "CODE",
+		StartAddr:      0x8000,
+		Size:           5,
+		ConfiguredSize: 0x8000,
+		Data:           []byte{0xA9, 0x01, 0x8D, 0x00, 0x02},
+		InOutput:       true,
+	}, output.Segments[0])
+}
+
+func TestTextAssemblyConfigExpressions(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "memory.cfg")
+	cfg := `SYMBOLS { __ROMSIZE__: type = weak, value = $4000; }
+MEMORY { ROM: start = $10000 - __ROMSIZE__ * BANKS, size = __ROMSIZE__ * BANKS; }
+SEGMENTS { CODE: load = ROM, type = ro; }
+`
+	assert.NoError(t, os.WriteFile(configFile, []byte(cfg), 0o644))
+
+	assembler := New()
+	output, err := assembler.AssembleText(t.Context(), &TextInput{
+		Source:     strings.NewReader(".segment \"CODE\"\nstart:\nnop\n"),
+		SourceName: testFilename,
+		ConfigFile: configFile,
+		Symbols:    map[string]uint64{"BANKS": 2},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0x8000), output.Symbols["start"].Value)
+	assert.Equal(t, uint64(0x4000), output.Symbols["__ROMSIZE__"].Value)
+}
+
+func TestTextAssemblyOutputFiles(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "memory.cfg")
+	cfg := `MEMORY {
+    PRG: start = $8000, size = $100;
+    CHR: start = $0000, size = $100, file = "%O.chr";
+}
+SEGMENTS {
+    CODE:  load = PRG, type = ro;
+    CHARS: load = CHR, type = ro;
+}
+`
+	assert.NoError(t, os.WriteFile(configFile, []byte(cfg), 0o644))
+
+	assembler := New()
+	output, err := assembler.AssembleText(t.Context(), &TextInput{
+		Source:     strings.NewReader(".segment \"CODE\"\nnop\n.segment \"CHARS\"\n.byte 1, 2\n"),
+		SourceName: testFilename,
+		ConfigFile: configFile,
+		OutputName: "game.bin",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xea}, output.Binary)
+	assert.Len(t, output.Files, 1)
+	assert.Equal(t, "game.bin.chr", output.Files[0].Name)
+	assert.Equal(t, []byte{1, 2}, output.Files[0].Data)
+
+	for _, seg := range output.Segments {
+		if seg.Name == "CHARS" {
+			assert.Equal(t, "game.bin.chr", seg.OutputFile)
+		}
+	}
+}
+
+func TestLinkObjects(t *testing.T) {
+	assembler := New()
+
+	sources := map[string]string{
+		"main.o": ".import sub\n.segment \"CODE\"\nstart:\njsr sub\njmp start\n",
+		"sub.o":  ".export sub\n.segment \"CODE\"\nsub:\nrts\n",
+	}
+	var objects []ObjectFile
+	for _, name := range []string{"main.o", "sub.o"} {
+		output, err := assembler.AssembleText(t.Context(), &TextInput{
+			Source:     strings.NewReader(sources[name]),
+			SourceName: name,
+			Object:     true,
+		})
+		assert.NoError(t, err)
+		objects = append(objects, ObjectFile{Name: name, Data: output.Binary})
+	}
+
+	output, err := assembler.Link(t.Context(), &LinkInput{Objects: objects})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x20, 0x06, 0x80, 0x4c, 0x00, 0x80, 0x60}, output.Binary)
+	assert.Equal(t, uint64(0x8006), output.Symbols["sub"].Value)
+
+	configFile := filepath.Join(t.TempDir(), "memory.cfg")
+	assert.NoError(t, os.WriteFile(configFile, []byte(defaultConfig), 0o644))
+	output, err = assembler.Link(t.Context(), &LinkInput{Objects: objects, ConfigFile: configFile})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{configFile}, output.Dependencies)
+
+	output, err = assembler.Link(t.Context(), &LinkInput{Objects: objects[:1]})
+	assert.Error(t, err)
+	assert.Len(t, output.Diagnostics, 1)
+}
+
+func TestTextAssemblyIncludeDirs(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0o755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "include"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "src", "local.asm"), []byte("lda #$01\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "include", "common.asm"), []byte("ldx #$02\n"), 0o644))
+
+	assembler := New()
+	output, err := assembler.AssembleText(t.Context(), &TextInput{
+		Source:      strings.NewReader(".segment \"CODE\"\n.include \"local.asm\"\n.include \"common.asm\"\n"),
+		SourceName:  filepath.Join(dir, "src", "main.asm"),
+		IncludeDirs: []string{filepath.Join(dir, "include")},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xa9, 0x01, 0xa2, 0x02}, output.Binary)
+}
+
+func TestTextAssemblyFileSystem(t *testing.T) {
+	fsys := fstest.MapFS{
+		"memory.cfg":       {Data: []byte("MEMORY { PRG: start = $C000, size = $100; }\nSEGMENTS { CODE: load = PRG, type = ro; }\n")},
+		"src/main.asm":     {Data: []byte(".segment \"CODE\"\n.include \"defs.asm\"\n.incbin \"data.bin\"\n")},
+		"src/defs.asm":     {Data: []byte("lda #$01\n")},
+		"include/data.bin": {Data: []byte{0xfe, 0xff}},
+	}
+	source, err := fsys.Open("src/main.asm")
+	assert.NoError(t, err)
+
+	assembler := New()
+	output, err := assembler.AssembleText(t.Context(), &TextInput{
+		Source:      source,
+		SourceName:  "src/main.asm",
+		ConfigFile:  "memory.cfg",
+		IncludeDirs: []string{"include"},
+		FS:          fsys,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xa9, 0x01, 0xfe, 0xff}, output.Binary)
+	assert.Equal(t, uint64(0xc000), output.Segments[0].StartAddr)
+	assert.Equal(t, []string{"src/main.asm", "src/defs.asm", "include/data.bin", "memory.cfg"}, output.Dependencies)
+
+	output, err = assembler.AssembleAST(t.Context(), &ASTInput{
+		AST: []ast.Node{ast.NewInclude("include/data.bin", true, 0, 0)},
+		FS:  fsys,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xfe, 0xff}, output.Binary)
+}
+
+func TestTextAssemblyFiles(t *testing.T) {
+	assembler := New()
+	output, err := assembler.AssembleText(t.Context(), &TextInput{
+		Source:     strings.NewReader(".import sub\n.segment \"CODE\"\nstart:\njsr sub\njmp start\n"),
+		SourceName: "main.asm",
+		Files: []SourceFile{
+			{Name: "sub.asm", Source: strings.NewReader(".export sub\n.segment \"CODE\"\nstart:\nsub:\nrts\n")},
+		},
+		Listing: &ListingOptions{},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x20, 0x06, 0x80, 0x4c, 0x00, 0x80, 0x60}, output.Binary)
+	assert.Equal(t, uint64(0x8006), output.Symbols["sub"].Value)
+	assert.Contains(t, output.Listing, "8006  60               rts")
+
+	_, err = assembler.AssembleText(t.Context(), &TextInput{
+		Source: strings.NewReader(".segment \"CODE\"\nnop\n"),
+		Files:  []SourceFile{{Name: "sub.asm"}},
+	})
+	assert.ErrorIs(t, err, ErrNilSource)
+}
+
+func TestTextAssemblyListing(t *testing.T) {
+	assembler := New()
+	output, err := assembler.AssembleText(t.Context(), &TextInput{
+		Source:     strings.NewReader(".segment \"CODE\"\nLDA #$01\nSTA $0200\n"),
+		SourceName: testFilename,
+		Listing:    &ListingOptions{Cycles: true},
+	})
+	assert.NoError(t, err)
+
+	expected := `     1                              .segment "CODE"
+     2  8000  A9 01            2    LDA #$01
+     3  8002  8D 00 02         4    STA $0200
+`
+	assert.Equal(t, expected, output.Listing)
+
+	output, err = assembler.AssembleText(t.Context(), &TextInput{
+		Source: strings.NewReader(".segment \"CODE\"\nNOP\n"),
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, output.Listing)
+}
+
+func TestTextAssemblyDebugInfo(t *testing.T) {
+	assembler := New()
+	output, err := assembler.AssembleText(t.Context(), &TextInput{
+		Source:     strings.NewReader(".segment \"CODE\"\nstart:\nLDA #$01\n"),
+		SourceName: testFilename,
+		DebugInfo:  &DebugInfoOptions{OutputName: "test.bin"},
+	})
+	assert.NoError(t, err)
+
+	assert.Contains(t, output.DebugInfo, "file\tid=0,name=\"test.asm\"")
+	assert.Contains(t, output.DebugInfo, "line\tid=0,file=0,line=3,span=0\n")
+	assert.Contains(t, output.DebugInfo, "seg\tid=0,name=\"CODE\",start=0x008000,size=0x0002,addrsize=absolute,type=rw,oname=\"test.bin\",ooffs=0\n")
+	assert.Contains(t, output.DebugInfo, "sym\tid=0,name=\"start\",addrsize=absolute,scope=0,def=1,val=0x8000,seg=0,type=lab\n")
+}
+
+func TestExportSymbols(t *testing.T) {
+	header := []byte{'N', 'E', 'S', 0x1a, 2, 1, 0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	output := &AssemblyOutput{
+		Binary: header,
+		Symbols: map[string]Symbol{
+			"reset": {Name: "reset", Value: 0x8000, Type: SymbolTypeFunction, Segment: "CODE",
+				InOutput: true, FileOffset: 0x10},
+			"main::loop": {Name: "main::loop", Value: 0x8003, Type: SymbolTypeLabel, Segment: "CODE",
+				InOutput: true, FileOffset: 0x13},
+			"bank1": {Name: "bank1", Value: 0xC010, Type: SymbolTypeLabel, Segment: "BANK1",
+				InOutput: true, FileOffset: 0x4020},
+			"counter": {Name: "counter", Value: 0x0010, Type: SymbolTypeVariable, Segment: "ZEROPAGE"},
+			"save":    {Name: "save", Value: 0x6002, Type: SymbolTypeVariable, Segment: "SRAM"},
+			"tiles": {Name: "tiles", Value: 0x0000, Type: SymbolTypeLabel, Segment: "CHARS",
+				InOutput: true, FileOffset: 0x8010},
+			"PLAYER_LIFE": {Name: "PLAYER_LIFE", Value: 3, Type: SymbolTypeConstant},
+		},
+		Segments: []Segment{
+			{Name: "CODE", StartAddr: 0x8000, InOutput: true, FileOffset: 0x10},
+			{Name: "BANK1", StartAddr: 0xC000, InOutput: true, FileOffset: 0x4010},
+			{Name: "CHARS", StartAddr: 0x0000, InOutput: true, FileOffset: 0x8010},
+			{Name: "ZEROPAGE", StartAddr: 0x0000},
+			{Name: "SRAM", StartAddr: 0x6000},
+		},
+	}
+
+	files, err := ExportSymbols(output, SymbolFormatMesen, "game.nes")
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "game.mlb", files[0].Name)
+	assert.Equal(t, "P:0000:reset\nP:0003:main_loop\nP:4010:bank1\nR:0010:counter\nS:0002:save\n",
+		string(files[0].Data))
+
+	files, err = ExportSymbols(output, SymbolFormatFCEUX, "game.nes")
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+	assert.Equal(t, SymbolFile{Name: "game.nes.0.nl", Data: []byte("$8000#reset#\n$8003#main_loop#\n")}, files[0])
+	assert.Equal(t, SymbolFile{Name: "game.nes.1.nl", Data: []byte("$C010#bank1#\n")}, files[1])
+	assert.Equal(t, SymbolFile{Name: "game.nes.ram.nl", Data: []byte("$0010#counter#\n$6002#save#\n")}, files[2])
+
+	files, err = ExportSymbols(output, SymbolFormatGeneric, "game.nes")
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "game.sym", files[0].Name)
+	expected := `PLAYER_LIFE = $0003
+bank1 = $C010
+counter = $0010
+main::loop = $8003
+reset = $8000
+save = $6002
+tiles = $0000
+`
+	assert.Equal(t, expected, string(files[0].Data))
+
+	_, err = ExportSymbols(output, "unknown", "game.nes")
+	assert.ErrorIs(t, err, ErrUnsupportedSymbolFormat)
+}
+
+func TestExportSymbolsBankedROM(t *testing.T) {
+	const source = `  .inesprg 1
+  .ineschr 1
+  .inesmap 0
+  .inesmir 1
+
+  .bank 0
+  .rsset $0010
+counter .rs 1
+  .org $C000
+reset:
+  lda counter
+  jmp main
+
+  .bank 1
+  .org $E000
+main:
+  jmp reset
+  .org $FFFC
+  .dw reset
+
+  .bank 2
+  .org $0000
+tiles:
+  .db 1,2,3
+  .org $1FFF
+  .db 0
+`
+
+	output, err := New().AssembleText(t.Context(), &TextInput{
+		Source:     strings.NewReader(source),
+		SourceName: testFilename,
+		Format:     FormatNesasm,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0x2010), output.Symbols["main"].FileOffset)
+	assert.Equal(t, uint64(0x4010), output.Symbols["tiles"].FileOffset)
+	assert.False(t, output.Symbols["counter"].InOutput)
+
+	files, err := ExportSymbols(output, SymbolFormatMesen, "game.nes")
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "P:0000:reset\nP:2000:main\nR:0010:counter\n", string(files[0].Data))
+
+	files, err = ExportSymbols(output, SymbolFormatFCEUX, "game.nes")
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.Equal(t, SymbolFile{Name: "game.nes.0.nl", Data: []byte("$C000#reset#\n$E000#main#\n")}, files[0])
+	assert.Equal(t, SymbolFile{Name: "game.nes.ram.nl", Data: []byte("$0010#counter#\n")}, files[1])
+}
+
+func TestTextAssemblyDiagnostics(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		code     string
+		expected []SourceLocation
+	}{
+		{
+			name:   "syntax errors",
+			source: ".segment \"CODE\"\n.unknown1\nnop\n.unknown2\n",
+			code:   "syntax",
+			expected: []SourceLocation{
+				{Filename: testFilename, Line: 2, Column: 1},
+				{Filename: testFilename, Line: 4, Column: 1},
+			},
+		},
+		{
+			name:   "undefined symbols",
+			source: ".segment \"CODE\"\nlda missing1\nnop\n  sta missing2\n",
+			code:   "undefined-symbol",
+			expected: []SourceLocation{
+				{Filename: testFilename, Line: 2, Column: 1},
+				{Filename: testFilename, Line: 4, Column: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assembler := New()
+			output, err := assembler.AssembleText(t.Context(), &TextInput{
+				Source:     strings.NewReader(tt.source),
+				SourceName: testFilename,
+				Format:     FormatCa65,
+			})
+			assert.Error(t, err)
+			assert.NotNil(t, output)
+			assert.Len(t, output.Diagnostics, len(tt.expected))
+
+			for i, diag := range output.Diagnostics {
+				assert.Equal(t, DiagnosticError, diag.Level)
+				assert.Equal(t, tt.code, diag.Code)
+				assert.Equal(t, tt.expected[i], diag.Location)
+				assert.NotEmpty(t, diag.Message)
+			}
+		})
+	}
+}
+
+func TestTextAssemblyDiagnosticExpansions(t *testing.T) {
+	const source = `.segment "CODE"
+MACRO store value
+  lda #value
+  sta missing
+ENDM
+store 1
+`
+
+	assembler := New()
+	output, err := assembler.AssembleText(t.Context(), &TextInput{
+		Source:     strings.NewReader(source),
+		SourceName: testFilename,
+		Format:     FormatAsm6,
+	})
+	assert.Error(t, err)
+	assert.Len(t, output.Diagnostics, 1)
+
+	diag := output.Diagnostics[0]
+	assert.Equal(t, SourceLocation{Filename: testFilename, Line: 4, Column: 3}, diag.Location)
+	assert.Equal(t, []Expansion{
+		{Macro: "store", Location: SourceLocation{Filename: testFilename, Line: 6, Column: 1}},
+	}, diag.Expansions)
+}
+
 func TestConfigurationBuilder(t *testing.T) {
 	config := NewConfigurationBuilder().
 		SetSymbol("test", 0x1000).
@@ -381,6 +940,8 @@ func TestSymbolTypes(t *testing.T) {
 	assert.Equal(t, SymbolType(0), SymbolTypeLabel)
 	assert.Equal(t, SymbolType(1), SymbolTypeConstant)
 	assert.Equal(t, SymbolType(2), SymbolTypeVariable)
+	assert.Equal(t, SymbolType(3), SymbolTypeFunction)
+	assert.Equal(t, SymbolType(4), SymbolTypeAlias)
 }
 
 func TestDiagnosticLevels(t *testing.T) {
@@ -5,13 +5,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/retroenv/retroasm/pkg/arch/m6502"
 	"github.com/retroenv/retroasm/pkg/assembler"
 	"github.com/retroenv/retroasm/pkg/assembler/config"
+	"github.com/retroenv/retroasm/pkg/lexer/token"
 	"github.com/retroenv/retroasm/pkg/parser/ast"
+	"github.com/retroenv/retroasm/pkg/scope"
 )
 
 // Sentinel errors.
@@ -64,12 +68,16 @@ func (a *ArchitectureAdapter[T]) CreateAssembler(cfg ArchitectureConfig) (Archit
 	}, nil
 }
 
-func (a *ArchitectureAdapter[T]) assembleAST(ctx context.Context, nodes []ast.Node, baseAddress uint64) ([]byte, error) {
-	return assembleASTWithConfig(ctx, a.config, nodes, baseAddress)
+func (a *ArchitectureAdapter[T]) assembleAST(ctx context.Context, source *astSource) (*assemblyResult, error) {
+	return assembleASTWithConfig(ctx, a.config, source)
 }
 
-func (a *ArchitectureAdapter[T]) assembleText(ctx context.Context, source anyReader, configFile string) ([]byte, error) {
-	return assembleTextWithConfig(ctx, a.config, source, configFile)
+func (a *ArchitectureAdapter[T]) assembleText(ctx context.Context, source *textSource) (*assemblyResult, error) {
+	return assembleTextWithConfig(ctx, a.config, source)
+}
+
+func (a *ArchitectureAdapter[T]) link(ctx context.Context, source *linkSource) (*assemblyResult, error) {
+	return linkWithConfig(ctx, a.config, source)
 }
 
 type anyReader interface {
@@ -77,8 +85,59 @@ type anyReader interface {
 }
 
 type architectureDispatcher interface {
-	assembleAST(ctx context.Context, nodes []ast.Node, baseAddress uint64) ([]byte, error)
-	assembleText(ctx context.Context, source anyReader, configFile string) ([]byte, error)
+	assembleAST(ctx context.Context, source *astSource) (*assemblyResult, error)
+	assembleText(ctx context.Context, source *textSource) (*assemblyResult, error)
+	link(ctx context.Context, source *linkSource) (*assemblyResult, error)
+}
+
+// astSource contains the resolved AST input of an assembler run.
+type astSource struct {
+	nodes       []ast.Node
+	baseAddress uint64
+	defines     map[string]uint64 // symbols that are defined before the AST is parsed
+	fsys        fs.FS             // file system that included files are read from, disk if nil
+
+	longBranches bool // expand branches whose target is out of range
+}
+
+// textSource contains the resolved text input of an assembler run.
+type textSource struct {
+	reader      anyReader
+	name        string
+	outputName  string // name of the main output file, replaces %O in memory file names
+	configFile  string
+	defines     map[string]uint64 // symbols that the source and expressions of the config can reference
+	mode        config.CompatibilityMode
+	listing     *ListingOptions
+	debugInfo   *DebugInfoOptions
+	object      bool // write a relocatable object file instead of a binary
+	autoImport  bool // import undefined symbols in object mode
+	files       []assembler.SourceFile
+	includeDirs []string
+	fsys        fs.FS // file system that included files and the config are read from, disk if nil
+
+	longBranches bool // expand branches whose target is out of range
+}
+
+// linkSource contains the resolved input of a link run.
+type linkSource struct {
+	objects    []*assembler.Object
+	outputName string // name of the main output file, replaces %O in memory file names
+	configFile string
+	defines    map[string]uint64 // symbols that expressions of the config can reference
+}
+
+// assemblyResult contains the architecture independent results of an assembler run.
+type assemblyResult struct {
+	binary    []byte
+	symbols   []assembler.Symbol
+	segments  []assembler.SegmentUsage
+	files     []OutputFile
+	listing   string
+	debugInfo string
+
+	dependencies []string               // names of all read files
+	notes        []assembler.Diagnostic // diagnostics of the successful run
 }
 
 type configDispatcher[T any] struct {
@@ -99,12 +158,16 @@ func newConfigDispatcher[T any](cfg *config.Config[T]) architectureDispatcher {
 	return &configDispatcher[T]{config: cfg}
 }
 
-func (d *configDispatcher[T]) assembleAST(ctx context.Context, nodes []ast.Node, baseAddress uint64) ([]byte, error) {
-	return assembleASTWithConfig(ctx, d.config, nodes, baseAddress)
+func (d *configDispatcher[T]) assembleAST(ctx context.Context, source *astSource) (*assemblyResult, error) {
+	return assembleASTWithConfig(ctx, d.config, source)
 }
 
-func (d *configDispatcher[T]) assembleText(ctx context.Context, source anyReader, configFile string) ([]byte, error) {
-	return assembleTextWithConfig(ctx, d.config, source, configFile)
+func (d *configDispatcher[T]) assembleText(ctx context.Context, source *textSource) (*assemblyResult, error) {
+	return assembleTextWithConfig(ctx, d.config, source)
+}
+
+func (d *configDispatcher[T]) link(ctx context.Context, source *linkSource) (*assemblyResult, error) {
+	return linkWithConfig(ctx, d.config, source)
 }
 
 func (a *defaultAssembler) RegisterArchitecture(name string, arch Architecture) error {
@@ -141,18 +204,32 @@ func (a *defaultAssembler) AssembleAST(ctx context.Context, input *ASTInput) (*A
 		return nil, fmt.Errorf("resolving architecture: %w", err)
 	}
 
-	binary, err := dispatcher.assembleAST(ctx, nodes, input.BaseAddr)
+	result, err := dispatcher.assembleAST(ctx, &astSource{
+		nodes:       nodes,
+		baseAddress: input.BaseAddr,
+		defines:     input.Symbols,
+		fsys:        input.FS,
+
+		longBranches: input.LongBranches,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("assembling AST: %w", err)
+		output := &AssemblyOutput{
+			AST:         input.AST,
+			Diagnostics: outputDiagnostics(err, input.SourceName),
+		}
+		return output, fmt.Errorf("assembling AST: %w", err)
 	}
 
-	result := &AssemblyOutput{
-		Binary:  binary,
-		AST:     input.AST,
-		Symbols: copyInputSymbols(input.Symbols, input.SourceName),
+	output := &AssemblyOutput{
+		Binary:       result.binary,
+		AST:          input.AST,
+		Symbols:      outputSymbols(input.Symbols, result.symbols, input.SourceName),
+		Segments:     outputSegments(result.segments),
+		Diagnostics:  outputNotes(result.notes, input.SourceName),
+		Dependencies: result.dependencies,
 	}
 
-	return result, nil
+	return output, nil
 }
 
 func (a *defaultAssembler) AssembleText(ctx context.Context, input *TextInput) (*AssemblyOutput, error) {
@@ -168,17 +245,99 @@ func (a *defaultAssembler) AssembleText(ctx context.Context, input *TextInput) (
 		return nil, fmt.Errorf("resolving architecture: %w", err)
 	}
 
-	binary, err := dispatcher.assembleText(ctx, input.Source, input.ConfigFile)
+	mode, source, err := resolveCompatibilityMode(input.Format, input.Source)
 	if err != nil {
-		return nil, fmt.Errorf("assembling text: %w", err)
+		return nil, fmt.Errorf("resolving source format: %w", err)
 	}
 
-	result := &AssemblyOutput{
-		Binary:  binary,
-		Symbols: copyInputSymbols(input.Symbols, input.SourceName),
+	files := make([]assembler.SourceFile, 0, len(input.Files))
+	for _, file := range input.Files {
+		if file.Source == nil {
+			return nil, fmt.Errorf("source file '%s': %w", file.Name, ErrNilSource)
+		}
+		files = append(files, assembler.SourceFile{Name: file.Name, Reader: file.Source})
 	}
 
-	return result, nil
+	result, err := dispatcher.assembleText(ctx, &textSource{
+		reader:      source,
+		name:        input.SourceName,
+		outputName:  input.OutputName,
+		configFile:  input.ConfigFile,
+		defines:     input.Symbols,
+		mode:        mode,
+		listing:     input.Listing,
+		debugInfo:   input.DebugInfo,
+		object:      input.Object,
+		autoImport:  input.AutoImport,
+		files:       files,
+		includeDirs: input.IncludeDirs,
+		fsys:        input.FS,
+
+		longBranches: input.LongBranches,
+	})
+	if err != nil {
+		output := &AssemblyOutput{
+			Diagnostics: outputDiagnostics(err, input.SourceName),
+		}
+		return output, fmt.Errorf("assembling text: %w", err)
+	}
+
+	output := &AssemblyOutput{
+		Binary:       result.binary,
+		Symbols:      outputSymbols(input.Symbols, result.symbols, input.SourceName),
+		Segments:     outputSegments(result.segments),
+		Diagnostics:  outputNotes(result.notes, input.SourceName),
+		Files:        result.files,
+		Listing:      result.listing,
+		DebugInfo:    result.debugInfo,
+		Dependencies: result.dependencies,
+	}
+
+	return output, nil
+}
+
+func (a *defaultAssembler) Link(ctx context.Context, input *LinkInput) (*AssemblyOutput, error) {
+	if input == nil {
+		return nil, ErrNilInput
+	}
+
+	dispatcher, err := a.resolveArchitectureDispatcher()
+	if err != nil {
+		return nil, fmt.Errorf("resolving architecture: %w", err)
+	}
+
+	objects := make([]*assembler.Object, 0, len(input.Objects))
+	for _, file := range input.Objects {
+		obj, err := assembler.ReadObject(bytes.NewReader(file.Data))
+		if err != nil {
+			return nil, fmt.Errorf("reading object file '%s': %w", file.Name, err)
+		}
+		if obj.Source == "" {
+			obj.Source = file.Name
+		}
+		objects = append(objects, obj)
+	}
+
+	result, err := dispatcher.link(ctx, &linkSource{
+		objects:    objects,
+		outputName: input.OutputName,
+		configFile: input.ConfigFile,
+		defines:    input.Symbols,
+	})
+	if err != nil {
+		output := &AssemblyOutput{
+			Diagnostics: outputDiagnostics(err, ""),
+		}
+		return output, fmt.Errorf("linking objects: %w", err)
+	}
+
+	return &AssemblyOutput{
+		Binary:       result.binary,
+		Symbols:      outputSymbols(input.Symbols, result.symbols, ""),
+		Segments:     outputSegments(result.segments),
+		Files:        result.files,
+		Dependencies: result.dependencies,
+	}, nil
 }
 
 func (a *architectureAssembler[T]) AssembleAST(nodes []ast.Node) (*AssemblyOutput, error) {
@@ -216,56 +375,166 @@ func dispatcherForArchitecture(architecture Architecture) (architectureDispatche
 	return dispatcher, nil
 }
 
-func assembleASTWithConfig[T any](ctx context.Context, cfg *config.Config[T], nodes []ast.Node, baseAddress uint64) ([]byte, error) {
-	if err := readAssemblerConfig(cfg, ""); err != nil {
+func assembleASTWithConfig[T any](ctx context.Context, sharedConfig *config.Config[T],
+	source *astSource) (*assemblyResult, error) {
+
+	cfg := copyConfig(sharedConfig)
+	if _, err := readAssemblerConfig(cfg, nil, ""); err != nil {
 		return nil, err
 	}
 
-	applyBaseAddress(cfg, baseAddress)
+	applyBaseAddress(cfg, source.baseAddress)
 
 	var buf bytes.Buffer
 	asm := assembler.New(cfg, &buf)
+	asm.SetDefines(source.defines)
+	asm.SetLongBranches(source.longBranches)
+	if source.fsys != nil {
+		asm.SetFileSystem(source.fsys)
+	}
 
-	if err := asm.ProcessAST(ctx, nodes); err != nil {
+	if err := asm.ProcessAST(ctx, source.nodes); err != nil {
 		return nil, fmt.Errorf("processing AST: %w", err)
 	}
 
-	return buf.Bytes(), nil
+	return newAssemblyResult(asm, buf.Bytes()), nil
 }
 
-func assembleTextWithConfig[T any](ctx context.Context, cfg *config.Config[T],
-	source anyReader, configFile string) ([]byte, error) {
+func assembleTextWithConfig[T any](ctx context.Context, sharedConfig *config.Config[T],
+	source *textSource) (*assemblyResult, error) {
 
-	if err := readAssemblerConfig(cfg, configFile); err != nil {
+	cfg := copyConfig(sharedConfig)
+	cfg.Defines = source.defines
+	configFiles, err := readAssemblerConfig(cfg, source.fsys, source.configFile)
+	if err != nil {
 		return nil, err
 	}
+	cfg.CompatibilityMode = source.mode
 
 	var buf bytes.Buffer
 	asm := assembler.New(cfg, &buf)
+	asm.SetSourceName(source.name)
+	asm.SetOutputName(source.outputName)
+	asm.SetObjectMode(source.object)
+	asm.SetAutoImport(source.autoImport)
+	asm.SetIncludeDirs(source.includeDirs)
+	asm.SetDefines(source.defines)
+	asm.SetLongBranches(source.longBranches)
+	if source.fsys != nil {
+		asm.SetFileSystem(source.fsys)
+	}
 
-	if err := asm.Process(ctx, source); err != nil {
+	if len(source.files) == 0 {
+		err = asm.Process(ctx, source.reader)
+	} else {
+		// additional source files are assembled together with the source into one program
+		files := append([]assembler.SourceFile{{Name: source.name, Reader: source.reader}}, source.files...)
+		err = asm.ProcessFiles(ctx, files)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("processing text: %w", err)
 	}
 
-	return buf.Bytes(), nil
+	result := newAssemblyResult(asm, buf.Bytes())
+	result.dependencies = append(result.dependencies, configFiles...)
+	for _, file := range asm.OutputFiles() {
+		result.files = append(result.files, OutputFile{Name: file.Name, Data: file.Data})
+	}
+	if source.listing != nil {
+		var listing strings.Builder
+		options := assembler.ListingOptions{
+			Cycles: source.listing.Cycles,
+		}
+		if err := asm.WriteListing(&listing, options); err != nil {
+			return nil, fmt.Errorf("writing listing: %w", err)
+		}
+		result.listing = listing.String()
+	}
+	if source.debugInfo != nil {
+		var debugInfo strings.Builder
+		outputName := source.debugInfo.OutputName
+		if outputName == "" {
+			outputName = source.outputName
+		}
+		if err := asm.WriteDebugInfo(&debugInfo, outputName); err != nil {
+			return nil, fmt.Errorf("writing debug info: %w", err)
+		}
+		result.debugInfo = debugInfo.String()
+	}
+	return result, nil
+}
+
+func linkWithConfig[T any](ctx context.Context, sharedConfig *config.Config[T],
+	source *linkSource) (*assemblyResult, error) {
+
+	cfg := copyConfig(sharedConfig)
+	cfg.Defines = source.defines
+	configFiles, err := readAssemblerConfig(cfg, nil, source.configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	asm := assembler.New(cfg, &buf)
+	asm.SetOutputName(source.outputName)
+
+	if err := asm.Link(ctx, source.objects); err != nil {
+		return nil, fmt.Errorf("linking object files: %w", err)
+	}
+
+	result := newAssemblyResult(asm, buf.Bytes())
+	result.dependencies = append(result.dependencies, configFiles...)
+	for _, file := range asm.OutputFiles() {
+		result.files = append(result.files, OutputFile{Name: file.Name, Data: file.Data})
+	}
+	return result, nil
+}
+
+// copyConfig returns a shallow copy of the adapter config for a single assembler run.
+// The adapter can be used concurrently and reading the config file replaces all config
+// sections of the copy instead of modifying the shared ones.
+func copyConfig[T any](cfg *config.Config[T]) *config.Config[T] {
+	c := *cfg
+	return &c
 }
 
-func readAssemblerConfig[T any](cfg *config.Config[T], configFile string) error {
+func newAssemblyResult[T any](asm *assembler.Assembler[T], binary []byte) *assemblyResult {
+	return &assemblyResult{
+		binary:       binary,
+		symbols:      asm.DefinedSymbols(),
+		segments:     asm.SegmentUsage(),
+		dependencies: asm.Dependencies(),
+		notes:        asm.Notes(),
+	}
+}
+
+// readAssemblerConfig reads the config file from the file system or from disk if the
+// file system is nil. The default config is used if no config file is set. It returns
+// the names of the read files, which are dependencies of the output.
+func readAssemblerConfig[T any](cfg *config.Config[T], fsys fs.FS, configFile string) ([]string, error) {
 	if configFile != "" {
-		cfgData, err := os.ReadFile(configFile)
+		var (
+			cfgData []byte
+			err     error
+		)
+		if fsys != nil {
+			cfgData, err = fs.ReadFile(fsys, filepath.ToSlash(configFile))
+		} else {
+			cfgData, err = os.ReadFile(configFile)
+		}
 		if err != nil {
-			return fmt.Errorf("opening config file '%s': %w", configFile, err)
+			return nil, fmt.Errorf("opening config file '%s': %w", configFile, err)
 		}
 		if err := cfg.ReadCa65Config(bytes.NewReader(cfgData)); err != nil {
-			return fmt.Errorf("reading config file '%s': %w", configFile, err)
+			return nil, fmt.Errorf("reading config file '%s': %w", configFile, err)
 		}
-		return nil
+		return []string{configFile}, nil
 	}
 
 	if err := cfg.ReadCa65Config(strings.NewReader(defaultConfig)); err != nil {
-		return fmt.Errorf("reading default config: %w", err)
+		return nil, fmt.Errorf("reading default config: %w", err)
 	}
-	return nil
+	return nil, nil
 }
 
 func applyBaseAddress[T any](cfg *config.Config[T], baseAddress uint64) {
@@ -279,6 +548,124 @@ func applyBaseAddress[T any](cfg *config.Config[T], baseAddress uint64) {
 	}
 }
 
+// outputSymbols returns the output Symbol map containing the passed input symbols and
+// all symbols that the assembled program defines.
+func outputSymbols(inputSymbols map[string]uint64, definedSymbols []assembler.Symbol,
+	sourceName string) map[string]Symbol {
+
+	result := copyInputSymbols(inputSymbols, sourceName)
+	for _, sym := range definedSymbols {
+		result[sym.Name] = Symbol{
+			Name:       sym.Name,
+			Value:      sym.Value,
+			Type:       convertSymbolType(sym.Type),
+			Segment:    sym.Segment,
+			Location:   sourceLocation(sym.Position, sourceName),
+			InOutput:   sym.InOutput,
+			FileOffset: sym.FileOffset,
+		}
+	}
+	return result
+}
+
+// outputSegments converts the segment usage of the assembler to the output segments.
+func outputSegments(usage []assembler.SegmentUsage) []Segment {
+	segments := make([]Segment, 0, len(usage))
+	for _, seg := range usage {
+		segments = append(segments, Segment{
+			Name:           seg.Name,
+			Memory:         seg.Memory,
+			StartAddr:      seg.Start,
+			Size:           seg.Size,
+			ConfiguredSize: seg.ConfiguredSize,
+			Data:           seg.Data,
+			InOutput:       seg.InOutput,
+			OutputFile:     seg.OutputFile,
+			FileOffset:     seg.FileOffset,
+		})
+	}
+	return segments
+}
+
+// outputDiagnostics converts the errors of a failed assembler run to the output diagnostics.
+func outputDiagnostics(err error, sourceName string) []Diagnostic {
+	return convertDiagnostics(assembler.Diagnostics(err), DiagnosticError, sourceName)
+}
+
+// outputNotes converts the notes of a successful assembler run to info diagnostics.
+func outputNotes(notes []assembler.Diagnostic, sourceName string) []Diagnostic {
+	if len(notes) == 0 {
+		return nil
+	}
+	return convertDiagnostics(notes, DiagnosticInfo, sourceName)
+}
+
+// convertDiagnostics converts assembler diagnostics to output diagnostics of the level.
+func convertDiagnostics(diagnostics []assembler.Diagnostic, level DiagnosticLevel, sourceName string) []Diagnostic {
+	result := make([]Diagnostic, 0, len(diagnostics))
+	for _, diag := range diagnostics {
+		result = append(result, Diagnostic{
+			Level:      level,
+			Message:    diag.Message,
+			Location:   sourceLocation(diag.Position, sourceName),
+			Expansions: outputExpansions(diag.Position, sourceName),
+			Code:       diag.Code,
+			Hints:      diag.Hints,
+		})
+	}
+	return result
+}
+
+// outputExpansions returns the include and macro expansions that led to the position.
+func outputExpansions(pos token.Position, sourceName string) []Expansion {
+	stack := pos.ExpansionStack()
+	if len(stack) == 0 {
+		return nil
+	}
+
+	expansions := make([]Expansion, 0, len(stack))
+	for _, exp := range stack {
+		expansion := Expansion{
+			Location: sourceLocation(exp.Position, sourceName),
+		}
+		if exp.Kind == token.MacroExpansion {
+			expansion.Macro = exp.Name
+		}
+		expansions = append(expansions, expansion)
+	}
+	return expansions
+}
+
+// sourceLocation converts a source position to a source location. Positions without
+// a file name refer to the main source.
+func sourceLocation(pos token.Position, sourceName string) SourceLocation {
+	filename := pos.File
+	if filename == "" {
+		filename = sourceName
+	}
+	return SourceLocation{
+		Filename: filename,
+		Line:     pos.Line,
+		Column:   pos.Column,
+	}
+}
+
+// convertSymbolType converts an assembler scope symbol type to the public symbol type.
+func convertSymbolType(typ scope.SymbolType) SymbolType {
+	switch typ {
+	case scope.AliasType:
+		return SymbolTypeAlias
+	case scope.FunctionType:
+		return SymbolTypeFunction
+	case scope.LabelType:
+		return SymbolTypeLabel
+	case scope.VariableType:
+		return SymbolTypeVariable
+	default:
+		return SymbolTypeConstant
+	}
+}
+
 // copyInputSymbols converts a map of symbol names to values into the output Symbol map.
 func copyInputSymbols(symbols map[string]uint64, sourceName string) map[string]Symbol {
 	result := make(map[string]Symbol, len(symbols))
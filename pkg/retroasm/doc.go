@@ -39,6 +39,10 @@
 //   - asm6: asm6/asm6f syntax
 //   - ca65: cc65 toolchain syntax
 //   - nesasm: NESasm3 syntax
+//   - x816: x816 syntax
+//
+// If TextInput.Format is empty, the format is detected from dialect specific
+// directives in the source, see DetectFormat.
 //
 // # Examples
 //
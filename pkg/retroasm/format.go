@@ -0,0 +1,88 @@
+package retroasm
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/retroenv/retroasm/pkg/assembler/config"
+)
+
+// formatMarkers maps directives that only exist in a specific assembler dialect
+// to the format that they identify. Markers are checked in the order listed.
+var formatMarkers = []struct {
+	format     string
+	directives []string
+}{
+	{
+		format:     FormatNesasm,
+		directives: []string{".inesprg", ".ineschr", ".inesmap", ".inesmir", ".inesbat", ".inessubmap", ".rsset"},
+	},
+	{
+		format:     FormatCa65,
+		directives: []string{".segment", ".proc", ".endproc", ".scope", ".endscope", ".setcpu"},
+	},
+	{
+		format: FormatX816,
+		directives: []string{".mem", ".index", ".hirom", ".lrom", ".hrom", ".smc", ".detect", ".dasm",
+			".locchar", ".localsymbolchar", ".dcd", ".dcl", ".dsd", ".dsl", ".src"},
+	},
+	{
+		format:     FormatAsm6,
+		directives: []string{".unstable", ".hunstable"},
+	},
+}
+
+// DetectFormat returns the assembly format of the source based on dialect specific
+// directives. It returns an empty string if no marker directive was found.
+func DetectFormat(source []byte) string {
+	found := map[string]bool{}
+
+	// lines are split without a scanner, which would stop at lines that exceed its buffer
+	for data := range bytes.Lines(source) {
+		line := string(data)
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+
+		for _, field := range strings.Fields(line) {
+			if field[0] == '.' {
+				found[strings.ToLower(field)] = true
+			}
+		}
+	}
+
+	for _, marker := range formatMarkers {
+		for _, directive := range marker.directives {
+			if found[directive] {
+				return marker.format
+			}
+		}
+	}
+	return ""
+}
+
+// resolveCompatibilityMode returns the compatibility mode for the given format.
+// If no format is set, the format is detected from the source and the returned
+// reader replays the already consumed source.
+func resolveCompatibilityMode(format string, source io.Reader) (config.CompatibilityMode, io.Reader, error) {
+	if format == "" {
+		data, err := io.ReadAll(source)
+		if err != nil {
+			return config.CompatDefault, nil, fmt.Errorf("reading source: %w", err)
+		}
+		source = bytes.NewReader(data)
+
+		format = DetectFormat(data)
+		if format == "" {
+			return config.CompatDefault, source, nil
+		}
+	}
+
+	mode, err := config.ParseCompatibilityMode(format)
+	if err != nil {
+		return config.CompatDefault, nil, fmt.Errorf("parsing format: %w", err)
+	}
+	return mode, source, nil
+}
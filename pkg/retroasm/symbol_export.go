@@ -0,0 +1,251 @@
+package retroasm
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Debugger symbol file format constants.
+const (
+	SymbolFormatMesen   = "mesen" // Mesen .mlb label file
+	SymbolFormatFCEUX   = "fceux" // FCEUX .nl files, one per 16 KB PRG ROM bank and one for RAM
+	SymbolFormatGeneric = "sym"   // plain name = $address file
+)
+
+// SymbolFormats lists all supported debugger symbol file formats.
+var SymbolFormats = []string{SymbolFormatMesen, SymbolFormatFCEUX, SymbolFormatGeneric}
+
+// ErrUnsupportedSymbolFormat is returned for an unknown debugger symbol file format.
+var ErrUnsupportedSymbolFormat = errors.New("unsupported symbol file format")
+
+const (
+	nesHeaderSize  = 16
+	nesPrgBankSize = 0x4000 // PRG ROM unit of the iNES header and bank size of FCEUX label files
+
+	nesRAMEnd      = 0x2000 // end of the mirrored internal RAM
+	nesRAMMask     = 0x07ff
+	nesRegisterEnd = 0x4020 // end of the PPU and APU register area
+	nesCartRAM     = 0x6000
+	nesCartRAMEnd  = 0x8000
+)
+
+// SymbolFile is a generated debugger symbol file.
+type SymbolFile struct {
+	Name string
+	Data []byte
+}
+
+// labelLocation defines where a label is located in the emulated system.
+type labelLocation int
+
+const (
+	labelPrgROM labelLocation = iota
+	labelCPUAddress
+	labelUnmapped // for example labels of the file header or CHR ROM
+)
+
+// exportLabel is a label of the assembled program that is exported to a debugger.
+type exportLabel struct {
+	name      string
+	address   uint64
+	location  labelLocation
+	romOffset uint64 // offset in the PRG ROM, only set for labels located in the PRG ROM
+}
+
+// romLayout describes the layout of the NES ROM file that was assembled.
+type romLayout struct {
+	headerSize uint64
+	prgSize    uint64 // 0 if unknown
+	battery    bool   // cartridge RAM is battery backed
+}
+
+// ExportSymbols converts the symbols of an assembly output to debugger symbol files in the
+// given format. The file names are derived from the name of the output binary, following
+// the naming conventions of the emulators that read them.
+//
+// Labels of segments that are part of the output binary are exported by their PRG ROM
+// offset, which excludes an iNES file header, all other labels by their CPU address.
+func ExportSymbols(output *AssemblyOutput, format, binaryName string) ([]SymbolFile, error) {
+	if output == nil {
+		return nil, ErrNilInput
+	}
+
+	switch strings.ToLower(format) {
+	case SymbolFormatMesen:
+		labels := exportLabels(output)
+		name := strings.TrimSuffix(binaryName, filepath.Ext(binaryName)) + ".mlb"
+		return []SymbolFile{{Name: name, Data: mesenLabels(labels, readROMLayout(output.Binary))}}, nil
+
+	case SymbolFormatFCEUX:
+		return fceuxLabelFiles(exportLabels(output), binaryName), nil
+
+	case SymbolFormatGeneric:
+		name := strings.TrimSuffix(binaryName, filepath.Ext(binaryName)) + ".sym"
+		return []SymbolFile{{Name: name, Data: genericSymbols(output.Symbols)}}, nil
+
+	default:
+		return nil, fmt.Errorf("%w '%s'", ErrUnsupportedSymbolFormat, format)
+	}
+}
+
+// readROMLayout returns the layout of the ROM based on the iNES header of the binary.
+func readROMLayout(binary []byte) romLayout {
+	if len(binary) < nesHeaderSize || !bytes.HasPrefix(binary, []byte("NES\x1a")) {
+		return romLayout{}
+	}
+
+	prgUnits := uint64(binary[4])
+	if binary[7]&0x0c == 0x08 { // NES 2.0 stores the most significant bits in byte 9
+		prgUnits |= uint64(binary[9]&0x0f) << 8
+	}
+
+	return romLayout{
+		headerSize: nesHeaderSize,
+		prgSize:    prgUnits * nesPrgBankSize,
+		battery:    binary[6]&0x02 != 0,
+	}
+}
+
+// exportLabels returns all labels, functions and variables of the output sorted by
+// their location.
+func exportLabels(output *AssemblyOutput) []exportLabel {
+	layout := readROMLayout(output.Binary)
+	segments := make(map[string]Segment, len(output.Segments))
+	for _, seg := range output.Segments {
+		segments[seg.Name] = seg
+	}
+
+	var labels []exportLabel
+	for _, sym := range output.Symbols {
+		if sym.Segment == "" || (sym.Type != SymbolTypeLabel && sym.Type != SymbolTypeFunction &&
+			sym.Type != SymbolTypeVariable) {
+			continue
+		}
+
+		label := exportLabel{
+			name:     strings.ReplaceAll(sym.Name, "::", "_"), // debuggers do not support scope separators
+			address:  sym.Value,
+			location: labelCPUAddress,
+		}
+
+		seg, ok := segments[sym.Segment]
+		switch {
+		case ok && seg.InOutput && seg.OutputFile != "":
+			label.location = labelUnmapped // for example CHR ROM written to a separate file
+
+		case sym.InOutput:
+			// the file offset of the symbol includes the bank of banked code
+			label.location = labelUnmapped
+			if sym.FileOffset >= layout.headerSize {
+				label.romOffset = sym.FileOffset - layout.headerSize
+				if layout.prgSize == 0 || label.romOffset < layout.prgSize {
+					label.location = labelPrgROM
+				}
+			}
+		}
+		labels = append(labels, label)
+	}
+
+	slices.SortFunc(labels, func(a, b exportLabel) int {
+		return cmp.Or(
+			cmp.Compare(a.location, b.location),
+			cmp.Compare(a.romOffset, b.romOffset),
+			cmp.Compare(a.address, b.address),
+			strings.Compare(a.name, b.name),
+		)
+	})
+	return labels
+}
+
+// mesenLabels returns the content of a Mesen .mlb label file.
+func mesenLabels(labels []exportLabel, layout romLayout) []byte {
+	var buf bytes.Buffer
+	for _, label := range labels {
+		switch label.location {
+		case labelPrgROM:
+			_, _ = fmt.Fprintf(&buf, "P:%04X:%s\n", label.romOffset, label.name)
+
+		case labelCPUAddress:
+			switch {
+			case label.address < nesRAMEnd:
+				_, _ = fmt.Fprintf(&buf, "R:%04X:%s\n", label.address&nesRAMMask, label.name)
+			case label.address < nesRegisterEnd:
+				_, _ = fmt.Fprintf(&buf, "G:%04X:%s\n", label.address, label.name)
+			case label.address >= nesCartRAM && label.address < nesCartRAMEnd:
+				memoryType := "W"
+				if layout.battery {
+					memoryType = "S"
+				}
+				_, _ = fmt.Fprintf(&buf, "%s:%04X:%s\n", memoryType, label.address-nesCartRAM, label.name)
+			}
+
+		case labelUnmapped:
+		}
+	}
+	return buf.Bytes()
+}
+
+// fceuxLabelFiles returns the FCEUX .nl label files, the labels of every 16 KB PRG ROM bank
+// are written to a separate file and all RAM labels to a .ram.nl file.
+func fceuxLabelFiles(labels []exportLabel, binaryName string) []SymbolFile {
+	var banks []uint64
+	bankData := map[uint64]*bytes.Buffer{}
+	var ram bytes.Buffer
+
+	for _, label := range labels {
+		switch label.location {
+		case labelPrgROM:
+			bank := label.romOffset / nesPrgBankSize
+			buf, ok := bankData[bank]
+			if !ok {
+				buf = &bytes.Buffer{}
+				bankData[bank] = buf
+				banks = append(banks, bank)
+			}
+			_, _ = fmt.Fprintf(buf, "$%04X#%s#\n", label.address, label.name)
+
+		case labelCPUAddress:
+			if label.address < nesCartRAMEnd {
+				_, _ = fmt.Fprintf(&ram, "$%04X#%s#\n", label.address, label.name)
+			}
+
+		case labelUnmapped:
+		}
+	}
+
+	files := make([]SymbolFile, 0, len(banks)+1)
+	for _, bank := range banks {
+		files = append(files, SymbolFile{
+			Name: fmt.Sprintf("%s.%X.nl", binaryName, bank),
+			Data: bankData[bank].Bytes(),
+		})
+	}
+	if ram.Len() > 0 {
+		files = append(files, SymbolFile{
+			Name: binaryName + ".ram.nl",
+			Data: ram.Bytes(),
+		})
+	}
+	return files
+}
+
+// genericSymbols returns a symbol file that contains every symbol in the
+// name = $address notation, sorted by name.
+func genericSymbols(symbols map[string]Symbol) []byte {
+	names := make([]string, 0, len(symbols))
+	for name := range symbols {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		_, _ = fmt.Fprintf(&buf, "%s = $%04X\n", name, symbols[name].Value)
+	}
+	return buf.Bytes()
+}
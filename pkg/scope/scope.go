@@ -1,28 +1,45 @@
 package scope
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Errors returned by symbol lookups and definitions.
+var (
+	ErrSymbolExists   = errors.New("symbol already exists and can not be overwritten")
+	ErrSymbolNotFound = errors.New("symbol not found in scope")
+)
 
 // Scope defines a scope that contains symbols, on a global, file or function level.
 // It supports embedding child scopes by a parent relationship.
 type Scope struct {
-	parent *Scope
+	parent   *Scope
+	children []*Scope
 
+	name    string // optional name of a named scope like a function
 	symbols map[string]*Symbol
 }
 
 // New creates a new scope with given parent that can be nil.
 func New(parent *Scope) *Scope {
-	return &Scope{
+	sc := &Scope{
 		parent:  parent,
 		symbols: map[string]*Symbol{},
 	}
+	if parent != nil {
+		parent.children = append(parent.children, sc)
+	}
+	return sc
 }
 
 // AddSymbol adds a symbol to the current scope.
 func (sc *Scope) AddSymbol(sym *Symbol) error {
 	existing, exists := sc.symbols[sym.name]
 	if exists && (existing.typ != AliasType || sym.typ != AliasType) {
-		return fmt.Errorf("symbol '%s' already exists and can not be overwritten", sym.name)
+		return fmt.Errorf("%w: '%s'", ErrSymbolExists, sym.name)
 	}
 
 	sc.symbols[sym.name] = sym
@@ -38,7 +55,7 @@ func (sc *Scope) GetSymbol(name string) (*Symbol, error) {
 			return sym, nil
 		}
 	}
-	return nil, fmt.Errorf("symbol '%s' not found in scope", name)
+	return nil, fmt.Errorf("%w: '%s'", ErrSymbolNotFound, name)
 }
 
 // Parent returns the parent scope.
@@ -46,6 +63,34 @@ func (sc *Scope) Parent() *Scope {
 	return sc.parent
 }
 
+// Children returns all child scopes in the order of their creation.
+func (sc *Scope) Children() []*Scope {
+	return sc.children
+}
+
+// SetName sets the name of the scope.
+func (sc *Scope) SetName(name string) {
+	sc.name = name
+}
+
+// Name returns the name of the scope, it is empty for anonymous scopes.
+func (sc *Scope) Name() string {
+	return sc.name
+}
+
+// Symbols returns all symbols of this scope sorted by name.
+// Only this scope is searched (parent and child scopes are not included).
+func (sc *Scope) Symbols() []*Symbol {
+	symbols := make([]*Symbol, 0, len(sc.symbols))
+	for _, sym := range sc.symbols {
+		symbols = append(symbols, sym)
+	}
+	slices.SortFunc(symbols, func(a, b *Symbol) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return symbols
+}
+
 // AllLabels returns the resolved address of every label-type symbol in this scope.
 // Only this scope is searched (parent scopes are not included).
 // Symbols whose addresses have not been assigned yet have address 0.
@@ -29,9 +29,9 @@ func TestScope(t *testing.T) {
 
 	// adding symbol again fails
 	err = parent.AddSymbol(sym)
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrSymbolExists)
 
 	// getting an undefined symbol fails
 	_, err = parent.GetSymbol("nonexisting")
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrSymbolNotFound)
 }
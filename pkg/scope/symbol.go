@@ -24,6 +24,16 @@ const (
 	EquType
 	LabelType
 	FunctionType
+	VariableType
+)
+
+// Visibility defines whether a symbol is visible to other files.
+type Visibility int
+
+const (
+	LocalVisibility  Visibility = iota // symbol is only visible in its file
+	ExportVisibility                   // symbol is defined by its file and visible to other files
+	ImportVisibility                   // symbol is defined by another file
 )
 
 // Expression defines the used expression functions.
@@ -43,6 +53,18 @@ type Symbol struct {
 	addressSet bool // true once SetAddress has been called (distinguishes address 0 from unset)
 	typ        SymbolType
 	expression Expression
+
+	segment  string         // name of the segment that contains the symbol
+	position token.Position // source position of the symbol definition
+
+	bank    uint64 // bank that contains the symbol, only valid if bankSet is set
+	bankSet bool
+
+	loadAddress    uint64 // address in the load memory of the segment, only valid if loadAddressSet is set
+	loadAddressSet bool
+
+	visibility Visibility
+	zeroPage   bool // symbol was declared as zero page address by a visibility directive
 }
 
 // NewSymbol creates a new symbol in the given scope.
@@ -66,9 +88,23 @@ func (sym *Symbol) Copy() *Symbol {
 		addressSet: sym.addressSet,
 		typ:        sym.typ,
 		expression: sym.expression.CopyExpression().(Expression),
+		segment:    sym.segment,
+		bank:       sym.bank,
+		bankSet:    sym.bankSet,
+		position:   sym.position,
+		visibility: sym.visibility,
+		zeroPage:   sym.zeroPage,
+
+		loadAddress:    sym.loadAddress,
+		loadAddressSet: sym.loadAddressSet,
 	}
 }
 
+// Name returns the name of the symbol.
+func (sym *Symbol) Name() string {
+	return sym.name
+}
+
 // SetAddress sets the address of the symbol. This is only useful for symbols of type label that
 // gets referenced in code.
 func (sym *Symbol) SetAddress(address uint64) {
@@ -91,8 +127,74 @@ func (sym *Symbol) Type() SymbolType {
 	return sym.typ
 }
 
+// SetSegment sets the name of the segment that contains the symbol.
+func (sym *Symbol) SetSegment(segment string) {
+	sym.segment = segment
+}
+
+// Segment returns the name of the segment that contains the symbol.
+func (sym *Symbol) Segment() string {
+	return sym.segment
+}
+
+// SetBank sets the bank that contains the symbol, for symbols that are placed in
+// a bank of a banked ROM.
+func (sym *Symbol) SetBank(bank uint64) {
+	sym.bank = bank
+	sym.bankSet = true
+}
+
+// Bank returns the bank that contains the symbol and whether the bank is set.
+func (sym *Symbol) Bank() (uint64, bool) {
+	return sym.bank, sym.bankSet
+}
+
+// SetLoadAddress sets the address of the symbol in the load memory of its segment.
+// It differs from the address for banked code and segments that run in another memory.
+func (sym *Symbol) SetLoadAddress(address uint64) {
+	sym.loadAddress = address
+	sym.loadAddressSet = true
+}
+
+// LoadAddress returns the address of the symbol in the load memory of its segment
+// and whether the load address is set.
+func (sym *Symbol) LoadAddress() (uint64, bool) {
+	return sym.loadAddress, sym.loadAddressSet
+}
+
+// SetPosition sets the source position of the symbol definition.
+func (sym *Symbol) SetPosition(position token.Position) {
+	sym.position = position
+}
+
+// Position returns the source position of the symbol definition.
+func (sym *Symbol) Position() token.Position {
+	return sym.position
+}
+
+// SetVisibility sets whether the symbol is visible to other files.
+func (sym *Symbol) SetVisibility(visibility Visibility) {
+	sym.visibility = visibility
+}
+
+// Visibility returns whether the symbol is visible to other files.
+func (sym *Symbol) Visibility() Visibility {
+	return sym.visibility
+}
+
+// SetZeroPage sets whether the symbol was declared as zero page address. Instructions
+// that reference a zero page symbol use zero page addressing before its address is known.
+func (sym *Symbol) SetZeroPage(zeroPage bool) {
+	sym.zeroPage = zeroPage
+}
+
+// ZeroPage returns whether the symbol was declared as zero page address.
+func (sym *Symbol) ZeroPage() bool {
+	return sym.zeroPage
+}
+
 // Value returns the value of the symbol, either an address for symbols of type label
-// or the value of the expression. The returned value can be of type int64, uint64 or []byte.
+// and variable or the value of the expression. The returned value can be of type int64, uint64 or []byte.
 func (sym *Symbol) Value(scope *Scope) (any, error) {
 	switch sym.typ {
 	case AliasType, EquType:
@@ -102,7 +204,7 @@ func (sym *Symbol) Value(scope *Scope) (any, error) {
 		}
 		return value, nil
 
-	case LabelType, FunctionType:
+	case LabelType, FunctionType, VariableType:
 		if !sym.addressSet {
 			return 0, ErrForwardReference
 		}
@@ -29,6 +29,69 @@ func TestScopeParent(t *testing.T) {
 	assert.Equal(t, parent, child.Parent())
 }
 
+func TestScopeChildren(t *testing.T) {
+	parent := New(nil)
+	assert.Empty(t, parent.Children())
+
+	first := New(parent)
+	first.SetName("main")
+	second := New(parent)
+	assert.Equal(t, []*Scope{first, second}, parent.Children())
+	assert.Equal(t, "main", first.Name())
+	assert.Equal(t, "", second.Name())
+}
+
+func TestScopeSymbols(t *testing.T) {
+	sc := New(nil)
+	for _, name := range []string{"c", "a", "b"} {
+		_, err := NewSymbol(sc, name, LabelType)
+		assert.NoError(t, err)
+	}
+
+	symbols := sc.Symbols()
+	assert.Len(t, symbols, 3)
+	assert.Equal(t, "a", symbols[0].Name())
+	assert.Equal(t, "b", symbols[1].Name())
+	assert.Equal(t, "c", symbols[2].Name())
+}
+
+func TestSymbolMetadata(t *testing.T) {
+	sym, err := NewSymbol(New(nil), "v", VariableType)
+	assert.NoError(t, err)
+
+	sym.SetSegment("ZEROPAGE")
+	sym.SetPosition(token.Position{Line: 2, Column: 5})
+	assert.Equal(t, "ZEROPAGE", sym.Segment())
+	assert.Equal(t, token.Position{Line: 2, Column: 5}, sym.Position())
+
+	assert.Equal(t, LocalVisibility, sym.Visibility())
+	sym.SetVisibility(ExportVisibility)
+	sym.SetZeroPage(true)
+	assert.Equal(t, ExportVisibility, sym.Visibility())
+	assert.True(t, sym.ZeroPage())
+
+	_, ok := sym.Bank()
+	assert.False(t, ok)
+	sym.SetBank(3)
+	bank, ok := sym.Bank()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(3), bank)
+
+	_, ok = sym.LoadAddress()
+	assert.False(t, ok)
+	sym.SetLoadAddress(0x4010)
+	loadAddress, ok := sym.LoadAddress()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0x4010), loadAddress)
+
+	_, err = sym.Value(nil)
+	assert.ErrorIs(t, err, ErrForwardReference)
+	sym.SetAddress(0x10)
+	value, err := sym.Value(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0x10), value)
+}
+
 func TestScopeAllLabels(t *testing.T) {
 	sc := New(nil)
 	for _, sym := range []*Symbol{